@@ -12,6 +12,55 @@ type Config struct {
 	Mail  MailConfig
 	Auth  AuthConfig
 	Redis RedisConfig
+	Media MediaConfig
+	// Exports keeps data exports apart from media, it is never served
+	// publicly and exports are downloaded through the API
+	Exports MediaConfig
+	Posts   PostsConfig
+	Users   UsersConfig
+}
+
+type PostsConfig struct {
+	MaxTitleLength   int
+	MaxContentLength int
+	// RenderCacheSize is how many rendered posts are kept in memory
+	RenderCacheSize int
+	// ScheduleInterval is how often due scheduled posts are published
+	ScheduleInterval time.Duration
+	// RestoreWindow is how long a deleted post or comment can be restored
+	// before it is purged
+	RestoreWindow time.Duration
+}
+
+type UsersConfig struct {
+	// MaxPinnedPosts is how many posts a user can pin to their profile
+	MaxPinnedPosts int
+	// UsernameCooldown is how long a user waits between username changes
+	UsernameCooldown time.Duration
+	// UsernameReservation is how long an old username is kept for its owner
+	UsernameReservation time.Duration
+	// DeletionGrace is how long a deleted account can still be restored
+	DeletionGrace time.Duration
+	// ExportTTL is how long a data export stays downloadable
+	ExportTTL time.Duration
+}
+
+type MediaConfig struct {
+	Backend       string // local or s3
+	LocalDir      string
+	PublicURL     string
+	MaxUploadSize int64
+	OrphanTTL     time.Duration
+	S3            S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
 }
 
 type RedisConfig struct {
@@ -27,6 +76,18 @@ type AuthConfig struct {
 	JWTSecret     string
 	JWTExp        time.Duration
 	JWTIss        string
+	OIDC          OIDCConfig
+}
+
+type OIDCConfig struct {
+	Enabled      bool
+	Provider     string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	StateExp     time.Duration
 }
 
 type AppConfig struct {
@@ -42,6 +103,13 @@ type MailConfig struct {
 	Exp       time.Duration
 	ApiKey    string
 	FromEmail string
+	Digest    DigestConfig
+}
+
+type DigestConfig struct {
+	Enabled   bool
+	Interval  time.Duration
+	BatchSize int
 }
 
 type DBConfig struct {
@@ -79,6 +147,11 @@ func InitConfig() Config {
 		Exp:       time.Hour * 24 * 3, // 3 days,
 		ApiKey:    env.GetString("MAILTRAP_API_KEY", ""),
 		FromEmail: env.GetString("FROM_EMAIL", ""),
+		Digest: DigestConfig{
+			Enabled:   env.GetBool("MAIL_DIGEST_ENABLED", false),
+			Interval:  time.Minute * 15,
+			BatchSize: env.GetInt("MAIL_DIGEST_BATCH_SIZE", 100),
+		},
 	}
 
 	auth := AuthConfig{
@@ -87,13 +160,72 @@ func InitConfig() Config {
 		JWTSecret:     env.GetString("AUTH_JWT_SECRET", ""),
 		JWTExp:        time.Hour * 24 * 3,
 		JWTIss:        "gophersocial",
+		OIDC: OIDCConfig{
+			Enabled:      env.GetBool("AUTH_OIDC_ENABLED", false),
+			Provider:     env.GetString("AUTH_OIDC_PROVIDER", "oidc"),
+			IssuerURL:    env.GetString("AUTH_OIDC_ISSUER_URL", ""),
+			ClientID:     env.GetString("AUTH_OIDC_CLIENT_ID", ""),
+			ClientSecret: env.GetString("AUTH_OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  env.GetString("AUTH_OIDC_REDIRECT_URL", ""),
+			Scopes:       []string{"openid", "email", "profile"},
+			StateExp:     time.Minute * 10,
+		},
+	}
+
+	media := MediaConfig{
+		Backend:       env.GetString("MEDIA_BACKEND", "local"),
+		LocalDir:      env.GetString("MEDIA_LOCAL_DIR", "./uploads"),
+		PublicURL:     env.GetString("MEDIA_PUBLIC_URL", "http://localhost:8080/media"),
+		MaxUploadSize: int64(env.GetInt("MEDIA_MAX_UPLOAD_SIZE", 10<<20)),
+		OrphanTTL:     time.Hour * 24,
+		S3: S3Config{
+			Endpoint:  env.GetString("MEDIA_S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    env.GetString("MEDIA_S3_REGION", "us-east-1"),
+			Bucket:    env.GetString("MEDIA_S3_BUCKET", ""),
+			AccessKey: env.GetString("MEDIA_S3_ACCESS_KEY", ""),
+			SecretKey: env.GetString("MEDIA_S3_SECRET_KEY", ""),
+			PathStyle: env.GetBool("MEDIA_S3_PATH_STYLE", false),
+		},
+	}
+
+	exports := MediaConfig{
+		Backend:  env.GetString("EXPORTS_BACKEND", "local"),
+		LocalDir: env.GetString("EXPORTS_LOCAL_DIR", "./exports"),
+		S3: S3Config{
+			Endpoint:  media.S3.Endpoint,
+			Region:    media.S3.Region,
+			Bucket:    env.GetString("EXPORTS_S3_BUCKET", ""),
+			AccessKey: media.S3.AccessKey,
+			SecretKey: media.S3.SecretKey,
+			PathStyle: media.S3.PathStyle,
+		},
+	}
+
+	posts := PostsConfig{
+		MaxTitleLength:   env.GetInt("POSTS_MAX_TITLE_LENGTH", 100),
+		MaxContentLength: env.GetInt("POSTS_MAX_CONTENT_LENGTH", 300),
+		RenderCacheSize:  env.GetInt("POSTS_RENDER_CACHE_SIZE", 1000),
+		ScheduleInterval: time.Second * 30,
+		RestoreWindow:    time.Hour * 24 * 7,
+	}
+
+	users := UsersConfig{
+		MaxPinnedPosts:      env.GetInt("USERS_MAX_PINNED_POSTS", 3),
+		UsernameCooldown:    time.Hour * 24 * 30,
+		UsernameReservation: time.Hour * 24 * 90,
+		DeletionGrace:       time.Hour * 24 * 30,
+		ExportTTL:           time.Hour * 24 * 7,
 	}
 
 	return Config{
-		App:   app,
-		DB:    db,
-		Mail:  mail,
-		Auth:  auth,
-		Redis: redis,
+		App:     app,
+		DB:      db,
+		Mail:    mail,
+		Auth:    auth,
+		Redis:   redis,
+		Media:   media,
+		Exports: exports,
+		Posts:   posts,
+		Users:   users,
 	}
 }
@@ -34,8 +34,8 @@ func (app *Application) Routes(redis cache.Storage) *gin.Engine {
 			postsID.Use(app.Store.Posts.PostContextMiddleware())
 
 			postsID.GET("/", app.Store.Posts.GetPostHandler)
-			postsID.PATCH("/", mid.CheckPostOwnership("staff"), app.Store.Posts.UpdatePostHandler)
-			postsID.DELETE("/", mid.CheckPostOwnership("admin"), app.Store.Posts.DeletePostHandler)
+			postsID.PATCH("/", app.Store.Posts.UpdatePostHandler)
+			postsID.DELETE("/", app.Store.Posts.DeletePostHandler)
 		}
 	}
 
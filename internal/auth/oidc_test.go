@@ -0,0 +1,147 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/auth/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "https://app.example.com/v1/auth/oidc/callback"
+
+func newProvider(t *testing.T, idp *oidctest.IdP) *auth.OIDCProvider {
+	t.Helper()
+
+	p, err := auth.NewOIDCProvider(
+		context.Background(),
+		"test",
+		idp.URL,
+		oidctest.ClientID,
+		oidctest.ClientSecret,
+		redirectURL,
+		[]string{"openid", "email", "profile"},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// login runs the browser side of the flow: it builds the authorization
+// URL, lets the stand-in sign the user in and checks the state comes back.
+func login(t *testing.T, idp *oidctest.IdP, p *auth.OIDCProvider, claims jwt.MapClaims) (code, verifier, nonce string) {
+	t.Helper()
+
+	state, _ := auth.RandomString(32)
+	nonce, _ = auth.RandomString(32)
+	verifier, _ = auth.RandomString(48)
+
+	code, gotState := idp.Authorize(t, p.AuthCodeURL(state, nonce, auth.PKCEChallenge(verifier)), claims)
+	if gotState != state {
+		t.Fatalf("state %q came back as %q", state, gotState)
+	}
+
+	return code, verifier, nonce
+}
+
+func TestOIDCExchange(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	p := newProvider(t, idp)
+
+	code, verifier, nonce := login(t, idp, p, jwt.MapClaims{
+		"sub":                "subject-42",
+		"email":              "gopher@example.com",
+		"email_verified":     true,
+		"preferred_username": "gopher",
+		"name":               "Gopher",
+	})
+
+	identity, err := p.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := auth.OIDCIdentity{
+		Provider:          "test",
+		Subject:           "subject-42",
+		Email:             "gopher@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "gopher",
+		Name:              "Gopher",
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOIDCExchangeRejectsIDToken(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	p := newProvider(t, idp)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"nonce of another login", jwt.MapClaims{"nonce": "replayed"}},
+		{"no nonce", jwt.MapClaims{"nonce": nil}},
+		{"other issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"issuer with a trailing slash", jwt.MapClaims{"iss": idp.URL + "/"}},
+		{"other audience", jwt.MapClaims{"aud": "another-client"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{"no expiry", jwt.MapClaims{"exp": nil}},
+		{"no subject", jwt.MapClaims{"sub": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, verifier, nonce := login(t, idp, p, tt.claims)
+
+			_, err := p.Exchange(context.Background(), code, verifier, nonce)
+			if !errors.Is(err, auth.ErrOIDCInvalidToken) {
+				t.Fatalf("Exchange error = %v, want %v", err, auth.ErrOIDCInvalidToken)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangePKCE(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	p := newProvider(t, idp)
+	ctx := context.Background()
+
+	code, _, nonce := login(t, idp, p, nil)
+	other, _ := auth.RandomString(48)
+
+	if _, err := p.Exchange(ctx, code, other, nonce); !errors.Is(err, auth.ErrOIDCExchangeToken) {
+		t.Fatalf("Exchange with another verifier: %v, want %v", err, auth.ErrOIDCExchangeToken)
+	}
+
+	code, verifier, nonce := login(t, idp, p, nil)
+	if _, err := p.Exchange(ctx, code, verifier, nonce); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(ctx, code, verifier, nonce); !errors.Is(err, auth.ErrOIDCExchangeToken) {
+		t.Fatalf("Exchange of a used code: %v, want %v", err, auth.ErrOIDCExchangeToken)
+	}
+}
+
+func TestNewOIDCProviderIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"issuer":"https://evil.example.com","token_endpoint":"https://evil.example.com/token"}`))
+	}))
+	defer srv.Close()
+
+	_, err := auth.NewOIDCProvider(context.Background(), "test", srv.URL, oidctest.ClientID, "", redirectURL, nil, nil)
+	if err == nil {
+		t.Fatal("NewOIDCProvider accepted a document for another issuer")
+	}
+}
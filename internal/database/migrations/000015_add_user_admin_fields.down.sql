@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS password_resets;
+
+ALTER TABLE users
+DROP COLUMN banned_at,
+DROP COLUMN ban_reason,
+DROP COLUMN password_reset_required;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+ADD COLUMN banned_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN ban_reason TEXT NOT NULL DEFAULT '',
+ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    token BYTEA PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS user_warnings;
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users
+DROP COLUMN suspended_until;
+
+ALTER TABLE comments
+DROP COLUMN hidden_at;
+
+ALTER TABLE posts
+DROP COLUMN hidden_at;
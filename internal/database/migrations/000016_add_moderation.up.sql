@@ -0,0 +1,47 @@
+ALTER TABLE posts
+ADD COLUMN hidden_at TIMESTAMP(0) WITH TIME ZONE;
+
+ALTER TABLE comments
+ADD COLUMN hidden_at TIMESTAMP(0) WITH TIME ZONE;
+
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGSERIAL PRIMARY KEY,
+    reporter_id BIGINT NOT NULL,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id BIGINT NOT NULL,
+    target_user_id BIGINT NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'actioned')),
+    resolution VARCHAR(20),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by BIGINT,
+    resolved_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- one open report per user and target
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter_target
+ON reports (reporter_id, target_type, target_id) WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports (target_type, target_id);
+
+CREATE TABLE IF NOT EXISTS user_warnings (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    moderator_id BIGINT NOT NULL,
+    report_id BIGINT,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_warnings_user_id ON user_warnings (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS filter_matches;
+
+DROP TABLE IF EXISTS filter_rules;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS filter_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('word', 'regex', 'domain')),
+    patterns TEXT [] NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('reject', 'hold', 'mask')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by BIGINT,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS filter_matches (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT,
+    target_type VARCHAR(20) NOT NULL,
+    target_id BIGINT,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    matched TEXT NOT NULL,
+    reviewed_by BIGINT,
+    reviewed_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (rule_id) REFERENCES filter_rules (id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_matches_created_at ON filter_matches (created_at);
+CREATE INDEX IF NOT EXISTS idx_filter_matches_target ON filter_matches (target_type, target_id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL,
+    version INT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    tags VARCHAR(100) [],
+    edited_by BIGINT,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE SET NULL,
+    UNIQUE (post_id, version)
+);
+
+-- existing posts start their history at the current version
+INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by, created_at)
+SELECT id, COALESCE(version, 0), title, content, tags, user_id, updated_at FROM posts
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+-- bring existing tags in line with the normalization applied on write
+UPDATE posts SET tags = (
+    SELECT COALESCE(array_agg(DISTINCT lower(btrim(t, ' #'))), '{}')
+    FROM unnest(tags) t
+    WHERE btrim(t, ' #') <> ''
+)
+WHERE tags IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tag_follows (
+    user_id BIGINT NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, tag),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_follows_tag ON tag_follows (tag);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    id BIGSERIAL PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id BIGINT NOT NULL,
+    author_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    "offset" INT NOT NULL,
+    length INT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE (target_type, target_id, "offset")
+);
+
+CREATE INDEX IF NOT EXISTS idx_mentions_user_id ON mentions (user_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS notification_preferences;
+
+DROP TABLE IF EXISTS notifications;
+
+DROP TABLE IF EXISTS post_reactions;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_audit_events_login;
+
+DROP TABLE IF EXISTS digest_deliveries;
+
+DROP TABLE IF EXISTS digest_preferences;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS digest_preferences (
+    user_id BIGINT PRIMARY KEY,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'weekly' CHECK (frequency IN ('off', 'daily', 'weekly')),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- one row per user and period, claimed before the mail goes out so a
+-- restart never sends the same digest twice
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+    user_id BIGINT NOT NULL,
+    frequency VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    unsubscribe_token BYTEA NOT NULL UNIQUE,
+    item_count INT NOT NULL DEFAULT 0,
+    claimed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP(0) WITH TIME ZONE,
+
+    PRIMARY KEY (user_id, frequency, period_start),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_login ON audit_events (actor_id, created_at) WHERE action = 'auth.login';
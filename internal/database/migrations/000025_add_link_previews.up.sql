@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS link_previews (
+    url TEXT PRIMARY KEY,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ok', 'failed')),
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- the first link of a post is the one unfurled
+CREATE TABLE IF NOT EXISTS post_links (
+    post_id BIGINT PRIMARY KEY,
+    url TEXT NOT NULL,
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (url) REFERENCES link_previews (url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_links_url ON post_links (url);
@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS format VARCHAR(10) NOT NULL DEFAULT 'plain'
+    CHECK (format IN ('plain', 'markdown'));
@@ -0,0 +1,4 @@
+-- set while a post waits to be published, cleared by the scheduler
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at ON posts (publish_at) WHERE publish_at IS NOT NULL;
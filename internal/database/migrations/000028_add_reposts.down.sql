@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_posts_quoted_post_id;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS quoted_post_id;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS is_quote;
+
+DROP TABLE IF EXISTS reposts;
@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS polls (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL UNIQUE,
+    multiple BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    -- set once the voters were told the poll closed
+    notified_at TIMESTAMP(0) WITH TIME ZONE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_closing ON polls (closes_at) WHERE notified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id BIGSERIAL PRIMARY KEY,
+    poll_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    label VARCHAR(100) NOT NULL,
+
+    FOREIGN KEY (poll_id) REFERENCES polls (id) ON DELETE CASCADE,
+    UNIQUE (poll_id, position)
+);
+
+-- one row per voter, the primary key is what makes a second vote fail
+CREATE TABLE IF NOT EXISTS poll_voters (
+    poll_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (poll_id, user_id),
+    FOREIGN KEY (poll_id) REFERENCES polls (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+    option_id BIGINT NOT NULL,
+    poll_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+
+    PRIMARY KEY (option_id, user_id),
+    FOREIGN KEY (option_id) REFERENCES poll_options (id) ON DELETE CASCADE,
+    FOREIGN KEY (poll_id, user_id) REFERENCES poll_voters (poll_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_poll_id ON poll_votes (poll_id, user_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS username_reservations;
+
+DROP TABLE IF EXISTS email_changes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS username_changed_at;
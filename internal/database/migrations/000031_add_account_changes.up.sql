@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS email_changes (
+    token BYTEA PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    email citext NOT NULL,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- an old username stays with its previous owner until expires_at, nobody
+-- else can register or switch to it
+CREATE TABLE IF NOT EXISTS username_reservations (
+    username VARCHAR(255) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    expires_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
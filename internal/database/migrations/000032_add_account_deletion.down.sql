@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS data_exports;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE posts
+ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id);
+
+DROP INDEX IF EXISTS idx_users_delete_after;
+
+ALTER TABLE users DROP COLUMN IF EXISTS delete_after;
@@ -0,0 +1,25 @@
+-- a user who asked to delete their account is purged after delete_after
+ALTER TABLE users ADD COLUMN IF NOT EXISTS delete_after TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE posts
+ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    storage_key TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP(0) WITH TIME ZONE,
+    expires_at TIMESTAMP(0) WITH TIME ZONE,
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_queue ON data_exports (created_at) WHERE status IN ('pending', 'processing');
+-- one export in flight per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_in_flight ON data_exports (user_id) WHERE status IN ('pending', 'processing');
@@ -0,0 +1,13 @@
+-- deleted posts and comments stay restorable until the purge job removes
+-- them. deleted_by tells an author's own delete from a moderator's.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_by BIGINT REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- purging a post or comment also removes the notifications about it
+CREATE INDEX IF NOT EXISTS idx_notifications_target ON notifications (target_type, target_id);
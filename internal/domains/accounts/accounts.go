@@ -0,0 +1,26 @@
+package accounts
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+func InitAccountDomain(db *sql.DB, cfg config.Config, storage, exports blob.Storage) AccountHandler {
+	uc := NewAccounts(db, cfg, storage, exports)
+	hdl := NewAccountHandler(uc)
+
+	return hdl
+}
+
+func NewAccounts(db *sql.DB, cfg config.Config, storage, exports blob.Storage) AccountUsecase {
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	return NewAccountUsecase(db, NewAccountRepository(db), users.NewUserRepository(db), auditUC, storage, exports, cfg)
+}
+
+func NewAccountWorker(db *sql.DB, cfg config.Config, storage, exports blob.Storage) *Worker {
+	return NewWorker(NewAccounts(db, cfg, storage, exports))
+}
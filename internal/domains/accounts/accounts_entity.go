@@ -0,0 +1,27 @@
+package accounts
+
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportReady      = "ready"
+	ExportFailed     = "failed"
+)
+
+type DeleteAccountPayload struct {
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+type Deletion struct {
+	DeleteAfter string `json:"delete_after"`
+}
+
+type Export struct {
+	ID        int64   `json:"id"`
+	UserID    int64   `json:"-"`
+	Status    string  `json:"status"`
+	Key       string  `json:"-"`
+	URL       string  `json:"download_url,omitempty"`
+	CreatedAt string  `json:"created_at"`
+	ExpiresAt *string `json:"expires_at,omitempty"`
+	Expired   bool    `json:"-"`
+}
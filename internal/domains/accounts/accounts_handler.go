@@ -0,0 +1,100 @@
+package accounts
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler interface {
+	DeleteAccountHandler(c *gin.Context)
+	RestoreAccountHandler(c *gin.Context)
+	RequestExportHandler(c *gin.Context)
+	GetExportHandler(c *gin.Context)
+	DownloadExportHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc AccountUsecase
+}
+
+func NewAccountHandler(uc AccountUsecase) AccountHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) DeleteAccountHandler(c *gin.Context) {
+	var payload DeleteAccountPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	deletion, err := h.uc.RequestDeletion(c, users.GetAuthUserFromContext(c), payload.Password)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusAccepted, deletion)
+}
+
+func (h *handler) RestoreAccountHandler(c *gin.Context) {
+	if err := h.uc.CancelDeletion(c, users.GetAuthUserFromContext(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) RequestExportHandler(c *gin.Context) {
+	export, err := h.uc.RequestExport(c, users.GetAuthUserFromContext(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusAccepted, export)
+}
+
+func (h *handler) GetExportHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	export, err := h.uc.GetExport(c, users.GetAuthUserFromContext(c), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	if export.Status == ExportReady && !export.Expired {
+		export.URL = c.Request.URL.Path + "/download"
+	}
+
+	response.ResponseData(c, http.StatusOK, export)
+}
+
+func (h *handler) DownloadExportHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	data, err := h.uc.DownloadExport(c, users.GetAuthUserFromContext(c), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, id))
+	c.Data(http.StatusOK, "application/zip", data)
+}
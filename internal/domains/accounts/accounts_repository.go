@@ -0,0 +1,344 @@
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type AccountRepository interface {
+	ScheduleDeletion(ctx context.Context, tx *sql.Tx, userID int64, at time.Time) (string, error)
+	CancelDeletion(ctx context.Context, tx *sql.Tx, userID int64) error
+	ListDue(ctx context.Context, now time.Time, limit int) ([]int64, error)
+	LockDue(ctx context.Context, tx *sql.Tx, userID int64) error
+	DeleteFiles(ctx context.Context, tx *sql.Tx, userID int64) (uploads, exports []string, err error)
+
+	CreateExport(ctx context.Context, userID int64) (*Export, error)
+	GetExport(ctx context.Context, userID, id int64) (*Export, error)
+	ClaimExports(ctx context.Context, limit int) ([]Export, error)
+	Collect(ctx context.Context, userID int64) (map[string]json.RawMessage, error)
+	FinishExport(ctx context.Context, id int64, key string, expiresAt time.Time) error
+	FailExport(ctx context.Context, id int64) error
+	ListExpiredExports(ctx context.Context, now time.Time, limit int) ([]Export, error)
+	DeleteExport(ctx context.Context, id int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) AccountRepository {
+	return &repository{db: db}
+}
+
+// ScheduleDeletion keeps an earlier deletion time if one is already set.
+func (r *repository) ScheduleDeletion(ctx context.Context, tx *sql.Tx, userID int64, at time.Time) (string, error) {
+	query := `
+		UPDATE users SET delete_after = COALESCE(delete_after, $2)
+		WHERE id = $1
+		RETURNING delete_after
+	`
+	var deleteAfter string
+	err := tx.QueryRowContext(ctx, query, userID, at).Scan(&deleteAfter)
+	return deleteAfter, err
+}
+
+// CancelDeletion returns sql.ErrNoRows when no deletion is pending.
+func (r *repository) CancelDeletion(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `UPDATE users SET delete_after = NULL WHERE id = $1 AND delete_after IS NOT NULL`
+
+	res, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *repository) ListDue(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	query := `
+		SELECT id FROM users
+		WHERE delete_after <= $1
+		ORDER BY delete_after
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// LockDue locks a user that is still due for deletion. It returns
+// sql.ErrNoRows when the deletion was cancelled in the meantime or another
+// instance is purging the user.
+func (r *repository) LockDue(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `
+		SELECT id FROM users
+		WHERE id = $1 AND delete_after <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`
+	var id int64
+	return tx.QueryRowContext(ctx, query, userID).Scan(&id)
+}
+
+// DeleteFiles removes the rows of every stored file of the user and
+// returns the storage keys of the uploads and of the exports.
+func (r *repository) DeleteFiles(ctx context.Context, tx *sql.Tx, userID int64) (uploads, exports []string, err error) {
+	query := `
+		WITH uploads AS (
+			DELETE FROM attachments WHERE user_id = $1
+			RETURNING storage_key, thumbnail_key
+		), exports AS (
+			DELETE FROM data_exports WHERE user_id = $1 AND storage_key <> ''
+			RETURNING storage_key
+		)
+		SELECT storage_key, false FROM uploads
+		UNION ALL SELECT thumbnail_key, false FROM uploads
+		UNION ALL SELECT storage_key, true FROM exports
+	`
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var export bool
+		if err := rows.Scan(&key, &export); err != nil {
+			return nil, nil, err
+		}
+
+		if export {
+			exports = append(exports, key)
+		} else {
+			uploads = append(uploads, key)
+		}
+	}
+
+	return uploads, exports, rows.Err()
+}
+
+func (r *repository) CreateExport(ctx context.Context, userID int64) (*Export, error) {
+	query := `
+		INSERT INTO data_exports (user_id) VALUES ($1)
+		RETURNING id, status, created_at
+	`
+	e := Export{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&e.ID, &e.Status, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (r *repository) GetExport(ctx context.Context, userID, id int64) (*Export, error) {
+	query := `
+		SELECT id, user_id, status, storage_key, created_at, expires_at,
+			COALESCE(expires_at <= NOW(), false)
+		FROM data_exports WHERE id = $1 AND user_id = $2
+	`
+	var e Export
+	err := r.db.QueryRowContext(ctx, query, id, userID).Scan(
+		&e.ID,
+		&e.UserID,
+		&e.Status,
+		&e.Key,
+		&e.CreatedAt,
+		&e.ExpiresAt,
+		&e.Expired,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// ClaimExports marks up to limit pending exports as processing and returns
+// them. An export stuck in processing, after a crash, is claimed again.
+func (r *repository) ClaimExports(ctx context.Context, limit int) ([]Export, error) {
+	query := `
+		UPDATE data_exports SET status = 'processing', started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' OR (status = 'processing' AND started_at < NOW() - INTERVAL '1 hour')
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []Export
+	for rows.Next() {
+		var e Export
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Status, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+
+	return exports, rows.Err()
+}
+
+// Collect reads every export section from one snapshot, so the sections
+// agree with each other.
+func (r *repository) Collect(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	data := make(map[string]json.RawMessage, len(exportSections))
+	for _, section := range exportSections {
+		var doc []byte
+		if err := tx.QueryRowContext(ctx, section.query, userID).Scan(&doc); err != nil {
+			return nil, err
+		}
+		data[section.name] = doc
+	}
+
+	return data, nil
+}
+
+func (r *repository) FinishExport(ctx context.Context, id int64, key string, expiresAt time.Time) error {
+	query := `UPDATE data_exports SET status = 'ready', storage_key = $2, expires_at = $3 WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, key, expiresAt)
+	return err
+}
+
+func (r *repository) FailExport(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE data_exports SET status = 'failed' WHERE id = $1`, id)
+	return err
+}
+
+func (r *repository) ListExpiredExports(ctx context.Context, now time.Time, limit int) ([]Export, error) {
+	query := `
+		SELECT id, user_id, status, storage_key, created_at, expires_at
+		FROM data_exports
+		WHERE expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exports []Export
+	for rows.Next() {
+		var e Export
+		err := rows.Scan(&e.ID, &e.UserID, &e.Status, &e.Key, &e.CreatedAt, &e.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *repository) DeleteExport(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM data_exports WHERE id = $1`, id)
+	return err
+}
+
+// exportSections are the parts of data.json in an export, each query takes
+// the user id and returns a JSON document.
+var exportSections = []struct {
+	name  string
+	query string
+}{
+	{"profile", `
+		SELECT row_to_json(t) FROM (
+			SELECT id, username, email, display_name, bio, avatar_url, location, website, created_at
+			FROM users WHERE id = $1
+		) t
+	`},
+	{"posts", `
+		SELECT COALESCE(json_agg(t ORDER BY t.id), '[]') FROM (
+			SELECT id, title, content, format, tags, quoted_post_id, created_at, updated_at, publish_at, hidden_at, deleted_at
+			FROM posts WHERE user_id = $1
+		) t
+	`},
+	{"comments", `
+		SELECT COALESCE(json_agg(t ORDER BY t.id), '[]') FROM (
+			SELECT id, post_id, parent_id, content, created_at, deleted_at
+			FROM comments WHERE user_id = $1
+		) t
+	`},
+	{"reactions", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT post_id, kind, created_at FROM post_reactions WHERE user_id = $1
+		) t
+	`},
+	{"reposts", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT post_id, created_at FROM reposts WHERE user_id = $1
+		) t
+	`},
+	{"following", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT u.id, u.username, f.created_at
+			FROM followers f JOIN users u ON u.id = f.user_id
+			WHERE f.follower_id = $1
+		) t
+	`},
+	{"followers", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT u.id, u.username, f.created_at
+			FROM followers f JOIN users u ON u.id = f.follower_id
+			WHERE f.user_id = $1
+		) t
+	`},
+	{"blocked", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT u.id, u.username, b.created_at
+			FROM user_blocks b JOIN users u ON u.id = b.blocked_id
+			WHERE b.blocker_id = $1
+		) t
+	`},
+	{"tag_follows", `
+		SELECT COALESCE(json_agg(t ORDER BY t.created_at), '[]') FROM (
+			SELECT tag, created_at FROM tag_follows WHERE user_id = $1
+		) t
+	`},
+	{"attachments", `
+		SELECT COALESCE(json_agg(t ORDER BY t.id), '[]') FROM (
+			SELECT id, post_id, storage_key, content_type, size, width, height, created_at
+			FROM attachments WHERE user_id = $1
+		) t
+	`},
+}
@@ -0,0 +1,339 @@
+package accounts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+// exportTimeout bounds building and uploading one export.
+const exportTimeout = 2 * time.Minute
+
+type AccountUsecase interface {
+	RequestDeletion(ctx context.Context, user *users.User, password string) (*Deletion, error)
+	CancelDeletion(ctx context.Context, user *users.User) error
+	RequestExport(ctx context.Context, user *users.User) (*Export, error)
+	GetExport(ctx context.Context, user *users.User, id int64) (*Export, error)
+	DownloadExport(ctx context.Context, user *users.User, id int64) ([]byte, error)
+
+	PurgeDue(ctx context.Context, limit int) (int, error)
+	ProcessExports(ctx context.Context, limit int) (int, error)
+	CleanupExports(ctx context.Context, limit int) (int, error)
+}
+
+type usecase struct {
+	db      *sql.DB
+	repo    AccountRepository
+	users   users.UserRepository
+	audit   audit.AuditUsecase
+	storage blob.Storage
+	// exports is private storage, exports are only fetched through
+	// DownloadExport
+	exports blob.Storage
+	cfg     config.UsersConfig
+}
+
+func NewAccountUsecase(db *sql.DB, repo AccountRepository, users users.UserRepository, audit audit.AuditUsecase, storage, exports blob.Storage, cfg config.Config) AccountUsecase {
+	return &usecase{
+		db:      db,
+		repo:    repo,
+		users:   users,
+		audit:   audit,
+		storage: storage,
+		exports: exports,
+		cfg:     cfg.Users,
+	}
+}
+
+// RequestDeletion schedules the account for deletion after the grace
+// period. Until then the user can still log in and cancel.
+func (uc *usecase) RequestDeletion(ctx context.Context, user *users.User, password string) (*Deletion, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := users.CheckPassword(ctx, uc.users, user.ID, password); err != nil {
+		return nil, err
+	}
+
+	var d Deletion
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		var err error
+		d.DeleteAfter, err = uc.repo.ScheduleDeletion(ctx, tx, user.ID, time.Now().Add(uc.cfg.DeletionGrace))
+		if err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserDeleteRequest,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+			Diff: map[string]audit.Change{
+				"delete_after": {To: d.DeleteAfter},
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (uc *usecase) CancelDeletion(ctx context.Context, user *users.User) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if err := uc.repo.CancelDeletion(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserDeleteCancel,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+		})
+	})
+	if err == sql.ErrNoRows {
+		return commons.ErrNotFound
+	}
+
+	return err
+}
+
+func (uc *usecase) RequestExport(ctx context.Context, user *users.User) (*Export, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	e, err := uc.repo.CreateExport(ctx, user.ID)
+	if err != nil {
+		return nil, commons.DBError(err)
+	}
+
+	return e, nil
+}
+
+func (uc *usecase) GetExport(ctx context.Context, user *users.User, id int64) (*Export, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	e, err := uc.repo.GetExport(ctx, user.ID, id)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// DownloadExport returns the zip of a ready export that has not expired
+// yet.
+func (uc *usecase) DownloadExport(ctx context.Context, user *users.User, id int64) ([]byte, error) {
+	e, err := uc.GetExport(ctx, user, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Status != ExportReady || e.Expired {
+		return nil, commons.ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	data, err := uc.exports.Get(ctx, e.Key)
+	if err != nil {
+		switch err {
+		case blob.ErrNotFound:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// PurgeDue hard deletes up to limit accounts whose grace period is over.
+// Files are removed after the rows are gone, a failure there only leaves
+// an unreachable file behind.
+func (uc *usecase) PurgeDue(ctx context.Context, limit int) (int, error) {
+	due, err := uc.repo.ListDue(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, userID := range due {
+		var uploads, exports []string
+		err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+			if err := uc.repo.LockDue(ctx, tx, userID); err != nil {
+				return err
+			}
+
+			var err error
+			if uploads, exports, err = uc.repo.DeleteFiles(ctx, tx, userID); err != nil {
+				return err
+			}
+
+			if err := uc.users.Delete(ctx, tx, userID); err != nil {
+				return err
+			}
+
+			return uc.audit.Record(ctx, tx, &audit.Event{
+				ActorID:    audit.ID(userID),
+				Action:     audit.ActionUserDelete,
+				TargetType: audit.TargetUser,
+				TargetID:   audit.ID(userID),
+			})
+		})
+		switch {
+		case err == sql.ErrNoRows:
+			continue
+		case err != nil:
+			return purged, err
+		}
+		purged++
+
+		for _, key := range uploads {
+			if err := uc.storage.Delete(ctx, key); err != nil {
+				log.Println("failed to delete file of purged user:", userID, key, err)
+			}
+		}
+		for _, key := range exports {
+			if err := uc.exports.Delete(ctx, key); err != nil {
+				log.Println("failed to delete export of purged user:", userID, key, err)
+			}
+		}
+	}
+
+	return purged, nil
+}
+
+// ProcessExports builds up to limit pending exports.
+func (uc *usecase) ProcessExports(ctx context.Context, limit int) (int, error) {
+	exports, err := uc.repo.ClaimExports(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range exports {
+		if err := uc.buildExport(ctx, &e); err != nil {
+			log.Println("failed to build data export:", e.ID, err)
+
+			if err := uc.repo.FailExport(ctx, e.ID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return len(exports), nil
+}
+
+// CleanupExports deletes up to limit exports past their expiry.
+func (uc *usecase) CleanupExports(ctx context.Context, limit int) (int, error) {
+	expired, err := uc.repo.ListExpiredExports(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range expired {
+		if err := uc.exports.Delete(ctx, e.Key); err != nil {
+			log.Println("failed to delete expired export:", e.ID, err)
+			continue
+		}
+
+		if err := uc.repo.DeleteExport(ctx, e.ID); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (uc *usecase) buildExport(ctx context.Context, e *Export) error {
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	data, err := uc.repo.Collect(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	if data["attachments"], err = uc.attachmentURLs(data["attachments"]); err != nil {
+		return err
+	}
+
+	doc, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(doc); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/%d.zip", e.UserID, e.ID)
+
+	if err := uc.exports.Put(ctx, key, "application/zip", buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := uc.repo.FinishExport(ctx, e.ID, key, time.Now().Add(uc.cfg.ExportTTL)); err != nil {
+		if err := uc.exports.Delete(ctx, key); err != nil {
+			log.Println("failed to delete unfinished export:", key, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// attachmentURLs swaps the storage keys in the attachments section for
+// download URLs.
+func (uc *usecase) attachmentURLs(section json.RawMessage) (json.RawMessage, error) {
+	var attachments []map[string]any
+	if err := json.Unmarshal(section, &attachments); err != nil {
+		return nil, err
+	}
+
+	for _, a := range attachments {
+		if key, ok := a["storage_key"].(string); ok {
+			a["url"] = uc.storage.URL(key)
+		}
+		delete(a, "storage_key")
+	}
+
+	return json.Marshal(attachments)
+}
@@ -0,0 +1,76 @@
+package accounts
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+// fakeRepository holds the exports of user 7. The embedded interface
+// panics on anything the tests do not use.
+type fakeRepository struct {
+	AccountRepository
+
+	exports map[int64]Export
+}
+
+func (r *fakeRepository) GetExport(ctx context.Context, userID, id int64) (*Export, error) {
+	e, ok := r.exports[id]
+	if !ok || e.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	return &e, nil
+}
+
+func TestDownloadExport(t *testing.T) {
+	ctx := context.Background()
+	exports := blob.NewLocalStorage(t.TempDir(), "")
+
+	zip := []byte("zip of user 7")
+	if err := exports.Put(ctx, "exports/7/1.zip", "application/zip", zip); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeRepository{exports: map[int64]Export{
+		1: {ID: 1, UserID: 7, Status: ExportReady, Key: "exports/7/1.zip"},
+		2: {ID: 2, UserID: 7, Status: ExportPending},
+		3: {ID: 3, UserID: 7, Status: ExportReady, Key: "exports/7/3.zip", Expired: true},
+		4: {ID: 4, UserID: 7, Status: ExportReady, Key: "exports/7/4.zip"},
+		5: {ID: 5, UserID: 8, Status: ExportReady, Key: "exports/8/5.zip"},
+	}}
+	uc := NewAccountUsecase(nil, repo, nil, nil, nil, exports, config.Config{})
+
+	data, err := uc.DownloadExport(ctx, &users.User{ID: 7}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, zip) {
+		t.Errorf("DownloadExport = %q, want %q", data, zip)
+	}
+
+	tests := []struct {
+		name string
+		id   int64
+	}{
+		{"not built yet", 2},
+		{"expired", 3},
+		{"file gone", 4},
+		{"export of another user", 5},
+		{"no such export", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := uc.DownloadExport(ctx, &users.User{ID: 7}, tt.id); !errors.Is(err, commons.ErrNotFound) {
+				t.Errorf("DownloadExport error = %v, want %v", err, commons.ErrNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package accounts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	workerInterval  = time.Minute
+	purgeBatchSize  = 20
+	exportBatchSize = 5
+	expireBatchSize = 100
+)
+
+// Worker purges accounts whose grace period is over, builds requested data
+// exports and removes expired ones.
+type Worker struct {
+	uc AccountUsecase
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorker(uc AccountUsecase) *Worker {
+	return &Worker{uc: uc}
+}
+
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(workerInterval)
+		defer ticker.Stop()
+
+		for {
+			w.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *Worker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+func (w *Worker) run(ctx context.Context) {
+	if _, err := w.uc.PurgeDue(ctx, purgeBatchSize); err != nil {
+		log.Println("failed to purge deleted accounts:", err)
+	}
+
+	if _, err := w.uc.ProcessExports(ctx, exportBatchSize); err != nil {
+		log.Println("failed to process data exports:", err)
+	}
+
+	if _, err := w.uc.CleanupExports(ctx, expireBatchSize); err != nil {
+		log.Println("failed to clean up data exports:", err)
+	}
+}
@@ -0,0 +1,23 @@
+package admin
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/cache"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+)
+
+func InitAdminDomain(db *sql.DB, cfg config.Config, cache *cache.Storage, mailer mailer.Client) AdminHandler {
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+
+	userrepo := users.NewUserRepository(db)
+	useruc := users.NewUserUsecase(db, userrepo, auditUC, nil, nil, nil, cfg)
+
+	uc := NewAdminUsecase(db, userrepo, useruc, auditUC, cache, mailer, cfg)
+	hdl := NewAdminHandler(uc)
+
+	return hdl
+}
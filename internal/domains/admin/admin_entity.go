@@ -0,0 +1,9 @@
+package admin
+
+type ChangeRolePayload struct {
+	Role string `json:"role" binding:"required,max=255"`
+}
+
+type BanUserPayload struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type AdminHandler interface {
+	ListUsersHandler(c *gin.Context)
+	GetUserHandler(c *gin.Context)
+	ChangeRoleHandler(c *gin.Context)
+	ActivateUserHandler(c *gin.Context)
+	DeactivateUserHandler(c *gin.Context)
+	BanUserHandler(c *gin.Context)
+	UnbanUserHandler(c *gin.Context)
+	ForcePasswordResetHandler(c *gin.Context)
+	DeleteUserHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc AdminUsecase
+}
+
+func NewAdminHandler(uc AdminUsecase) AdminHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) ListUsersHandler(c *gin.Context) {
+	q := users.UserQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	list, err := h.uc.ListUsers(c, q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, list)
+}
+
+func (h *handler) GetUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.GetUser(c, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+func (h *handler) ChangeRoleHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload ChangeRolePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.ChangeRole(c, users.GetAuthUserFromContext(c), id, payload.Role)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+func (h *handler) ActivateUserHandler(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *handler) DeactivateUserHandler(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *handler) setActive(c *gin.Context, active bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.SetActive(c, users.GetAuthUserFromContext(c), id, active)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+func (h *handler) BanUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload BanUserPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.Ban(c, users.GetAuthUserFromContext(c), id, payload.Reason)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+func (h *handler) UnbanUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.Unban(c, users.GetAuthUserFromContext(c), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+func (h *handler) ForcePasswordResetHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.ForcePasswordReset(c, users.GetAuthUserFromContext(c), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusAccepted, nil)
+}
+
+func (h *handler) DeleteUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.DeleteUser(c, users.GetAuthUserFromContext(c), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
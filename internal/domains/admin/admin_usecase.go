@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/cache"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+	"github.com/google/uuid"
+)
+
+type AdminUsecase interface {
+	ListUsers(ctx context.Context, q users.UserQuery) ([]users.User, error)
+	GetUser(ctx context.Context, userID int64) (*users.User, error)
+	ChangeRole(ctx context.Context, actor *users.User, userID int64, roleName string) (*users.User, error)
+	SetActive(ctx context.Context, actor *users.User, userID int64, active bool) (*users.User, error)
+	Ban(ctx context.Context, actor *users.User, userID int64, reason string) (*users.User, error)
+	Unban(ctx context.Context, actor *users.User, userID int64) (*users.User, error)
+	ForcePasswordReset(ctx context.Context, actor *users.User, userID int64) error
+	DeleteUser(ctx context.Context, actor *users.User, userID int64) error
+}
+
+type usecase struct {
+	db       *sql.DB
+	userRepo users.UserRepository
+	users    users.UserUsecase
+	audit    audit.AuditUsecase
+	cache    *cache.Storage
+	mailer   mailer.Client
+	config   config.Config
+}
+
+// NewAdminUsecase accepts a nil cache and mailer, like the middleware and
+// the users usecase.
+func NewAdminUsecase(db *sql.DB, userRepo users.UserRepository, users users.UserUsecase, audit audit.AuditUsecase, cache *cache.Storage, mailer mailer.Client, config config.Config) AdminUsecase {
+	return &usecase{
+		db:       db,
+		userRepo: userRepo,
+		users:    users,
+		audit:    audit,
+		cache:    cache,
+		mailer:   mailer,
+		config:   config,
+	}
+}
+
+func (uc *usecase) ListUsers(ctx context.Context, q users.UserQuery) ([]users.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.userRepo.List(ctx, q)
+}
+
+func (uc *usecase) GetUser(ctx context.Context, userID int64) (*users.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	user, err := uc.userRepo.GetAccount(ctx, userID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (uc *usecase) ChangeRole(ctx context.Context, actor *users.User, userID int64, roleName string) (*users.User, error) {
+	role, err := uc.users.GetRoleByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	// nobody can hand out more power than they have
+	if role.Level > actor.Role.Level {
+		return nil, commons.ErrForbidden
+	}
+
+	return uc.mutate(ctx, actor, userID, func(tx *sql.Tx, target *users.User) (*audit.Event, error) {
+		if err := uc.userRepo.UpdateRole(ctx, tx, userID, role.Name); err != nil {
+			return nil, err
+		}
+
+		return &audit.Event{
+			Action: audit.ActionUserRole,
+			Diff: map[string]audit.Change{
+				"role": {From: target.Role.Name, To: role.Name},
+			},
+		}, nil
+	})
+}
+
+func (uc *usecase) SetActive(ctx context.Context, actor *users.User, userID int64, active bool) (*users.User, error) {
+	return uc.mutate(ctx, actor, userID, func(tx *sql.Tx, target *users.User) (*audit.Event, error) {
+		if err := uc.userRepo.SetActive(ctx, tx, userID, active); err != nil {
+			return nil, err
+		}
+
+		action := audit.ActionUserActivate
+		if !active {
+			action = audit.ActionUserDeactivate
+		}
+
+		return &audit.Event{
+			Action: action,
+			Diff: map[string]audit.Change{
+				"is_active": {From: target.IsActive, To: active},
+			},
+		}, nil
+	})
+}
+
+func (uc *usecase) Ban(ctx context.Context, actor *users.User, userID int64, reason string) (*users.User, error) {
+	return uc.mutate(ctx, actor, userID, func(tx *sql.Tx, target *users.User) (*audit.Event, error) {
+		if err := uc.userRepo.SetBanned(ctx, tx, userID, true, reason); err != nil {
+			return nil, err
+		}
+
+		return &audit.Event{
+			Action: audit.ActionUserBan,
+			Diff: map[string]audit.Change{
+				"banned":     {From: target.BannedAt != nil, To: true},
+				"ban_reason": {From: target.BanReason, To: reason},
+			},
+		}, nil
+	})
+}
+
+func (uc *usecase) Unban(ctx context.Context, actor *users.User, userID int64) (*users.User, error) {
+	return uc.mutate(ctx, actor, userID, func(tx *sql.Tx, target *users.User) (*audit.Event, error) {
+		if err := uc.userRepo.SetBanned(ctx, tx, userID, false, ""); err != nil {
+			return nil, err
+		}
+
+		return &audit.Event{
+			Action: audit.ActionUserUnban,
+			Diff: map[string]audit.Change{
+				"banned":     {From: target.BannedAt != nil, To: false},
+				"ban_reason": {From: target.BanReason, To: ""},
+			},
+		}, nil
+	})
+}
+
+// ForcePasswordReset mails the reset link to the user. The token is never
+// shown to the admin, who could otherwise take over the account.
+func (uc *usecase) ForcePasswordReset(ctx context.Context, actor *users.User, userID int64) error {
+	plainToken := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(plainToken))
+	hashToken := hex.EncodeToString(hash[:])
+
+	target, err := uc.mutate(ctx, actor, userID, func(tx *sql.Tx, target *users.User) (*audit.Event, error) {
+		if err := uc.userRepo.CreatePasswordReset(ctx, tx, userID, hashToken, uc.config.Mail.Exp); err != nil {
+			return nil, err
+		}
+
+		return &audit.Event{
+			Action: audit.ActionUserForceReset,
+			Diff: map[string]audit.Change{
+				"password_reset_required": {From: target.PasswordResetRequired, To: true},
+			},
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if uc.mailer == nil {
+		log.Println("mailer is not configured, skipping", mailer.PasswordResetTemplate, "to user", target.Username)
+		return nil
+	}
+
+	isSandbox := uc.config.App.Env != "production"
+	return uc.mailer.Send(mailer.PasswordResetTemplate, target.Username, target.Email, map[string]string{
+		"Username": target.Username,
+		"ResetURL": uc.config.App.FrontendURL + "/password-reset/" + plainToken,
+	}, isSandbox)
+}
+
+func (uc *usecase) DeleteUser(ctx context.Context, actor *users.User, userID int64) error {
+	target, err := uc.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTarget(actor, target); err != nil {
+		return err
+	}
+
+	if err := uc.users.Delete(ctx, actor.ID, userID); err != nil {
+		return err
+	}
+
+	uc.evict(ctx, userID)
+
+	return nil
+}
+
+// mutate runs fn and its audit event in one transaction and returns the
+// updated account.
+func (uc *usecase) mutate(ctx context.Context, actor *users.User, userID int64, fn func(tx *sql.Tx, target *users.User) (*audit.Event, error)) (*users.User, error) {
+	target, err := uc.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTarget(actor, target); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		event, err := fn(tx, target)
+		if err != nil {
+			return err
+		}
+
+		event.ActorID = audit.ID(actor.ID)
+		event.TargetType = audit.TargetUser
+		event.TargetID = audit.ID(target.ID)
+
+		return uc.audit.Record(ctx, tx, event)
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	// the middleware would keep serving the old role and status
+	uc.evict(ctx, userID)
+
+	return uc.GetUser(ctx, userID)
+}
+
+// evict drops the cached account, a failure only delays the change until
+// the entry expires.
+func (uc *usecase) evict(ctx context.Context, userID int64) {
+	if uc.cache == nil {
+		return
+	}
+
+	if err := uc.cache.Users.Delete(ctx, userID); err != nil {
+		log.Println("failed to evict cached user:", userID, err)
+	}
+}
+
+// checkTarget stops admins from acting on themselves or on accounts with a
+// higher role.
+func checkTarget(actor, target *users.User) error {
+	if actor.ID == target.ID || target.Role.Level > actor.Role.Level {
+		return commons.ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package audit
+
+import "database/sql"
+
+func InitAuditDomain(db *sql.DB) AuditHandler {
+	repo := NewAuditRepository(db)
+	uc := NewAuditUsecase(repo)
+	hdl := NewAuditHandler(uc)
+
+	return hdl
+}
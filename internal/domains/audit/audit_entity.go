@@ -0,0 +1,66 @@
+package audit
+
+const (
+	ActionLogin             = "auth.login"
+	ActionLoginFailed       = "auth.login_failed"
+	ActionUserActivate      = "user.activate"
+	ActionUserDeactivate    = "user.deactivate"
+	ActionUserBan           = "user.ban"
+	ActionUserUnban         = "user.unban"
+	ActionUserRole          = "user.role_change"
+	ActionUserForceReset    = "user.force_password_reset"
+	ActionUserPasswordReset = "user.password_reset"
+	ActionUserDelete        = "user.delete"
+	ActionUserDeleteRequest = "user.delete_request"
+	ActionUserDeleteCancel  = "user.delete_cancel"
+	ActionUserEmailChange   = "user.email_change"
+	ActionUserRename        = "user.username_change"
+	ActionPostUpdate        = "post.update"
+	ActionPostDelete        = "post.delete"
+	ActionCommentDelete     = "comment.delete"
+
+	ActionModerationDismiss = "moderation.dismiss"
+	ActionModerationHide    = "moderation.hide"
+	ActionModerationDelete  = "moderation.delete"
+	ActionModerationWarn    = "moderation.warn"
+	ActionModerationSuspend = "moderation.suspend"
+
+	TargetUser    = "user"
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+type Event struct {
+	ID         int64             `json:"id"`
+	ActorID    *int64            `json:"actor_id"`
+	Action     string            `json:"action"`
+	TargetType string            `json:"target_type"`
+	TargetID   *int64            `json:"target_id"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"user_agent"`
+	Diff       map[string]Change `json:"diff"`
+	CreatedAt  string            `json:"created_at"`
+}
+
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// EventFilter is bound from the query string. Since and Until use the
+// time.DateTime layout.
+type EventFilter struct {
+	ActorID    int64  `form:"actor_id"`
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetID   int64  `form:"target_id"`
+	Since      string `form:"since" binding:"omitempty,datetime=2006-01-02 15:04:05"`
+	Until      string `form:"until" binding:"omitempty,datetime=2006-01-02 15:04:05"`
+	Limit      int    `form:"limit" binding:"gte=1,lte=100"`
+	Offset     int    `form:"offset" binding:"gte=0"`
+}
+
+// ID is a small helper for the optional actor and target columns.
+func ID(id int64) *int64 {
+	return &id
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler interface {
+	ListEventsHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc AuditUsecase
+}
+
+func NewAuditHandler(uc AuditUsecase) AuditHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) ListEventsHandler(c *gin.Context) {
+	filter := EventFilter{
+		Limit:  50,
+		Offset: 0,
+	}
+
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	events, err := h.uc.List(c, filter)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, events)
+}
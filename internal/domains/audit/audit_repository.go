@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, tx *sql.Tx, event *Event) error
+	List(ctx context.Context, filter EventFilter) ([]Event, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, tx *sql.Tx, event *Event) error {
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, ip, user_agent, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at
+	`
+	diff, err := json.Marshal(event.Diff)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		event.ActorID,
+		event.Action,
+		event.TargetType,
+		event.TargetID,
+		event.IP,
+		event.UserAgent,
+		diff,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) List(ctx context.Context, f EventFilter) ([]Event, error) {
+	query := `
+		SELECT id, actor_id, action, target_type, target_id, ip, user_agent, diff, created_at
+		FROM audit_events
+		WHERE
+			($1 = 0 OR actor_id = $1) AND
+			($2 = '' OR action = $2) AND
+			($3 = '' OR target_type = $3) AND
+			($4 = 0 OR target_id = $4) AND
+			($5 = '' OR created_at >= $5::timestamptz) AND
+			($6 = '' OR created_at <= $6::timestamptz)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7 OFFSET $8
+	`
+	rows, err := r.db.QueryContext(ctx, query, f.ActorID, f.Action, f.TargetType, f.TargetID, f.Since, f.Until, f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		var diff []byte
+		err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&e.UserAgent,
+			&diff,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(diff, &e.Diff); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditUsecase interface {
+	// Record appends the event inside tx, so it is only kept when the
+	// audited action commits.
+	Record(ctx context.Context, tx *sql.Tx, event *Event) error
+	List(ctx context.Context, filter EventFilter) ([]Event, error)
+}
+
+type usecase struct {
+	repo AuditRepository
+}
+
+func NewAuditUsecase(repo AuditRepository) AuditUsecase {
+	return &usecase{repo: repo}
+}
+
+func (uc *usecase) Record(ctx context.Context, tx *sql.Tx, event *Event) error {
+	// request metadata comes from the gin context the usecases receive
+	if c, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok {
+		if event.IP == "" {
+			event.IP = c.ClientIP()
+		}
+		if event.UserAgent == "" {
+			event.UserAgent = c.Request.UserAgent()
+		}
+	}
+
+	if event.Diff == nil {
+		event.Diff = map[string]Change{}
+	}
+
+	return uc.repo.Create(ctx, tx, event)
+}
+
+func (uc *usecase) List(ctx context.Context, filter EventFilter) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx, filter)
+}
@@ -1,19 +1,52 @@
 package authdomain
 
 import (
+	"context"
 	"database/sql"
+	"log"
+	"time"
 
 	"github.com/codepnw/gopher-social/cmd/config"
 	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
 	"github.com/codepnw/gopher-social/internal/domains/users"
 )
 
 func InitAuthDomain(db *sql.DB, cfg config.Config, jwt *auth.JWTAuthenticator) AuthHandler {
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+
 	userrepo := users.NewUserRepository(db)
-	useruc := users.NewUserUsecase(db, userrepo, cfg)
+	useruc := users.NewUserUsecase(db, userrepo, auditUC, nil, nil, nil, cfg)
 
-	uc := NewAuthUsecase(useruc)
-	hdl := NewAuthHandler(uc, cfg, jwt)
+	repo := NewAuthRepository(db)
+	uc := NewAuthUsecase(db, repo, auditUC, useruc, userrepo)
+	hdl := NewAuthHandler(uc, cfg, jwt, initOIDCProvider(cfg.Auth.OIDC))
 
 	return hdl
 }
+
+func initOIDCProvider(cfg config.OIDCConfig) *auth.OIDCProvider {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	provider, err := auth.NewOIDCProvider(
+		ctx,
+		cfg.Provider,
+		cfg.IssuerURL,
+		cfg.ClientID,
+		cfg.ClientSecret,
+		cfg.RedirectURL,
+		cfg.Scopes,
+		nil,
+	)
+	if err != nil {
+		log.Println("oidc provider disabled:", err)
+		return nil
+	}
+
+	return provider
+}
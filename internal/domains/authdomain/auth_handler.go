@@ -2,35 +2,47 @@ package authdomain
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/codepnw/gopher-social/cmd/config"
 	"github.com/codepnw/gopher-social/internal/auth"
-	"github.com/codepnw/gopher-social/internal/domains/commons"
 	"github.com/codepnw/gopher-social/internal/utils/response"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+const (
+	oidcStateCookie    = "oidc_state"
+	oidcNonceCookie    = "oidc_nonce"
+	oidcVerifierCookie = "oidc_verifier"
+)
+
 type AuthHandler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	OIDCLogin(c *gin.Context)
+	OIDCCallback(c *gin.Context)
 }
 
 type handler struct {
 	uc     AuthUsecase
 	config config.Config
 	jwt    *auth.JWTAuthenticator
+	oidc   *auth.OIDCProvider
 }
 
-func NewAuthHandler(uc AuthUsecase, config config.Config, jwt *auth.JWTAuthenticator) AuthHandler {
+func NewAuthHandler(uc AuthUsecase, config config.Config, jwt *auth.JWTAuthenticator, oidc *auth.OIDCProvider) AuthHandler {
 	return &handler{
 		uc:     uc,
 		config: config,
 		jwt:    jwt,
+		oidc:   oidc,
 	}
 }
 
@@ -47,14 +59,7 @@ func (h *handler) Register(c *gin.Context) {
 	hashToken := hex.EncodeToString(hash[:])
 
 	if err := h.uc.Register(c, &payload, hashToken, h.config.Auth.JWTExp); err != nil {
-		switch err {
-		case commons.ErrDuplicateEmail:
-			response.BadRequestResponse(c, err)
-		case commons.ErrDuplicateUsername:
-			response.BadRequestResponse(c, err)
-		default:
-			response.InternalServerError(c, err)
-		}
+		response.Error(c, err)
 		return
 	}
 
@@ -72,17 +77,110 @@ func (h *handler) Login(c *gin.Context) {
 
 	user, err := h.uc.GetUser(c, payload)
 	if err != nil {
-		switch err {
-		case commons.ErrInvalidEmailPassword:
-			response.BadRequestResponse(c, err)
-		default:
-			response.InternalServerError(c, err)
-		}
+		response.Error(c, err)
+		return
+	}
+
+	token, err := h.generateToken(user.ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, token)
+}
+
+func (h *handler) OIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		response.Error(c, auth.ErrOIDCDisabled)
+		return
+	}
+
+	state, err := auth.RandomString(32)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	nonce, err := auth.RandomString(32)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	verifier, err := auth.RandomString(48)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	maxAge := int(h.config.Auth.OIDC.StateExp.Seconds())
+	secure := h.config.App.Env != "development"
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/", "", secure, true)
+	c.SetCookie(oidcNonceCookie, nonce, maxAge, "/", "", secure, true)
+	c.SetCookie(oidcVerifierCookie, verifier, maxAge, "/", "", secure, true)
+
+	c.Redirect(http.StatusFound, h.oidc.AuthCodeURL(state, nonce, auth.PKCEChallenge(verifier)))
+}
+
+func (h *handler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		response.Error(c, auth.ErrOIDCDisabled)
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		response.BadRequestResponse(c, fmt.Errorf("identity provider error: %s", errCode))
+		return
+	}
+
+	state, _ := c.Cookie(oidcStateCookie)
+	nonce, _ := c.Cookie(oidcNonceCookie)
+	verifier, _ := c.Cookie(oidcVerifierCookie)
+
+	// the login cookies are single use
+	secure := h.config.App.Env != "development"
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", secure, true)
+	c.SetCookie(oidcNonceCookie, "", -1, "/", "", secure, true)
+	c.SetCookie(oidcVerifierCookie, "", -1, "/", "", secure, true)
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		response.BadRequestResponse(c, errors.New("invalid oauth state"))
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		response.BadRequestResponse(c, errors.New("authorization code is missing"))
+		return
+	}
+
+	identity, err := h.oidc.Exchange(c, code, verifier, nonce)
+	if err != nil {
+		response.Error(c, err)
 		return
 	}
 
+	user, err := h.uc.OIDCLogin(c, identity)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	token, err := h.generateToken(user.ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, token)
+}
+
+func (h *handler) generateToken(userID int64) (string, error) {
 	claims := jwt.MapClaims{
-		"sub": user.ID,
+		"sub": userID,
 		"exp": time.Now().Add(h.config.Auth.JWTExp).Unix(),
 		"iat": time.Now().Unix(),
 		"nbf": time.Now().Unix(),
@@ -90,11 +188,5 @@ func (h *handler) Login(c *gin.Context) {
 		"aud": h.config.Auth.JWTIss,
 	}
 
-	token, err := h.jwt.GenerateToken(claims)
-	if err != nil {
-		response.InternalServerError(c, err)
-		return
-	}
-
-	response.ResponseData(c, http.StatusOK, token)
+	return h.jwt.GenerateToken(claims)
 }
@@ -0,0 +1,414 @@
+package authdomain
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/auth/oidctest"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// nopDriver only opens and commits transactions, the fakes below ignore tx.
+type nopDriver struct{}
+
+func (nopDriver) Open(string) (driver.Conn, error) { return nopConn{}, nil }
+
+type nopConn struct{}
+
+func (nopConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (nopConn) Close() error                        { return nil }
+func (nopConn) Begin() (driver.Tx, error)           { return nopConn{}, nil }
+func (nopConn) Commit() error                       { return nil }
+func (nopConn) Rollback() error                     { return nil }
+
+func TestMain(m *testing.M) {
+	sql.Register("authdomain-nop", nopDriver{})
+
+	// error responses are logged
+	if _, err := logger.InitLogger(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakeStore backs the auth and user repositories. The embedded interfaces
+// here and in the wrappers below panic on anything the login does not use.
+type fakeStore struct {
+	users.UserRepository
+
+	mu         sync.Mutex
+	users      map[int64]*users.User
+	identities []Identity
+	logins     []string
+}
+
+func newFakeStore(existing ...*users.User) *fakeStore {
+	s := &fakeStore{users: make(map[int64]*users.User)}
+	for _, u := range existing {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *fakeStore) GetIdentity(ctx context.Context, provider, subject string) (*Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range s.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) CreateIdentity(ctx context.Context, tx *sql.Tx, identity *Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.identities = append(s.identities, *identity)
+	return nil
+}
+
+func (s *fakeStore) GetByID(ctx context.Context, id int64) (*users.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, commons.ErrNotFound
+	}
+	return u, nil
+}
+
+// fakeUsers is the user usecase over the same store.
+type fakeUsers struct {
+	users.UserUsecase
+	store *fakeStore
+}
+
+func (u fakeUsers) GetByID(ctx context.Context, id int64) (*users.User, error) {
+	return u.store.GetByID(ctx, id)
+}
+
+func (s *fakeStore) GetByEmail(ctx context.Context, email string) (*users.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) Create(ctx context.Context, tx *sql.Tx, user *users.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.ID = int64(len(s.users) + 100)
+	s.users[user.ID] = user
+	return nil
+}
+
+// fakeAudit keeps the login methods in the store.
+type fakeAudit struct {
+	audit.AuditUsecase
+	store *fakeStore
+}
+
+func (a fakeAudit) Record(ctx context.Context, tx *sql.Tx, event *audit.Event) error {
+	a.store.mu.Lock()
+	defer a.store.mu.Unlock()
+
+	if event.Action == audit.ActionLogin {
+		a.store.logins = append(a.store.logins, event.Diff["method"].To.(string))
+	}
+	return nil
+}
+
+var testConfig = config.Config{
+	App: config.AppConfig{Env: "development"},
+	Auth: config.AuthConfig{
+		JWTSecret: "jwt-secret",
+		JWTExp:    time.Hour,
+		JWTIss:    "gophersocial",
+		OIDC:      config.OIDCConfig{StateExp: 5 * time.Minute},
+	},
+}
+
+type oidcTest struct {
+	idp    *oidctest.IdP
+	store  *fakeStore
+	jwt    *auth.JWTAuthenticator
+	router *gin.Engine
+}
+
+func newOIDCTest(t *testing.T, store *fakeStore) *oidcTest {
+	t.Helper()
+
+	idp := oidctest.NewIdP(t)
+
+	provider, err := auth.NewOIDCProvider(
+		context.Background(),
+		"test",
+		idp.URL,
+		oidctest.ClientID,
+		oidctest.ClientSecret,
+		"http://localhost:8080/v1/auth/oidc/callback",
+		[]string{"openid", "email"},
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("authdomain-nop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	jwtAuth := auth.NewJWTAuthenticator(testConfig.Auth.JWTSecret, testConfig.Auth.JWTIss, testConfig.Auth.JWTIss)
+	uc := NewAuthUsecase(db, store, fakeAudit{store: store}, fakeUsers{store: store}, store)
+	hdl := NewAuthHandler(uc, testConfig, jwtAuth, provider)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/oidc/login", hdl.OIDCLogin)
+	r.GET("/oidc/callback", hdl.OIDCCallback)
+
+	return &oidcTest{idp: idp, store: store, jwt: jwtAuth, router: r}
+}
+
+func (o *oidcTest) do(path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	o.router.ServeHTTP(w, req)
+	return w
+}
+
+// start begins a login and lets the stand-in sign the user in. It returns
+// the login cookies and the callback query the browser would come back with.
+func (o *oidcTest) start(t *testing.T, claims jwt.MapClaims) ([]*http.Cookie, url.Values) {
+	t.Helper()
+
+	w := o.do("/oidc/login", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status %d: %s", w.Code, w.Body)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("login set %d cookies, want state, nonce and verifier", len(cookies))
+	}
+	for _, c := range cookies {
+		if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+			t.Errorf("cookie %s is not HttpOnly and SameSite=Lax", c.Name)
+		}
+	}
+
+	code, state := o.idp.Authorize(t, w.Header().Get("Location"), claims)
+
+	return cookies, url.Values{"code": {code}, "state": {state}}
+}
+
+// finish runs the callback and returns the user id in the issued token,
+// or the problem code.
+func (o *oidcTest) finish(t *testing.T, cookies []*http.Cookie, query url.Values) (int64, string) {
+	t.Helper()
+
+	w := o.do("/oidc/callback?"+query.Encode(), cookies)
+
+	var body struct {
+		Data string `json:"data"`
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("callback status %d: %s", w.Code, w.Body)
+	}
+
+	if w.Code != http.StatusOK {
+		return 0, body.Code
+	}
+
+	token, err := o.jwt.ValidateToken(body.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := token.Claims.(jwt.MapClaims)["sub"].(float64)
+
+	return int64(sub), ""
+}
+
+func TestOIDCLinksExistingAccountByEmail(t *testing.T) {
+	store := newFakeStore(&users.User{ID: 5, Username: "gopher", Email: "gopher@example.com", IsActive: true})
+	o := newOIDCTest(t, store)
+
+	claims := jwt.MapClaims{"sub": "subject-1", "email": "gopher@example.com", "email_verified": true}
+
+	cookies, query := o.start(t, claims)
+	userID, code := o.finish(t, cookies, query)
+	if code != "" {
+		t.Fatalf("callback failed with %s", code)
+	}
+	if userID != 5 {
+		t.Errorf("logged in as %d, want the existing account 5", userID)
+	}
+
+	if len(store.users) != 1 {
+		t.Errorf("provisioned a new user next to the existing account")
+	}
+	want := Identity{UserID: 5, Provider: "test", Subject: "subject-1", Email: "gopher@example.com"}
+	if len(store.identities) != 1 || store.identities[0] != want {
+		t.Fatalf("identities = %+v, want %+v", store.identities, want)
+	}
+
+	// the second login goes through the link, even with another email
+	claims["email"] = "renamed@example.com"
+	cookies, query = o.start(t, claims)
+	if userID, code := o.finish(t, cookies, query); userID != 5 || code != "" {
+		t.Errorf("second login as %d (%s), want 5", userID, code)
+	}
+	if len(store.identities) != 1 {
+		t.Errorf("linked the identity twice")
+	}
+
+	if len(store.logins) != 2 || store.logins[0] != "oidc:test" {
+		t.Errorf("audited logins %v", store.logins)
+	}
+}
+
+func TestOIDCDoesNotLinkUnverifiedEmail(t *testing.T) {
+	store := newFakeStore(&users.User{ID: 5, Username: "gopher", Email: "gopher@example.com", IsActive: true})
+	o := newOIDCTest(t, store)
+
+	cookies, query := o.start(t, jwt.MapClaims{"email": "gopher@example.com", "email_verified": false})
+	if _, code := o.finish(t, cookies, query); code != "email_unverified" {
+		t.Errorf("callback code %q, want email_unverified", code)
+	}
+	if len(store.identities) != 0 || len(store.logins) != 0 {
+		t.Errorf("unverified email was linked: %+v", store.identities)
+	}
+}
+
+func TestOIDCProvisionsNewUser(t *testing.T) {
+	store := newFakeStore()
+	o := newOIDCTest(t, store)
+
+	cookies, query := o.start(t, jwt.MapClaims{"email": "new@example.com", "email_verified": true, "preferred_username": "New Gopher"})
+	userID, code := o.finish(t, cookies, query)
+	if code != "" {
+		t.Fatalf("callback failed with %s", code)
+	}
+
+	u := store.users[userID]
+	if u == nil || u.Username != "newgopher" || u.Email != "new@example.com" || u.Role.Name != "user" {
+		t.Errorf("provisioned %+v", u)
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != userID {
+		t.Errorf("identities = %+v", store.identities)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes what the browser sends back
+		tamper func(cookies []*http.Cookie, query url.Values) []*http.Cookie
+		code   string
+	}{
+		{
+			name: "state from another login",
+			tamper: func(cookies []*http.Cookie, query url.Values) []*http.Cookie {
+				query.Set("state", "forged")
+				return cookies
+			},
+			code: "bad_request",
+		},
+		{
+			name: "no state cookie",
+			tamper: func(cookies []*http.Cookie, query url.Values) []*http.Cookie {
+				return without(cookies, oidcStateCookie)
+			},
+			code: "bad_request",
+		},
+		{
+			name: "no state in either",
+			tamper: func(cookies []*http.Cookie, query url.Values) []*http.Cookie {
+				query.Del("state")
+				return without(cookies, oidcStateCookie)
+			},
+			code: "bad_request",
+		},
+		{
+			name: "verifier of another login",
+			tamper: func(cookies []*http.Cookie, query url.Values) []*http.Cookie {
+				return replace(cookies, oidcVerifierCookie, "another-verifier-of-enough-length-0123456789")
+			},
+			code: "oidc_exchange_failed",
+		},
+		{
+			name: "nonce of another login",
+			tamper: func(cookies []*http.Cookie, query url.Values) []*http.Cookie {
+				return replace(cookies, oidcNonceCookie, "another-nonce")
+			},
+			code: "oidc_invalid_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(&users.User{ID: 5, Email: "gopher@example.com", IsActive: true})
+			o := newOIDCTest(t, store)
+
+			cookies, query := o.start(t, jwt.MapClaims{"email": "gopher@example.com", "email_verified": true})
+			cookies = tt.tamper(cookies, query)
+
+			if userID, code := o.finish(t, cookies, query); code != tt.code {
+				t.Errorf("callback logged in as %d with code %q, want %q", userID, code, tt.code)
+			}
+			if len(store.identities) != 0 || len(store.logins) != 0 {
+				t.Errorf("rejected callback still logged in")
+			}
+		})
+	}
+}
+
+func without(cookies []*http.Cookie, name string) []*http.Cookie {
+	var out []*http.Cookie
+	for _, c := range cookies {
+		if c.Name != name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func replace(cookies []*http.Cookie, name, value string) []*http.Cookie {
+	out := without(cookies, name)
+	return append(out, &http.Cookie{Name: name, Value: value})
+}
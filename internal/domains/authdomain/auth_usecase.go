@@ -2,24 +2,45 @@ package authdomain
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
 	"github.com/codepnw/gopher-social/internal/domains/commons"
 	"github.com/codepnw/gopher-social/internal/domains/users"
 )
 
+const provisionRetries = 3
+
+var usernameSanitizer = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
 type AuthUsecase interface {
 	Register(ctx context.Context, payload *RegisterUserPayload, token string, exp time.Duration) error
 	GetUser(ctx context.Context, req LoginUserPayload) (*users.User, error)
+	OIDCLogin(ctx context.Context, identity *auth.OIDCIdentity) (*users.User, error)
 }
 
 type usecase struct {
-	userRepo users.UserUsecase
+	db        *sql.DB
+	repo      AuthRepository
+	audit     audit.AuditUsecase
+	userRepo  users.UserUsecase
+	userStore users.UserRepository
 }
 
-func NewAuthUsecase(userRepo users.UserUsecase) AuthUsecase {
-	return &usecase{userRepo: userRepo}
+func NewAuthUsecase(db *sql.DB, repo AuthRepository, audit audit.AuditUsecase, userRepo users.UserUsecase, userStore users.UserRepository) AuthUsecase {
+	return &usecase{
+		db:        db,
+		repo:      repo,
+		audit:     audit,
+		userRepo:  userRepo,
+		userStore: userStore,
+	}
 }
 
 func (uc *usecase) Register(ctx context.Context, payload *RegisterUserPayload, token string, exp time.Duration) error {
@@ -47,16 +68,189 @@ func (uc *usecase) GetUser(ctx context.Context, req LoginUserPayload) (*users.Us
 	if err != nil {
 	log.Println("GetUSER=========1", err)
 
-		return nil, commons.ErrInvalidEmailPassword
+		return nil, uc.recordFailedLogin(ctx, nil, req.Email)
 	}
 
 	if err = user.ComparePassword(req.Password); err != nil {
 	log.Println("GetUSER=========2", user)
 
-		return nil, commons.ErrInvalidEmailPassword
+		return nil, uc.recordFailedLogin(ctx, audit.ID(user.ID), req.Email)
 	}
 
 	log.Println("GetUSER=========", user)
 
+	if user.PasswordResetRequired {
+		return nil, commons.ErrPasswordResetRequired
+	}
+
+	if err := uc.recordLogin(ctx, user.ID, "password"); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (uc *usecase) recordLogin(ctx context.Context, userID int64, method string) error {
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(userID),
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(userID),
+			Diff: map[string]audit.Change{
+				"method": {To: method},
+			},
+		})
+	})
+}
+
+// recordFailedLogin always returns ErrInvalidEmailPassword unless the audit
+// write itself fails.
+func (uc *usecase) recordFailedLogin(ctx context.Context, userID *int64, email string) error {
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			Action:     audit.ActionLoginFailed,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Diff: map[string]audit.Change{
+				"email": {To: email},
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return commons.ErrInvalidEmailPassword
+}
+
+// OIDCLogin resolves an external identity to a local user. Known identities
+// log straight in, a verified email is linked to the matching account and
+// anyone else is provisioned with the "user" role.
+func (uc *usecase) OIDCLogin(ctx context.Context, identity *auth.OIDCIdentity) (*users.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	user, err := uc.resolveIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.recordLogin(ctx, user.ID, "oidc:"+identity.Provider); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
+
+func (uc *usecase) resolveIdentity(ctx context.Context, identity *auth.OIDCIdentity) (*users.User, error) {
+	linked, err := uc.repo.GetIdentity(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil:
+		return uc.userRepo.GetByID(ctx, linked.UserID)
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, auth.ErrOIDCEmailMissing
+	}
+
+	if !identity.EmailVerified {
+		return nil, commons.ErrUnverifiedEmail
+	}
+
+	user, err := uc.userStore.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+			return uc.repo.CreateIdentity(ctx, tx, newIdentity(user.ID, identity))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	return uc.provisionUser(ctx, identity)
+}
+
+func (uc *usecase) provisionUser(ctx context.Context, identity *auth.OIDCIdentity) (*users.User, error) {
+	password, err := auth.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &users.UserReq{}
+	if err := req.HashPassword(password); err != nil {
+		return nil, err
+	}
+
+	base := oidcUsername(identity)
+
+	for i := 0; ; i++ {
+		username := base
+		if i > 0 {
+			suffix, err := auth.RandomString(3)
+			if err != nil {
+				return nil, err
+			}
+			username = base + "_" + strings.ToLower(usernameSanitizer.ReplaceAllString(suffix, ""))
+		}
+
+		user := &users.User{
+			Username: username,
+			Email:    identity.Email,
+			Password: req.Password,
+			IsActive: true,
+			Role:     users.Role{Name: "user"},
+		}
+
+		err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+			if err := uc.userStore.Create(ctx, tx, user); err != nil {
+				return err
+			}
+
+			return uc.repo.CreateIdentity(ctx, tx, newIdentity(user.ID, identity))
+		})
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, commons.ErrDuplicateUsername) && i+1 < provisionRetries:
+			continue
+		default:
+			// a duplicate email means the account exists but has not been
+			// activated yet
+			return nil, err
+		}
+	}
+}
+
+func newIdentity(userID int64, identity *auth.OIDCIdentity) *Identity {
+	return &Identity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+}
+
+func oidcUsername(identity *auth.OIDCIdentity) string {
+	name := identity.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	name = usernameSanitizer.ReplaceAllString(strings.ToLower(name), "")
+	if name == "" {
+		name = "user"
+	}
+
+	if len(name) > 90 {
+		name = name[:90]
+	}
+
+	return name
+}
@@ -0,0 +1,20 @@
+package comments
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/filters"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+)
+
+func InitCommentsDomain(db *sql.DB, cfg config.Config, filter filters.Filter, notifier commons.Notifier) CommentsHandler {
+	repo := NewCommentsRepository(db)
+	auditusecase := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	uc := NewCommentsUsecase(db, repo, auditusecase, filter, mentions.NewMentions(db), notifier, cfg)
+	hdl := NewCommentsHandler(uc)
+
+	return hdl
+}
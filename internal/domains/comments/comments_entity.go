@@ -1,12 +1,23 @@
 package comments
 
-import "github.com/codepnw/gopher-social/internal/domains/users"
+import (
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
 
 type Comment struct {
-	ID        int64      `json:"id"`
-	PostID    int64      `json:"post_id"`
-	UserID    int64      `json:"user_id"`
-	Content   string     `json:"content"`
-	CreatedAt string     `json:"created_at"`
-	User      users.User `json:"user"`
+	ID        int64              `json:"id"`
+	PostID    int64              `json:"post_id"`
+	ParentID  *int64             `json:"parent_id"`
+	UserID    int64              `json:"user_id"`
+	Content   string             `json:"content"`
+	CreatedAt string             `json:"created_at"`
+	HiddenAt  *string            `json:"hidden_at,omitempty"`
+	Mentions  []mentions.Mention `json:"mentions"`
+	User      users.User         `json:"user"`
+}
+
+type CreateCommentPayload struct {
+	Content  string `json:"content" binding:"required,max=300"`
+	ParentID *int64 `json:"parent_id"`
 }
@@ -1 +1,110 @@
-package comments
\ No newline at end of file
+package comments
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type CommentsHandler interface {
+	CreateCommentHandler(c *gin.Context)
+	ListCommentsHandler(c *gin.Context)
+	DeleteCommentHandler(c *gin.Context)
+	RestoreCommentHandler(c *gin.Context)
+}
+
+// handlers run behind PostContextMiddleware, which has already checked
+// that the post exists and is visible to the user.
+type handler struct {
+	uc CommentsUsecase
+}
+
+func NewCommentsHandler(uc CommentsUsecase) CommentsHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) CreateCommentHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload CreateCommentPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	comment, err := h.uc.Create(c, users.GetAuthUserFromContext(c), postID, &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, comment)
+}
+
+func (h *handler) ListCommentsHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	comments, err := h.uc.GetByPostID(c, postID, users.GetAuthUserFromContext(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, comments)
+}
+
+func (h *handler) DeleteCommentHandler(c *gin.Context) {
+	postID, commentID, err := commentParams(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.Delete(c, users.GetAuthUserFromContext(c), postID, commentID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) RestoreCommentHandler(c *gin.Context) {
+	postID, commentID, err := commentParams(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	comment, err := h.uc.Restore(c, users.GetAuthUserFromContext(c), postID, commentID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, comment)
+}
+
+func commentParams(c *gin.Context) (postID, commentID int64, err error) {
+	postID, err = strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	commentID, err = strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return postID, commentID, nil
+}
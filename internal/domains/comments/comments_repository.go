@@ -3,11 +3,19 @@ package comments
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
 )
 
 type CommentsRepository interface {
-	create(ctx context.Context, comment *Comment) error
-	getByPostID(ctx context.Context, postID int64) ([]Comment, error)
+	// create returns the author of the post and of the parent comment, if any
+	create(ctx context.Context, tx *sql.Tx, comment *Comment, hidden bool) (postAuthor int64, parentAuthor *int64, err error)
+	getByPostID(ctx context.Context, postID int64, viewer *users.User) ([]Comment, error)
+	// lock returns the comment on postID and locks it until tx ends
+	lock(ctx context.Context, tx *sql.Tx, postID, commentID int64) (*Comment, error)
+	delete(ctx context.Context, tx *sql.Tx, commentID, actorID int64) error
+	restore(ctx context.Context, postID, commentID, userID int64, deletedAfter time.Time) (*Comment, error)
 }
 
 type repository struct {
@@ -18,36 +26,125 @@ func NewCommentsRepository(db *sql.DB) CommentsRepository {
 	return &repository{db: db}
 }
 
-func (r *repository) create(ctx context.Context, comment *Comment) error {
+func (r *repository) create(ctx context.Context, tx *sql.Tx, comment *Comment, hidden bool) (int64, *int64, error) {
+	// a reply must point at a comment on the same post, otherwise nothing is
+	// inserted and the caller gets sql.ErrNoRows
 	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3) RETURNING id, created_at
+		INSERT INTO comments (post_id, user_id, content, hidden_at, parent_id)
+		SELECT $1, $2, $3, CASE WHEN $4 THEN NOW() END, $5
+		WHERE $5::bigint IS NULL OR EXISTS (SELECT 1 FROM comments WHERE id = $5 AND post_id = $1 AND deleted_at IS NULL)
+		RETURNING id, created_at, hidden_at,
+			(SELECT user_id FROM posts WHERE id = $1),
+			(SELECT user_id FROM comments WHERE id = $5)
 	`
-	err := r.db.QueryRowContext(
-		ctx, 
+	var (
+		postAuthor   int64
+		parentAuthor *int64
+	)
+	err := tx.QueryRowContext(
+		ctx,
 		query,
 		comment.PostID,
 		comment.UserID,
 		comment.Content,
-	).Scan(&comment.ID, &comment.CreatedAt)
+		hidden,
+		comment.ParentID,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.HiddenAt, &postAuthor, &parentAuthor)
 
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
 
-	return nil
+	return postAuthor, parentAuthor, nil
 }
 
-func (r *repository) getByPostID(ctx context.Context, postID int64) ([]Comment, error) {
+func (r *repository) getByPostID(ctx context.Context, postID int64, viewer *users.User) ([]Comment, error) {
 	query := `
-		SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, users.username, users.id FROM comments c
+		SELECT c.id, c.post_id, c.parent_id, c.user_id, c.content, c.created_at, c.hidden_at, users.username, users.id FROM comments c
 		JOIN users on users.id = c.user_id
-		WHERE c.post_id = $1
+		WHERE c.post_id = $1 AND c.deleted_at IS NULL AND (c.hidden_at IS NULL OR c.user_id = $2 OR $3)
 		ORDER BY c.created_at DESC;
 	`
-	_ = query
-	
+	rows, err := r.db.QueryContext(ctx, query, postID, viewer.ID, viewer.IsModerator())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return nil, nil
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		err := rows.Scan(
+			&c.ID,
+			&c.PostID,
+			&c.ParentID,
+			&c.UserID,
+			&c.Content,
+			&c.CreatedAt,
+			&c.HiddenAt,
+			&c.User.Username,
+			&c.User.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
 }
 
+func (r *repository) lock(ctx context.Context, tx *sql.Tx, postID, commentID int64) (*Comment, error) {
+	query := `
+		SELECT id, post_id, parent_id, user_id, content, created_at, hidden_at FROM comments
+		WHERE id = $1 AND post_id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	var c Comment
+	err := tx.QueryRowContext(ctx, query, commentID, postID).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.ParentID,
+		&c.UserID,
+		&c.Content,
+		&c.CreatedAt,
+		&c.HiddenAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *repository) delete(ctx context.Context, tx *sql.Tx, commentID, actorID int64) error {
+	query := `UPDATE comments SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, commentID, actorID)
+	return err
+}
+
+// restore brings back a comment its author deleted after deletedAfter.
+// Comments removed by a moderator cannot be restored.
+func (r *repository) restore(ctx context.Context, postID, commentID, userID int64, deletedAfter time.Time) (*Comment, error) {
+	query := `
+		UPDATE comments SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND post_id = $2 AND user_id = $3 AND deleted_by = $3 AND deleted_at > $4
+		RETURNING id, post_id, parent_id, user_id, content, created_at, hidden_at
+	`
+	var c Comment
+	err := r.db.QueryRowContext(ctx, query, commentID, postID, userID, deletedAfter).Scan(
+		&c.ID,
+		&c.PostID,
+		&c.ParentID,
+		&c.UserID,
+		&c.Content,
+		&c.CreatedAt,
+		&c.HiddenAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
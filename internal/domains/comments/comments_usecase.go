@@ -1 +1,226 @@
-package comments
\ No newline at end of file
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/filters"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+type CommentsUsecase interface {
+	Create(ctx context.Context, author *users.User, postID int64, payload *CreateCommentPayload) (*Comment, error)
+	GetByPostID(ctx context.Context, postID int64, viewer *users.User) ([]Comment, error)
+	Delete(ctx context.Context, actor *users.User, postID, commentID int64) error
+	Restore(ctx context.Context, actor *users.User, postID, commentID int64) (*Comment, error)
+}
+
+type usecase struct {
+	db       *sql.DB
+	repo     CommentsRepository
+	audit    audit.AuditUsecase
+	filter   filters.Filter
+	mentions mentions.MentionUsecase
+	notifier commons.Notifier
+	cfg      config.PostsConfig
+}
+
+func NewCommentsUsecase(db *sql.DB, repo CommentsRepository, audit audit.AuditUsecase, filter filters.Filter, mentions mentions.MentionUsecase, notifier commons.Notifier, cfg config.Config) CommentsUsecase {
+	return &usecase{
+		db:       db,
+		repo:     repo,
+		audit:    audit,
+		filter:   filter,
+		mentions: mentions,
+		notifier: notifier,
+		cfg:      cfg.Posts,
+	}
+}
+
+func (uc *usecase) Create(ctx context.Context, author *users.User, postID int64, payload *CreateCommentPayload) (*Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	result, err := uc.filter.Check(ctx, payload.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Action == filters.ActionReject {
+		if err := uc.filter.Record(ctx, filters.TargetComment, nil, author.ID, result); err != nil {
+			return nil, err
+		}
+		return nil, commons.ErrContentRejected
+	}
+
+	comment := &Comment{
+		PostID:   postID,
+		ParentID: payload.ParentID,
+		UserID:   author.ID,
+		Content:  result.Fields[0],
+		User:     *author,
+	}
+
+	var (
+		postAuthor   int64
+		parentAuthor *int64
+	)
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		postAuthor, parentAuthor, err = uc.repo.create(ctx, tx, comment, result.Action == filters.ActionHold)
+		if err != nil {
+			return err
+		}
+
+		comment.Mentions, err = uc.mentions.Save(ctx, tx, mentions.TargetComment, comment.ID, author.ID, comment.Content)
+		return err
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	// the comment is stored, a failure here must not fail the request
+	if err := uc.filter.Record(ctx, filters.TargetComment, &comment.ID, author.ID, result); err != nil {
+		log.Println("failed to record filter matches:", comment.ID, err)
+	}
+
+	if comment.HiddenAt == nil {
+		uc.notify(comment, postAuthor, parentAuthor)
+	}
+
+	return comment, nil
+}
+
+func (uc *usecase) GetByPostID(ctx context.Context, postID int64, viewer *users.User) ([]Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	comments, err := uc.repo.getByPostID(ctx, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+
+	found, err := uc.mentions.List(ctx, mentions.TargetComment, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range comments {
+		comments[i].Mentions = found[comments[i].ID]
+	}
+
+	return comments, nil
+}
+
+// Delete soft deletes a comment. Only its author and moderators may delete
+// it, the author can restore it within the restore window.
+func (uc *usecase) Delete(ctx context.Context, actor *users.User, postID, commentID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		comment, err := uc.repo.lock(ctx, tx, postID, commentID)
+		if err != nil {
+			return err
+		}
+
+		if actor.ID != comment.UserID && !actor.IsModerator() {
+			return commons.ErrForbidden
+		}
+
+		if err := uc.repo.delete(ctx, tx, comment.ID, actor.ID); err != nil {
+			return err
+		}
+
+		if actor.ID == comment.UserID {
+			return nil
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(actor.ID),
+			Action:     audit.ActionCommentDelete,
+			TargetType: audit.TargetComment,
+			TargetID:   audit.ID(comment.ID),
+			Diff: map[string]audit.Change{
+				"content": {From: comment.Content},
+				"user_id": {From: comment.UserID},
+			},
+		})
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restore undeletes a comment the actor deleted themselves.
+func (uc *usecase) Restore(ctx context.Context, actor *users.User, postID, commentID int64) (*Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	comment, err := uc.repo.restore(ctx, postID, commentID, actor.ID, time.Now().Add(-uc.cfg.RestoreWindow))
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	comment.User = *actor
+
+	found, err := uc.mentions.List(ctx, mentions.TargetComment, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	comment.Mentions = found[comment.ID]
+
+	return comment, nil
+}
+
+// notify tells the post author about the comment and the parent author
+// about the reply. Nobody gets both a reply and a comment notification.
+func (uc *usecase) notify(comment *Comment, postAuthor int64, parentAuthor *int64) {
+	if parentAuthor != nil {
+		uc.notifier.Notify(commons.NotifyEvent{
+			UserID:     *parentAuthor,
+			ActorID:    comment.UserID,
+			Type:       commons.NotifyReply,
+			TargetType: "comment",
+			TargetID:   *comment.ParentID,
+		})
+	}
+
+	if parentAuthor == nil || *parentAuthor != postAuthor {
+		uc.notifier.Notify(commons.NotifyEvent{
+			UserID:     postAuthor,
+			ActorID:    comment.UserID,
+			Type:       commons.NotifyComment,
+			TargetType: "post",
+			TargetID:   comment.PostID,
+		})
+	}
+
+	mentions.Notify(uc.notifier, comment.UserID, mentions.TargetComment, comment.ID, comment.Mentions, nil)
+}
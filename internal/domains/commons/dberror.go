@@ -0,0 +1,39 @@
+package commons
+
+import (
+	"errors"
+
+	"github.com/codepnw/gopher-social/internal/utils/errs"
+	"github.com/lib/pq"
+)
+
+// constraintErrors names the error reported for a unique violation on a
+// specific constraint, anything else is ErrConflict.
+var constraintErrors = map[string]*errs.Error{
+	"users_email_key":    ErrDuplicateEmail,
+	"users_username_key": ErrDuplicateUsername,
+}
+
+// DBError translates Postgres constraint violations into domain errors and
+// returns any other error unchanged. It is the only place that looks at
+// Postgres error codes.
+func DBError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		if e, ok := constraintErrors[pqErr.Constraint]; ok {
+			return e.Wrap(err)
+		}
+		return ErrConflict.Wrap(err)
+	case "23503": // foreign_key_violation
+		return ErrInvalidReference.Wrap(err)
+	case "23514": // check_violation
+		return ErrInvalidValue.Wrap(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,28 @@
+package commons
+
+const (
+	NotifyFollow   = "follow"
+	NotifyComment  = "comment"
+	NotifyReply    = "reply"
+	NotifyMention  = "mention"
+	NotifyReaction = "reaction"
+	NotifyRepost   = "repost"
+	NotifyQuote    = "quote"
+	// NotifyPollClosed goes to the voters, the actor is the poll's author
+	NotifyPollClosed = "poll_closed"
+)
+
+// NotifyEvent tells UserID that ActorID did something to a target. The
+// notifications domain groups events for the same target.
+type NotifyEvent struct {
+	UserID     int64
+	ActorID    int64
+	Type       string
+	TargetType string
+	TargetID   int64
+}
+
+// Notifier queues notifications, Notify must not block the caller.
+type Notifier interface {
+	Notify(e NotifyEvent)
+}
@@ -0,0 +1,14 @@
+package commons
+
+import "context"
+
+const (
+	EventNotification = "notification"
+	EventNewPost      = "feed.new_post"
+)
+
+// Publisher pushes an event to every connected client of userIDs, on any
+// API instance. Users without an open stream simply miss it.
+type Publisher interface {
+	Publish(ctx context.Context, userIDs []int64, eventType string, data any) error
+}
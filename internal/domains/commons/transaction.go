@@ -13,8 +13,8 @@ func WithTransaction(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) er
 
 	if err = fn(tx); err != nil {
 		tx.Rollback()
-		return err
+		return DBError(err)
 	}
 
-	return tx.Commit()
+	return DBError(tx.Commit())
 }
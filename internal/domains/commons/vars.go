@@ -1,22 +1,73 @@
 package commons
 
 import (
-	"errors"
+	"net/http"
 	"time"
+
+	"github.com/codepnw/gopher-social/internal/utils/errs"
 )
 
 const (
 	ContextPostKey = "post"
 	ContextUserKey = "user"
 
+	ContextAuthUserKey  = "auth_user"
+	ContextAuthTokenKey = "auth_token"
+
 	ContextQueryTimeout = 5 * time.Second
 )
 
+// role levels seeded by migration 000010
+const (
+	RoleLevelUser  = 1
+	RoleLevelStaff = 2
+	RoleLevelAdmin = 3
+)
+
+// Domain errors carry their HTTP status and a stable code, handlers pass
+// them to response.Error as they are. Codes are part of the API, do not
+// change them.
 var (
-	ErrNotFound          = errors.New("resource not found")
-	ErrConflict          = errors.New("resource already exists")
-	ErrDuplicateEmail    = errors.New("a user with email already exists")
-	ErrDuplicateUsername = errors.New("a user with username already exists")
+	ErrNotFound          = errs.New(http.StatusNotFound, "not_found", "resource not found")
+	ErrConflict          = errs.New(http.StatusConflict, "conflict", "resource already exists")
+	ErrEditConflict      = errs.New(http.StatusConflict, "edit_conflict", "resource was modified by another request")
+	ErrDuplicateEmail    = errs.New(http.StatusConflict, "duplicate_email", "a user with email already exists")
+	ErrDuplicateUsername = errs.New(http.StatusConflict, "duplicate_username", "a user with username already exists")
+	ErrUsernameCooldown  = errs.New(http.StatusConflict, "username_cooldown", "username was changed too recently")
+
+	ErrInvalidReference = errs.New(http.StatusBadRequest, "invalid_reference", "referenced resource does not exist")
+	ErrInvalidValue     = errs.New(http.StatusBadRequest, "invalid_value", "value is not allowed")
+
+	ErrInvalidEmailPassword  = errs.New(http.StatusBadRequest, "invalid_credentials", "invalid email or password")
+	ErrUnverifiedEmail       = errs.New(http.StatusBadRequest, "email_unverified", "email address is not verified")
+	ErrPasswordResetRequired = errs.New(http.StatusBadRequest, "password_reset_required", "password reset is required")
+	ErrInvalidPassword       = errs.New(http.StatusBadRequest, "invalid_password", "current password is incorrect")
+
+	ErrUnauthorized = errs.New(http.StatusUnauthorized, "unauthorized", "authentication is required")
+	ErrForbidden    = errs.New(http.StatusForbidden, "forbidden", "forbidden")
+	ErrInvalidToken = errs.New(http.StatusUnauthorized, "invalid_token", "invalid or expired token")
+	ErrInvalidScope = errs.New(http.StatusBadRequest, "invalid_scope", "invalid token scope")
+	ErrInvalidLink  = errs.New(http.StatusBadRequest, "invalid_link", "link is invalid or has expired")
+
+	ErrInvalidTag = errs.New(http.StatusBadRequest, "invalid_tag", "invalid tag")
+	ErrTooLong    = errs.New(http.StatusBadRequest, "too_long", "value is too long")
+
+	ErrInvalidSchedule = errs.New(http.StatusBadRequest, "invalid_schedule", "publish time must be in the future")
+	ErrNotScheduled    = errs.New(http.StatusConflict, "not_scheduled", "post is not scheduled")
+	ErrNotShareable    = errs.New(http.StatusBadRequest, "not_shareable", "post cannot be reposted or quoted")
+
+	ErrInvalidProfile = errs.New(http.StatusBadRequest, "invalid_profile", "invalid profile")
+	ErrPinLimit       = errs.New(http.StatusConflict, "pin_limit", "pinned posts limit reached")
+
+	ErrInvalidPoll  = errs.New(http.StatusBadRequest, "invalid_poll", "invalid poll")
+	ErrInvalidVote  = errs.New(http.StatusBadRequest, "invalid_vote", "invalid vote")
+	ErrPollClosed   = errs.New(http.StatusConflict, "poll_closed", "poll is closed")
+	ErrAlreadyVoted = errs.New(http.StatusConflict, "already_voted", "already voted in this poll")
+
+	ErrContentRejected   = errs.New(http.StatusBadRequest, "content_rejected", "content violates the community guidelines")
+	ErrInvalidFilterRule = errs.New(http.StatusBadRequest, "invalid_filter_rule", "invalid filter rule")
 
-	ErrInvalidEmailPassword = errors.New("invalid email or password")
+	ErrUnsupportedMedia  = errs.New(http.StatusUnsupportedMediaType, "unsupported_media", "unsupported media type")
+	ErrMediaTooLarge     = errs.New(http.StatusRequestEntityTooLarge, "media_too_large", "media is too large")
+	ErrInvalidAttachment = errs.New(http.StatusBadRequest, "invalid_attachment", "invalid attachment")
 )
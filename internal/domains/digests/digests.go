@@ -0,0 +1,28 @@
+package digests
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/feed"
+	"github.com/codepnw/gopher-social/internal/domains/notifications"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+)
+
+func InitDigestDomain(db *sql.DB) DigestHandler {
+	repo := NewDigestRepository(db)
+	uc := NewDigestUsecase(repo)
+	hdl := NewDigestHandler(uc)
+
+	return hdl
+}
+
+func NewDigestWorker(db *sql.DB, cfg config.Config, mailer mailer.Client) *Worker {
+	return NewWorker(
+		NewDigestRepository(db),
+		feed.NewFeedRepository(db),
+		notifications.NewNotificationRepository(db),
+		mailer,
+		cfg,
+	)
+}
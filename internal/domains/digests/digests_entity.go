@@ -0,0 +1,63 @@
+package digests
+
+import (
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/feed"
+	"github.com/codepnw/gopher-social/internal/domains/notifications"
+)
+
+const (
+	FrequencyOff    = "off"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+
+	// DefaultFrequency applies to users who never changed their preference.
+	DefaultFrequency = FrequencyWeekly
+
+	maxDigestItems = 10
+)
+
+type Preferences struct {
+	Frequency string `json:"frequency"`
+}
+
+type PreferencesPayload struct {
+	Frequency string `json:"frequency" binding:"required,oneof=off daily weekly"`
+}
+
+// Recipient is a user due for a digest, i.e. one who has not logged in
+// during the period.
+type Recipient struct {
+	ID       int64
+	Username string
+	Email    string
+}
+
+// Digest is the data rendered by the user_digest template.
+type Digest struct {
+	Username       string
+	Frequency      string
+	Posts          []feed.PostWithMetaData
+	Notifications  []notifications.Notification
+	UnreadCount    int
+	FrontendURL    string
+	UnsubscribeURL string
+}
+
+func (d *Digest) Empty() bool {
+	return len(d.Posts) == 0 && len(d.Notifications) == 0
+}
+
+// period returns the start of the current daily or weekly period (weeks
+// start on Monday, UTC) and how far back the digest looks.
+func period(frequency string, now time.Time) (time.Time, time.Duration) {
+	day := now.UTC().Truncate(24 * time.Hour)
+
+	if frequency == FrequencyDaily {
+		return day, 24 * time.Hour
+	}
+
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset), 7 * 24 * time.Hour
+}
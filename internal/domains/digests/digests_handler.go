@@ -0,0 +1,58 @@
+package digests
+
+import (
+	"net/http"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type DigestHandler interface {
+	GetPreferencesHandler(c *gin.Context)
+	UpdatePreferencesHandler(c *gin.Context)
+	UnsubscribeHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc DigestUsecase
+}
+
+func NewDigestHandler(uc DigestUsecase) DigestHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) GetPreferencesHandler(c *gin.Context) {
+	prefs, err := h.uc.GetPreferences(c, users.GetAuthUserFromContext(c).ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, prefs)
+}
+
+func (h *handler) UpdatePreferencesHandler(c *gin.Context) {
+	var payload PreferencesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	prefs, err := h.uc.SetPreferences(c, users.GetAuthUserFromContext(c).ID, payload.Frequency)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, prefs)
+}
+
+func (h *handler) UnsubscribeHandler(c *gin.Context) {
+	if err := h.uc.Unsubscribe(c, c.Param("token")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
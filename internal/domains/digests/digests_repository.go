@@ -0,0 +1,163 @@
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+)
+
+type DigestRepository interface {
+	GetPreferences(ctx context.Context, userID int64) (*Preferences, error)
+	SetPreferences(ctx context.Context, userID int64, frequency string) error
+	DueRecipients(ctx context.Context, frequency string, periodStart, inactiveSince time.Time, afterID int64, limit int) ([]Recipient, error)
+	Claim(ctx context.Context, userID int64, frequency string, periodStart time.Time, tokenHash string) (bool, error)
+	Release(ctx context.Context, userID int64, frequency string, periodStart time.Time) error
+	MarkSent(ctx context.Context, userID int64, frequency string, periodStart time.Time, items int) error
+	Unsubscribe(ctx context.Context, tokenHash string) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewDigestRepository(db *sql.DB) DigestRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetPreferences(ctx context.Context, userID int64) (*Preferences, error) {
+	prefs := &Preferences{Frequency: DefaultFrequency}
+
+	query := `SELECT frequency FROM digest_preferences WHERE user_id = $1`
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&prefs.Frequency)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+func (r *repository) SetPreferences(ctx context.Context, userID int64, frequency string) error {
+	query := `
+		INSERT INTO digest_preferences (user_id, frequency) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, updated_at = NOW()
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, frequency)
+	return err
+}
+
+// DueRecipients pages by user id through active users on frequency who
+// have not logged in since inactiveSince and have no delivery for the
+// period yet.
+func (r *repository) DueRecipients(ctx context.Context, frequency string, periodStart, inactiveSince time.Time, afterID int64, limit int) ([]Recipient, error) {
+	query := `
+		SELECT u.id, u.username, u.email
+		FROM users u
+		LEFT JOIN digest_preferences p ON p.user_id = u.id
+		WHERE
+			u.id > $1 AND
+			u.is_active AND
+			u.banned_at IS NULL AND
+			COALESCE(p.frequency, $2) = $3 AND
+			NOT EXISTS (
+				SELECT 1 FROM digest_deliveries d
+				WHERE d.user_id = u.id AND d.frequency = $3 AND d.period_start = $4
+			) AND
+			NOT EXISTS (
+				SELECT 1 FROM audit_events a
+				WHERE a.actor_id = u.id AND a.action = $5 AND a.created_at >= $6
+			)
+		ORDER BY u.id
+		LIMIT $7
+	`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		afterID,
+		DefaultFrequency,
+		frequency,
+		periodStart,
+		audit.ActionLogin,
+		inactiveSince,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []Recipient
+	for rows.Next() {
+		var rc Recipient
+		if err := rows.Scan(&rc.ID, &rc.Username, &rc.Email); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, rc)
+	}
+
+	return recipients, rows.Err()
+}
+
+// Claim reserves the digest of a period, false means another run (or
+// another instance) already has it.
+func (r *repository) Claim(ctx context.Context, userID int64, frequency string, periodStart time.Time, tokenHash string) (bool, error) {
+	query := `
+		INSERT INTO digest_deliveries (user_id, frequency, period_start, unsubscribe_token)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, frequency, period_start) DO NOTHING
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, frequency, periodStart, tokenHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *repository) Release(ctx context.Context, userID int64, frequency string, periodStart time.Time) error {
+	query := `
+		DELETE FROM digest_deliveries
+		WHERE user_id = $1 AND frequency = $2 AND period_start = $3 AND sent_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, frequency, periodStart)
+	return err
+}
+
+func (r *repository) MarkSent(ctx context.Context, userID int64, frequency string, periodStart time.Time, items int) error {
+	query := `
+		UPDATE digest_deliveries SET sent_at = NOW(), item_count = $4
+		WHERE user_id = $1 AND frequency = $2 AND period_start = $3
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, frequency, periodStart, items)
+	return err
+}
+
+func (r *repository) Unsubscribe(ctx context.Context, tokenHash string) error {
+	query := `
+		INSERT INTO digest_preferences (user_id, frequency)
+		SELECT user_id, $2 FROM digest_deliveries WHERE unsubscribe_token = $1
+		ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, updated_at = NOW()
+	`
+	res, err := r.db.ExecContext(ctx, query, tokenHash, FrequencyOff)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package digests
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+type DigestUsecase interface {
+	GetPreferences(ctx context.Context, userID int64) (*Preferences, error)
+	SetPreferences(ctx context.Context, userID int64, frequency string) (*Preferences, error)
+	Unsubscribe(ctx context.Context, token string) error
+}
+
+type usecase struct {
+	repo DigestRepository
+}
+
+func NewDigestUsecase(repo DigestRepository) DigestUsecase {
+	return &usecase{repo: repo}
+}
+
+func (uc *usecase) GetPreferences(ctx context.Context, userID int64) (*Preferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.GetPreferences(ctx, userID)
+}
+
+func (uc *usecase) SetPreferences(ctx context.Context, userID int64, frequency string) (*Preferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.SetPreferences(ctx, userID, frequency); err != nil {
+		return nil, err
+	}
+
+	return &Preferences{Frequency: frequency}, nil
+}
+
+// Unsubscribe turns digests off for the user the token was mailed to. It
+// is idempotent, the token stays valid.
+func (uc *usecase) Unsubscribe(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.Unsubscribe(ctx, hashToken(token)); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrInvalidLink
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,184 @@
+package digests
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/feed"
+	"github.com/codepnw/gopher-social/internal/domains/notifications"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+	"github.com/google/uuid"
+)
+
+const sendTimeout = 30 * time.Second
+
+// Worker periodically mails digests to inactive users. Every delivery is
+// claimed in digest_deliveries before the mail goes out, so restarts and
+// concurrent instances never send a period twice. A crash between claim
+// and send loses that digest rather than duplicating it.
+type Worker struct {
+	repo          DigestRepository
+	feed          feed.FeedRepository
+	notifications notifications.NotificationRepository
+	mailer        mailer.Client
+	config        config.Config
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorker(repo DigestRepository, feed feed.FeedRepository, notifications notifications.NotificationRepository, mailer mailer.Client, config config.Config) *Worker {
+	return &Worker{
+		repo:          repo,
+		feed:          feed,
+		notifications: notifications,
+		mailer:        mailer,
+		config:        config,
+	}
+}
+
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.config.Mail.Digest.Interval)
+		defer ticker.Stop()
+
+		for {
+			w.run(ctx, time.Now())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the worker and waits for the current run to finish.
+func (w *Worker) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+func (w *Worker) run(ctx context.Context, now time.Time) {
+	limit := max(w.config.Mail.Digest.BatchSize, 1)
+
+	for _, frequency := range []string{FrequencyDaily, FrequencyWeekly} {
+		start, lookback := period(frequency, now)
+
+		var afterID int64
+		for ctx.Err() == nil {
+			recipients, err := w.repo.DueRecipients(ctx, frequency, start, now.Add(-lookback), afterID, limit)
+			if err != nil {
+				log.Println("failed to load digest recipients:", frequency, err)
+				break
+			}
+
+			for _, rc := range recipients {
+				if err := w.send(ctx, rc, frequency, start, now.Add(-lookback)); err != nil {
+					log.Println("failed to send digest:", frequency, rc.ID, err)
+				}
+				afterID = rc.ID
+			}
+
+			if len(recipients) < limit {
+				break
+			}
+		}
+	}
+}
+
+func (w *Worker) send(ctx context.Context, rc Recipient, frequency string, start, since time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	token := uuid.New().String()
+
+	claimed, err := w.repo.Claim(ctx, rc.ID, frequency, start, hashToken(token))
+	if err != nil || !claimed {
+		return err
+	}
+
+	digest, err := w.build(ctx, rc, frequency, since, token)
+	if err != nil {
+		return w.release(ctx, rc.ID, frequency, start, err)
+	}
+
+	// the claim stays, with sent_at unset, so quiet periods are not
+	// rebuilt on every run
+	if digest.Empty() {
+		return nil
+	}
+
+	isSandbox := w.config.App.Env != "production"
+	if err := w.mailer.Send(mailer.UserDigestTemplate, rc.Username, rc.Email, digest, isSandbox); err != nil {
+		return w.release(ctx, rc.ID, frequency, start, err)
+	}
+
+	return w.repo.MarkSent(ctx, rc.ID, frequency, start, len(digest.Posts)+len(digest.Notifications))
+}
+
+// release drops the claim so the next run retries, and returns cause.
+func (w *Worker) release(ctx context.Context, userID int64, frequency string, start time.Time, cause error) error {
+	if err := w.repo.Release(ctx, userID, frequency, start); err != nil {
+		log.Println("failed to release digest claim:", userID, err)
+	}
+
+	return cause
+}
+
+func (w *Worker) build(ctx context.Context, rc Recipient, frequency string, since time.Time, token string) (*Digest, error) {
+	viewer := &users.User{ID: rc.ID}
+
+	items, err := w.feed.GetUserFeed(ctx, rc.ID, viewer, feed.PaginatedFeedQuery{
+		Limit: maxDigestItems,
+		Sort:  "desc",
+		Tags:  []string{},
+		Since: since.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []feed.PostWithMetaData
+	for _, p := range items {
+		if p.UserID != rc.ID {
+			posts = append(posts, p)
+		}
+	}
+
+	pending, err := w.notifications.List(ctx, rc.ID, notifications.NotificationQuery{
+		Unread: true,
+		Limit:  maxDigestItems,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := w.notifications.UnreadCount(ctx, rc.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Digest{
+		Username:       rc.Username,
+		Frequency:      frequency,
+		Posts:          posts,
+		Notifications:  pending,
+		UnreadCount:    unread,
+		FrontendURL:    w.config.App.FrontendURL,
+		UnsubscribeURL: w.config.App.FrontendURL + "/digests/unsubscribe/" + token,
+	}, nil
+}
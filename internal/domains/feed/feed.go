@@ -1,11 +1,19 @@
 package feed
 
-import "database/sql"
+import (
+	"database/sql"
 
-func InitFeedDomain(db *sql.DB) FeedHandler {
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+func InitFeedDomain(db *sql.DB, storage blob.Storage, renderer *posts.Renderer) FeedHandler {
 	repo := NewFeedRepository(db)
-	uc := NewFeedUsecase(repo)
+	uc := NewFeedUsecase(repo, posts.NewPostRepository(db), media.NewMedia(db, storage), previews.NewPreviews(db, nil), polls.NewPolls(db, nil), renderer)
 	hdl := NewFeedHandler(uc)
 
 	return hdl
-}
\ No newline at end of file
+}
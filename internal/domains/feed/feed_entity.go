@@ -22,6 +22,8 @@ type PaginatedFeedQuery struct {
 type PostWithMetaData struct {
 	posts.Post
 	CommentsCount int `json:"comments_count"`
+	// RepostedBy is set when the post is in the feed because of a repost
+	RepostedBy *posts.Reposter `json:"reposted_by,omitempty"`
 }
 
 func (fq PaginatedFeedQuery) Parse(c *gin.Context) (PaginatedFeedQuery, error) {
@@ -1,10 +1,10 @@
 package feed
 
 import (
-	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/codepnw/gopher-social/internal/domains/users"
 	"github.com/codepnw/gopher-social/internal/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -36,14 +36,11 @@ func (h *handler) GetUserFeedHandler(c *gin.Context) {
 		return
 	}
 
-	feed, err := h.uc.GetUserFeed(c, userID, fq)
+	feed, err := h.uc.GetUserFeed(c, userID, users.GetAuthUserFromContext(c), fq)
 	if err != nil {
-		response.InternalServerError(c, err)
+		response.Error(c, err)
 		return
 	}
 
-	// TODO: feed empty fix later
-	log.Println("Feed =======", userID, feed)
-
 	response.ResponseData(c, http.StatusOK, feed)
 }
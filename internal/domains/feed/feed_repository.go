@@ -4,11 +4,13 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/users"
 	"github.com/lib/pq"
 )
 
 type FeedRepository interface {
-	GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
+	GetUserFeed(ctx context.Context, userID int64, viewer *users.User, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
 }
 
 type repository struct {
@@ -19,25 +21,74 @@ func NewFeedRepository(db *sql.DB) FeedRepository {
 	return &repository{db: db}
 }
 
-func (r *repository) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+func (r *repository) GetUserFeed(ctx context.Context, userID int64, viewer *users.User, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
 	query := `
+		WITH items AS (
+			SELECT p.id AS post_id, NULL::bigint AS reposter_id, p.created_at AS activity_at
+			FROM posts p
+			WHERE
+				p.user_id = $1 OR
+				p.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1) OR
+				p.tags && ARRAY(SELECT tag FROM tag_follows WHERE user_id = $1)
+			UNION ALL
+			SELECT r.post_id, r.user_id, r.created_at
+			FROM reposts r
+			WHERE
+				(r.user_id = $1 OR r.user_id IN (SELECT user_id FROM followers WHERE follower_id = $1)) AND
+				NOT EXISTS (
+					SELECT 1 FROM user_blocks b
+					WHERE (b.blocker_id = $1 AND b.blocked_id = r.user_id)
+						OR (b.blocker_id = r.user_id AND b.blocked_id = $1)
+				)
+		),
+		-- a post reached directly and through reposts, or through several
+		-- reposts, is shown once at its latest activity
+		latest AS (
+			SELECT DISTINCT ON (post_id) post_id, reposter_id, activity_at
+			FROM items
+			ORDER BY post_id, activity_at DESC
+		)
 		SELECT 
-			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
-			u.username, COUNT(c.id) AS comments_count
-		FROM posts p
-		LEFT JOIN comments c ON c.post_id = p.id
+			p.id, p.user_id, p.title, p.content, p.format, p.created_at, p.version, p.tags,
+			p.is_quote, p.quoted_post_id, u.username,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts_count,
+			(SELECT COUNT(*) FROM posts q WHERE q.quoted_post_id = p.id AND q.hidden_at IS NULL AND q.publish_at IS NULL AND q.deleted_at IS NULL) AS quotes_count,
+			l.reposter_id, ru.username, l.activity_at
+		FROM latest l
+		JOIN posts p ON p.id = l.post_id
 		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
+		LEFT JOIN users ru ON ru.id = l.reposter_id
 		WHERE 
-			f.user_id = $1 AND
 			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
+			(p.tags @> $5 OR $5 = '{}') AND
+			(p.hidden_at IS NULL OR p.user_id = $6 OR $7) AND
+			p.publish_at IS NULL AND
+			p.deleted_at IS NULL AND
+			l.activity_at >= COALESCE(NULLIF($8::text, '')::timestamptz, '-infinity') AND
+			l.activity_at <= COALESCE(NULLIF($9::text, '')::timestamptz, 'infinity') AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = $1 AND b.blocked_id = p.user_id)
+					OR (b.blocker_id = p.user_id AND b.blocked_id = $1)
+			)
+		ORDER BY l.activity_at ` + fq.Sort + `, p.id ` + fq.Sort + `
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		userID,
+		fq.Limit,
+		fq.Offset,
+		fq.Search,
+		pq.Array(fq.Tags),
+		viewer.ID,
+		viewer.IsModerator(),
+		fq.Since,
+		fq.Until,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -45,22 +96,43 @@ func (r *repository) GetUserFeed(ctx context.Context, userID int64, fq Paginated
 
 	var feed []PostWithMetaData
 	for rows.Next() {
-		var p PostWithMetaData
+		var (
+			p            PostWithMetaData
+			reposterID   *int64
+			reposterName *string
+			activityAt   string
+		)
 		err := rows.Scan(
 			&p.ID,
 			&p.UserID,
 			&p.Title,
 			&p.Content,
+			&p.Format,
 			&p.CreatedAt,
 			&p.Version,
 			pq.Array(&p.Tags),
+			&p.IsQuote,
+			&p.QuotedPostID,
 			&p.User.Username,
 			&p.CommentsCount,
+			&p.RepostsCount,
+			&p.QuotesCount,
+			&reposterID,
+			&reposterName,
+			&activityAt,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if reposterID != nil && reposterName != nil {
+			p.RepostedBy = &posts.Reposter{
+				UserID:     *reposterID,
+				Username:   *reposterName,
+				RepostedAt: activityAt,
+			}
+		}
+
 		feed = append(feed, p)
 	}
 
@@ -4,23 +4,84 @@ import (
 	"context"
 
 	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
+	"github.com/codepnw/gopher-social/internal/domains/users"
 )
 
 type FeedUsecase interface {
-	GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
+	GetUserFeed(ctx context.Context, userID int64, viewer *users.User, fq PaginatedFeedQuery) ([]PostWithMetaData, error)
 }
 
 type usecase struct {
-	repo FeedRepository
+	repo     FeedRepository
+	posts    posts.PostRepository
+	media    media.MediaUsecase
+	previews previews.PreviewUsecase
+	polls    polls.PollUsecase
+	renderer *posts.Renderer
 }
 
-func NewFeedUsecase(repo FeedRepository) FeedUsecase {
-	return &usecase{repo: repo}
+func NewFeedUsecase(repo FeedRepository, posts posts.PostRepository, media media.MediaUsecase, previews previews.PreviewUsecase, polls polls.PollUsecase, renderer *posts.Renderer) FeedUsecase {
+	return &usecase{
+		repo:     repo,
+		posts:    posts,
+		media:    media,
+		previews: previews,
+		polls:    polls,
+		renderer: renderer,
+	}
 }
 
-func (uc *usecase) GetUserFeed(ctx context.Context, userID int64, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
+func (uc *usecase) GetUserFeed(ctx context.Context, userID int64, viewer *users.User, fq PaginatedFeedQuery) ([]PostWithMetaData, error) {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	return uc.repo.GetUserFeed(ctx, userID, fq)
+	tags, err := posts.NormalizeTags(fq.Tags)
+	if err != nil {
+		return nil, err
+	}
+	fq.Tags = tags
+
+	feed, err := uc.repo.GetUserFeed(ctx, userID, viewer, fq)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(feed))
+	for i := range feed {
+		ids[i] = feed[i].ID
+	}
+
+	attachments, err := uc.media.ListByPosts(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := uc.previews.ListByPosts(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	attached, err := uc.polls.ListByPosts(ctx, viewer, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*posts.Post, len(feed))
+	for i := range feed {
+		feed[i].Attachments = attachments[feed[i].ID]
+		feed[i].Preview = linked[feed[i].ID]
+		feed[i].Poll = attached[feed[i].ID]
+		uc.renderer.Render(&feed[i].Post)
+		items[i] = &feed[i].Post
+	}
+
+	if err := posts.LoadQuotes(ctx, uc.posts, items...); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
 }
@@ -0,0 +1,21 @@
+package filters
+
+import (
+	"database/sql"
+	"time"
+)
+
+// rulesTTL bounds how stale another instance's rule changes can be.
+const rulesTTL = 30 * time.Second
+
+func NewFilter(db *sql.DB) *Engine {
+	return NewEngine(NewFilterRepository(db), rulesTTL)
+}
+
+func InitFilterDomain(db *sql.DB, engine *Engine) FilterHandler {
+	repo := NewFilterRepository(db)
+	uc := NewFilterUsecase(db, repo, engine)
+	hdl := NewFilterHandler(uc)
+
+	return hdl
+}
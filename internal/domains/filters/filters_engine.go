@@ -0,0 +1,254 @@
+package filters
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Filter interface {
+	// Check runs every enabled rule over fields and returns the strictest
+	// action together with the masked fields.
+	Check(ctx context.Context, fields ...string) (*Result, error)
+	// Record stores the matches of result for the moderation team.
+	Record(ctx context.Context, targetType string, targetID *int64, userID int64, result *Result) error
+}
+
+type compiledRule struct {
+	rule    Rule
+	re      *regexp.Regexp
+	domains []string
+}
+
+// Engine keeps the compiled rules in memory and reloads them when the
+// rules version in the database changes, so edits apply without a restart.
+type Engine struct {
+	repo FilterRepository
+	ttl  time.Duration
+
+	mu        sync.RWMutex
+	rules     []compiledRule
+	version   string
+	checkedAt time.Time
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[a-z0-9-]+\.)+[a-z]{2,}(?::\d+)?(?:/[^\s]*)?`)
+
+func NewEngine(repo FilterRepository, ttl time.Duration) *Engine {
+	return &Engine{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Invalidate forces the next Check to reload the rules.
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	e.checkedAt = time.Time{}
+	e.version = ""
+	e.mu.Unlock()
+}
+
+func (e *Engine) Check(ctx context.Context, fields ...string) (*Result, error) {
+	rules, err := e.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Action: ActionAllow,
+		Fields: append([]string(nil), fields...),
+	}
+
+	for _, cr := range rules {
+		for i, text := range result.Fields {
+			matched := cr.match(text)
+			if len(matched) == 0 {
+				continue
+			}
+
+			if cr.rule.Action == ActionMask {
+				result.Fields[i] = cr.mask(text)
+			}
+
+			if actionRank[cr.rule.Action] > actionRank[result.Action] {
+				result.Action = cr.rule.Action
+			}
+
+			for _, m := range matched {
+				result.Matches = append(result.Matches, Match{
+					RuleID:  &cr.rule.ID,
+					Action:  cr.rule.Action,
+					Matched: m,
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (e *Engine) Record(ctx context.Context, targetType string, targetID *int64, userID int64, result *Result) error {
+	if len(result.Matches) == 0 {
+		return nil
+	}
+
+	matches := make([]Match, len(result.Matches))
+	for i, m := range result.Matches {
+		m.TargetType = targetType
+		m.TargetID = targetID
+		m.UserID = userID
+		matches[i] = m
+	}
+
+	return e.repo.CreateMatches(ctx, matches)
+}
+
+func (e *Engine) loadRules(ctx context.Context) ([]compiledRule, error) {
+	e.mu.RLock()
+	rules, fresh := e.rules, time.Since(e.checkedAt) < e.ttl
+	e.mu.RUnlock()
+
+	if fresh {
+		return rules, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// another request may have reloaded while we waited for the lock
+	if time.Since(e.checkedAt) < e.ttl {
+		return e.rules, nil
+	}
+
+	version, err := e.repo.RulesVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != e.version {
+		list, err := e.repo.ListRules(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled := make([]compiledRule, 0, len(list))
+		for _, rule := range list {
+			cr, err := compileRule(rule)
+			if err != nil {
+				// rules are validated on write, skip anything that still fails
+				log.Println("filter rule skipped:", rule.ID, err)
+				continue
+			}
+			compiled = append(compiled, cr)
+		}
+
+		e.rules = compiled
+		e.version = version
+	}
+
+	e.checkedAt = time.Now()
+
+	return e.rules, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	cr := compiledRule{rule: rule}
+
+	switch rule.Kind {
+	case KindWord:
+		words := make([]string, 0, len(rule.Patterns))
+		for _, w := range rule.Patterns {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, regexp.QuoteMeta(w))
+			}
+		}
+		if len(words) == 0 {
+			return cr, errors.New("empty word list")
+		}
+		re, err := regexp.Compile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)
+		if err != nil {
+			return cr, err
+		}
+		cr.re = re
+
+	case KindRegex:
+		re, err := regexp.Compile(`(?i)(?:` + strings.Join(rule.Patterns, ")|(?:") + `)`)
+		if err != nil {
+			return cr, err
+		}
+		cr.re = re
+
+	case KindDomain:
+		for _, d := range rule.Patterns {
+			d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+			if d != "" {
+				cr.domains = append(cr.domains, d)
+			}
+		}
+	}
+
+	return cr, nil
+}
+
+func (cr compiledRule) match(text string) []string {
+	if cr.re != nil {
+		return cr.re.FindAllString(text, -1)
+	}
+
+	var matched []string
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		if cr.blocksLink(link) {
+			matched = append(matched, link)
+		}
+	}
+
+	return matched
+}
+
+// mask stars out what the rule matches, whole links for domain rules.
+func (cr compiledRule) mask(text string) string {
+	stars := func(s string) string {
+		return strings.Repeat("*", len([]rune(s)))
+	}
+
+	if cr.re != nil {
+		return cr.re.ReplaceAllStringFunc(text, stars)
+	}
+
+	return urlPattern.ReplaceAllStringFunc(text, func(link string) string {
+		if cr.blocksLink(link) {
+			return stars(link)
+		}
+		return link
+	})
+}
+
+func (cr compiledRule) blocksLink(link string) bool {
+	host := linkHost(link)
+	for _, d := range cr.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
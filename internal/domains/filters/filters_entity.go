@@ -0,0 +1,109 @@
+package filters
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	KindWord   = "word"
+	KindRegex  = "regex"
+	KindDomain = "domain"
+
+	ActionAllow  = "allow"
+	ActionMask   = "mask"
+	ActionHold   = "hold"
+	ActionReject = "reject"
+
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// actionRank orders actions, the strictest matching rule wins.
+var actionRank = map[string]int{
+	ActionAllow:  0,
+	ActionMask:   1,
+	ActionHold:   2,
+	ActionReject: 3,
+}
+
+type Rule struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Patterns  []string `json:"patterns"`
+	Action    string   `json:"action"`
+	Enabled   bool     `json:"enabled"`
+	CreatedBy *int64   `json:"created_by"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+type RulePayload struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	Kind     string   `json:"kind" binding:"required,oneof=word regex domain"`
+	Patterns []string `json:"patterns" binding:"required,min=1,max=500,dive,required,max=200"`
+	Action   string   `json:"action" binding:"required,oneof=reject hold mask"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+type Match struct {
+	ID         int64   `json:"id"`
+	RuleID     *int64  `json:"rule_id"`
+	TargetType string  `json:"target_type"`
+	TargetID   *int64  `json:"target_id"`
+	UserID     int64   `json:"user_id"`
+	Action     string  `json:"action"`
+	Matched    string  `json:"matched"`
+	ReviewedBy *int64  `json:"reviewed_by"`
+	ReviewedAt *string `json:"reviewed_at"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+type MatchQuery struct {
+	Action     string `json:"action" binding:"omitempty,oneof=reject hold mask"`
+	Unreviewed bool   `json:"unreviewed"`
+	Limit      int    `json:"limit" binding:"gte=1,lte=100"`
+	Offset     int    `json:"offset" binding:"gte=0"`
+}
+
+// Result is the outcome of running text through every enabled rule. Fields
+// holds the input with mask rules applied, in the order it was given.
+type Result struct {
+	Action  string
+	Fields  []string
+	Matches []Match
+}
+
+func (q MatchQuery) Parse(c *gin.Context) (MatchQuery, error) {
+	if action := c.Query("action"); action != "" {
+		q.Action = action
+	}
+
+	if unreviewed := c.Query("unreviewed"); unreviewed != "" {
+		u, err := strconv.ParseBool(unreviewed)
+		if err != nil {
+			return q, err
+		}
+		q.Unreviewed = u
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
@@ -0,0 +1,133 @@
+package filters
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type FilterHandler interface {
+	ListRulesHandler(c *gin.Context)
+	CreateRuleHandler(c *gin.Context)
+	UpdateRuleHandler(c *gin.Context)
+	DeleteRuleHandler(c *gin.Context)
+
+	ListMatchesHandler(c *gin.Context)
+	ApproveMatchHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc FilterUsecase
+}
+
+func NewFilterHandler(uc FilterUsecase) FilterHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) ListRulesHandler(c *gin.Context) {
+	rules, err := h.uc.ListRules(c)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, rules)
+}
+
+func (h *handler) CreateRuleHandler(c *gin.Context) {
+	var payload RulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	rule, err := h.uc.CreateRule(c, users.GetAuthUserFromContext(c), &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, rule)
+}
+
+func (h *handler) UpdateRuleHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload RulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	rule, err := h.uc.UpdateRule(c, id, &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, rule)
+}
+
+func (h *handler) DeleteRuleHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.DeleteRule(c, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) ListMatchesHandler(c *gin.Context) {
+	q := MatchQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	matches, err := h.uc.ListMatches(c, q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, matches)
+}
+
+func (h *handler) ApproveMatchHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.ApproveMatch(c, users.GetAuthUserFromContext(c), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
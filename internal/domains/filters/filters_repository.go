@@ -0,0 +1,247 @@
+package filters
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type FilterRepository interface {
+	ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error)
+	RulesVersion(ctx context.Context) (string, error)
+	GetRule(ctx context.Context, id int64) (*Rule, error)
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id int64) error
+
+	CreateMatches(ctx context.Context, matches []Match) error
+	ListMatches(ctx context.Context, q MatchQuery) ([]Match, error)
+	GetMatch(ctx context.Context, id int64) (*Match, error)
+	ReviewMatches(ctx context.Context, tx *sql.Tx, targetType string, targetID, moderatorID int64) error
+	ReleaseContent(ctx context.Context, tx *sql.Tx, targetType string, targetID int64) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewFilterRepository(db *sql.DB) FilterRepository {
+	return &repository{db: db}
+}
+
+const ruleColumns = `id, name, kind, patterns, action, enabled, created_by, created_at, updated_at`
+
+func scanRule(row interface{ Scan(...any) error }, rule *Rule) error {
+	return row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Kind,
+		pq.Array(&rule.Patterns),
+		&rule.Action,
+		&rule.Enabled,
+		&rule.CreatedBy,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+}
+
+func (r *repository) ListRules(ctx context.Context, enabledOnly bool) ([]Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM filter_rules WHERE enabled OR NOT $1 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		var rule Rule
+		if err := scanRule(rows, &rule); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// RulesVersion changes whenever a rule is added, edited or removed.
+func (r *repository) RulesVersion(ctx context.Context) (string, error) {
+	query := `SELECT COUNT(*) || ':' || COALESCE(EXTRACT(EPOCH FROM MAX(updated_at))::TEXT, '0') FROM filter_rules`
+
+	var version string
+	if err := r.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
+
+func (r *repository) GetRule(ctx context.Context, id int64) (*Rule, error) {
+	query := `SELECT ` + ruleColumns + ` FROM filter_rules WHERE id = $1`
+
+	var rule Rule
+	if err := scanRule(r.db.QueryRowContext(ctx, query, id), &rule); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (r *repository) CreateRule(ctx context.Context, rule *Rule) error {
+	query := `
+		INSERT INTO filter_rules (name, kind, patterns, action, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		rule.Name,
+		rule.Kind,
+		pq.Array(rule.Patterns),
+		rule.Action,
+		rule.Enabled,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateRule(ctx context.Context, rule *Rule) error {
+	query := `
+		UPDATE filter_rules SET
+			name = $1, kind = $2, patterns = $3, action = $4, enabled = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		rule.Name,
+		rule.Kind,
+		pq.Array(rule.Patterns),
+		rule.Action,
+		rule.Enabled,
+		rule.ID,
+	).Scan(&rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteRule(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM filter_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *repository) CreateMatches(ctx context.Context, matches []Match) error {
+	query := `
+		INSERT INTO filter_matches (rule_id, target_type, target_id, user_id, action, matched)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, m := range matches {
+		_, err := r.db.ExecContext(ctx, query, m.RuleID, m.TargetType, m.TargetID, m.UserID, m.Action, m.Matched)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const matchColumns = `id, rule_id, target_type, target_id, user_id, action, matched, reviewed_by, reviewed_at, created_at`
+
+func scanMatch(row interface{ Scan(...any) error }, m *Match) error {
+	return row.Scan(
+		&m.ID,
+		&m.RuleID,
+		&m.TargetType,
+		&m.TargetID,
+		&m.UserID,
+		&m.Action,
+		&m.Matched,
+		&m.ReviewedBy,
+		&m.ReviewedAt,
+		&m.CreatedAt,
+	)
+}
+
+func (r *repository) ListMatches(ctx context.Context, q MatchQuery) ([]Match, error) {
+	query := `
+		SELECT ` + matchColumns + `
+		FROM filter_matches
+		WHERE
+			($1 = '' OR action = $1) AND
+			(NOT $2 OR reviewed_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, q.Action, q.Unreviewed, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []Match{}
+	for rows.Next() {
+		var m Match
+		if err := scanMatch(rows, &m); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+func (r *repository) GetMatch(ctx context.Context, id int64) (*Match, error) {
+	query := `SELECT ` + matchColumns + ` FROM filter_matches WHERE id = $1`
+
+	var m Match
+	if err := scanMatch(r.db.QueryRowContext(ctx, query, id), &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (r *repository) ReviewMatches(ctx context.Context, tx *sql.Tx, targetType string, targetID, moderatorID int64) error {
+	query := `
+		UPDATE filter_matches SET reviewed_by = $1, reviewed_at = NOW()
+		WHERE target_type = $2 AND target_id = $3 AND reviewed_at IS NULL
+	`
+	_, err := tx.ExecContext(ctx, query, moderatorID, targetType, targetID)
+	return err
+}
+
+func (r *repository) ReleaseContent(ctx context.Context, tx *sql.Tx, targetType string, targetID int64) error {
+	table := "posts"
+	if targetType == TargetComment {
+		table = "comments"
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET hidden_at = NULL WHERE id = $1`, targetID)
+	return err
+}
@@ -0,0 +1,168 @@
+package filters
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+type FilterUsecase interface {
+	ListRules(ctx context.Context) ([]Rule, error)
+	CreateRule(ctx context.Context, actor *users.User, payload *RulePayload) (*Rule, error)
+	UpdateRule(ctx context.Context, id int64, payload *RulePayload) (*Rule, error)
+	DeleteRule(ctx context.Context, id int64) error
+
+	ListMatches(ctx context.Context, q MatchQuery) ([]Match, error)
+	ApproveMatch(ctx context.Context, moderator *users.User, matchID int64) error
+}
+
+type usecase struct {
+	db     *sql.DB
+	repo   FilterRepository
+	engine *Engine
+}
+
+func NewFilterUsecase(db *sql.DB, repo FilterRepository, engine *Engine) FilterUsecase {
+	return &usecase{
+		db:     db,
+		repo:   repo,
+		engine: engine,
+	}
+}
+
+func (uc *usecase) ListRules(ctx context.Context) ([]Rule, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.ListRules(ctx, false)
+}
+
+func (uc *usecase) CreateRule(ctx context.Context, actor *users.User, payload *RulePayload) (*Rule, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	rule := newRule(payload)
+	rule.CreatedBy = &actor.ID
+
+	if _, err := compileRule(*rule); err != nil {
+		return nil, commons.ErrInvalidFilterRule
+	}
+
+	if err := uc.repo.CreateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	uc.engine.Invalidate()
+
+	return rule, nil
+}
+
+func (uc *usecase) UpdateRule(ctx context.Context, id int64, payload *RulePayload) (*Rule, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	rule, err := uc.repo.GetRule(ctx, id)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	updated := newRule(payload)
+	updated.ID = rule.ID
+	updated.CreatedBy = rule.CreatedBy
+	updated.CreatedAt = rule.CreatedAt
+	if payload.Enabled == nil {
+		updated.Enabled = rule.Enabled
+	}
+
+	if _, err := compileRule(*updated); err != nil {
+		return nil, commons.ErrInvalidFilterRule
+	}
+
+	if err := uc.repo.UpdateRule(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	uc.engine.Invalidate()
+
+	return updated, nil
+}
+
+func (uc *usecase) DeleteRule(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.DeleteRule(ctx, id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	uc.engine.Invalidate()
+
+	return nil
+}
+
+func (uc *usecase) ListMatches(ctx context.Context, q MatchQuery) ([]Match, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.ListMatches(ctx, q)
+}
+
+// ApproveMatch marks every match on the same content as reviewed and
+// publishes it if it was held.
+func (uc *usecase) ApproveMatch(ctx context.Context, moderator *users.User, matchID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	match, err := uc.repo.GetMatch(ctx, matchID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	// rejected content was never stored
+	if match.TargetID == nil {
+		return commons.ErrNotFound
+	}
+
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if match.Action == ActionHold {
+			if err := uc.repo.ReleaseContent(ctx, tx, match.TargetType, *match.TargetID); err != nil {
+				return err
+			}
+		}
+
+		return uc.repo.ReviewMatches(ctx, tx, match.TargetType, *match.TargetID, moderator.ID)
+	})
+}
+
+func newRule(payload *RulePayload) *Rule {
+	rule := &Rule{
+		Name:     payload.Name,
+		Kind:     payload.Kind,
+		Patterns: payload.Patterns,
+		Action:   payload.Action,
+		Enabled:  true,
+	}
+
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+
+	return rule
+}
@@ -0,0 +1,23 @@
+package media
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+func InitMediaDomain(db *sql.DB, cfg config.Config, storage blob.Storage) MediaHandler {
+	uc := NewMedia(db, storage)
+	hdl := NewMediaHandler(uc, cfg.Media.MaxUploadSize)
+
+	return hdl
+}
+
+func NewMedia(db *sql.DB, storage blob.Storage) MediaUsecase {
+	return NewMediaUsecase(NewMediaRepository(db), storage)
+}
+
+func NewMediaCleaner(db *sql.DB, cfg config.Config, storage blob.Storage) *Cleaner {
+	return NewCleaner(NewMedia(db, storage), cfg.Media.OrphanTTL)
+}
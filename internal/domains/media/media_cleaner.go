@@ -0,0 +1,77 @@
+package media
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	cleanupInterval  = time.Hour
+	cleanupBatchSize = 100
+)
+
+// Cleaner periodically deletes uploads that were never attached to a post
+// within ttl, and those of deleted posts.
+type Cleaner struct {
+	uc  MediaUsecase
+	ttl time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewCleaner(uc MediaUsecase, ttl time.Duration) *Cleaner {
+	return &Cleaner{
+		uc:  uc,
+		ttl: ttl,
+	}
+}
+
+func (cl *Cleaner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cl.cancel = cancel
+
+	cl.wg.Add(1)
+	go func() {
+		defer cl.wg.Done()
+
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for {
+			cl.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (cl *Cleaner) Close() {
+	if cl.cancel != nil {
+		cl.cancel()
+	}
+	cl.wg.Wait()
+}
+
+func (cl *Cleaner) run(ctx context.Context) {
+	before := time.Now().Add(-cl.ttl)
+
+	for ctx.Err() == nil {
+		removed, err := cl.uc.CleanupOrphans(ctx, before, cleanupBatchSize)
+		if err != nil {
+			log.Println("failed to clean up orphaned uploads:", err)
+			return
+		}
+
+		// a short batch, or one where every file failed, ends the run
+		if removed < cleanupBatchSize {
+			return
+		}
+	}
+}
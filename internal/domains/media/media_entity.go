@@ -0,0 +1,32 @@
+package media
+
+const (
+	// MaxAttachments per post.
+	MaxAttachments = 4
+
+	maxPixels     = 40_000_000
+	thumbnailSize = 320
+	jpegQuality   = 90
+)
+
+// allowedTypes maps the sniffed content type to the stored file extension.
+var allowedTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type Attachment struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	PostID       *int64 `json:"post_id,omitempty"`
+	ContentType  string `json:"content_type"`
+	Size         int    `json:"size"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	CreatedAt    string `json:"created_at"`
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
@@ -0,0 +1,106 @@
+package media
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead leaves room for the form boundaries and headers around
+// the file itself.
+const multipartOverhead = 1 << 20
+
+type MediaHandler interface {
+	UploadHandler(c *gin.Context)
+	GetHandler(c *gin.Context)
+	DeleteHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc      MediaUsecase
+	maxSize int64
+}
+
+func NewMediaHandler(uc MediaUsecase, maxSize int64) MediaHandler {
+	return &handler{
+		uc:      uc,
+		maxSize: maxSize,
+	}
+}
+
+func (h *handler) UploadHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, commons.ErrMediaTooLarge)
+			return
+		}
+		response.BadRequestResponse(c, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxSize {
+		response.Error(c, commons.ErrMediaTooLarge)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, h.maxSize+1))
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if int64(len(data)) > h.maxSize {
+		response.Error(c, commons.ErrMediaTooLarge)
+		return
+	}
+
+	attachment, err := h.uc.Upload(c, users.GetAuthUserFromContext(c), data)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, attachment)
+}
+
+func (h *handler) GetHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	attachment, err := h.uc.GetByID(c, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, attachment)
+}
+
+func (h *handler) DeleteHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.Delete(c, users.GetAuthUserFromContext(c), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
@@ -0,0 +1,230 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+// testImage is w x h, red in the top left quarter and blue elsewhere, so
+// tests can tell where the corner ended up.
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 && y < h/2 {
+				img.Set(x, y, color.NRGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment with the orientation tag
+// right after the JPEG SOI marker.
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112) // orientation
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)      // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+
+	return append(out, data[2:]...)
+}
+
+func inward(v, size int) int {
+	if v == 0 {
+		return 3
+	}
+	return size - 4
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func TestProcessImagePNG(t *testing.T) {
+	out, err := processImage(encodePNG(t, testImage(800, 400)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.ContentType != "image/png" || out.Ext != ".png" || out.Width != 800 || out.Height != 400 {
+		t.Errorf("got %s %s %dx%d", out.ContentType, out.Ext, out.Width, out.Height)
+	}
+
+	thumb, err := png.Decode(bytes.NewReader(out.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != thumbnailSize || b.Dy() != thumbnailSize/2 {
+		t.Errorf("thumbnail is %dx%d", b.Dx(), b.Dy())
+	}
+	if out.ThumbnailType != "image/png" || out.ThumbnailExt != ".png" {
+		t.Errorf("thumbnail type %s %s", out.ThumbnailType, out.ThumbnailExt)
+	}
+}
+
+func TestProcessImageSmallKeepsSize(t *testing.T) {
+	out, err := processImage(encodePNG(t, testImage(10, 20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumb, err := png.Decode(bytes.NewReader(out.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 10 || b.Dy() != 20 {
+		t.Errorf("thumbnail is %dx%d, want the original 10x20", b.Dx(), b.Dy())
+	}
+}
+
+func TestProcessImageJPEGOrientation(t *testing.T) {
+	src := encodeJPEG(t, testImage(40, 20))
+
+	tests := []struct {
+		orientation   uint16
+		width, height int
+		// where the red top left corner of the source ends up
+		redX, redY int
+	}{
+		{1, 40, 20, 0, 0},
+		{3, 40, 20, 39, 19},
+		{6, 20, 40, 19, 0},
+		{8, 20, 40, 0, 39},
+	}
+
+	for _, tt := range tests {
+		data := withOrientation(src, tt.orientation)
+		if got := jpegOrientation(data); got != int(tt.orientation) {
+			t.Fatalf("jpegOrientation = %d, want %d", got, tt.orientation)
+		}
+
+		out, err := processImage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if out.Width != tt.width || out.Height != tt.height {
+			t.Errorf("orientation %d: %dx%d, want %dx%d", tt.orientation, out.Width, out.Height, tt.width, tt.height)
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(out.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a few pixels in, JPEG blurs the edges
+		x, y := inward(tt.redX, tt.width), inward(tt.redY, tt.height)
+		if !isRed(img.At(x, y)) || isRed(img.At(tt.width-1-x, tt.height-1-y)) {
+			t.Errorf("orientation %d: corner not at (%d, %d)", tt.orientation, tt.redX, tt.redY)
+		}
+
+		// re-encoding drops the EXIF segment along with the tag
+		if bytes.Contains(out.Data, []byte("Exif")) {
+			t.Errorf("orientation %d: EXIF kept", tt.orientation)
+		}
+	}
+}
+
+func TestProcessImageGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 500, 500), palette),
+			image.NewPaletted(image.Rect(0, 0, 500, 500), palette),
+		},
+		Delay: []int{10, 10},
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := processImage(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.DecodeAll(bytes.NewReader(out.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 2 {
+		t.Errorf("kept %d frames, want 2", len(decoded.Image))
+	}
+	if out.ThumbnailType != "image/png" {
+		t.Errorf("thumbnail type %s, want image/png", out.ThumbnailType)
+	}
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	// a valid PNG header claiming more pixels than allowed
+	huge := encodePNG(t, testImage(1, 1))
+	ihdr := huge[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], 10_000)
+	binary.BigEndian.PutUint32(ihdr[4:], 10_000)
+	binary.BigEndian.PutUint32(huge[8+8+13:], crc32.ChecksumIEEE(huge[8+4:8+8+13]))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("hello, not an image"), commons.ErrUnsupportedMedia},
+		{"html", []byte("<html><script>alert(1)</script></html>"), commons.ErrUnsupportedMedia},
+		{"truncated png", encodePNG(t, testImage(50, 50))[:60], commons.ErrUnsupportedMedia},
+		{"too many pixels", huge, commons.ErrMediaTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := processImage(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("processImage error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type MediaRepository interface {
+	Create(ctx context.Context, a *Attachment) error
+	GetByID(ctx context.Context, id int64) (*Attachment, error)
+	Delete(ctx context.Context, id int64) error
+	Attach(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) (int64, error)
+	ListByPosts(ctx context.Context, postIDs []int64) ([]Attachment, error)
+	ListOrphans(ctx context.Context, before time.Time, limit int) ([]Attachment, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewMediaRepository(db *sql.DB) MediaRepository {
+	return &repository{db: db}
+}
+
+const attachmentColumns = `
+	id, user_id, post_id, storage_key, thumbnail_key, content_type, size, width, height, created_at
+`
+
+func scanAttachment(row interface{ Scan(...any) error }, a *Attachment) error {
+	return row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.PostID,
+		&a.Key,
+		&a.ThumbnailKey,
+		&a.ContentType,
+		&a.Size,
+		&a.Width,
+		&a.Height,
+		&a.CreatedAt,
+	)
+}
+
+func (r *repository) Create(ctx context.Context, a *Attachment) error {
+	query := `
+		INSERT INTO attachments (user_id, storage_key, thumbnail_key, content_type, size, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(
+		ctx,
+		query,
+		a.UserID,
+		a.Key,
+		a.ThumbnailKey,
+		a.ContentType,
+		a.Size,
+		a.Width,
+		a.Height,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *repository) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	var a Attachment
+	if err := scanAttachment(r.db.QueryRowContext(ctx, query, id), &a); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (r *repository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM attachments WHERE id = $1`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Attach links the user's unattached uploads to a post, in the given order.
+// It returns how many were linked so callers can reject foreign or already
+// used ids.
+func (r *repository) Attach(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) (int64, error) {
+	query := `
+		UPDATE attachments SET post_id = $1, position = array_position($3::bigint[], id)
+		WHERE id = ANY($3) AND user_id = $2 AND post_id IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, postID, userID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *repository) ListByPosts(ctx context.Context, postIDs []int64) ([]Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE post_id = ANY($1)
+		ORDER BY post_id, position
+	`
+	return r.list(ctx, query, pq.Array(postIDs))
+}
+
+// ListOrphans returns uploads never attached to a post, or whose post was
+// deleted, created before before.
+func (r *repository) ListOrphans(ctx context.Context, before time.Time, limit int) ([]Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE post_id IS NULL AND created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	return r.list(ctx, query, before, limit)
+}
+
+func (r *repository) list(ctx context.Context, query string, args ...any) ([]Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
@@ -0,0 +1,227 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+)
+
+type MediaUsecase interface {
+	Upload(ctx context.Context, user *users.User, data []byte) (*Attachment, error)
+	GetByID(ctx context.Context, id int64) (*Attachment, error)
+	Delete(ctx context.Context, user *users.User, id int64) error
+	Attach(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) error
+	ListByPosts(ctx context.Context, postIDs ...int64) (map[int64][]Attachment, error)
+	CleanupOrphans(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type usecase struct {
+	repo    MediaRepository
+	storage blob.Storage
+}
+
+func NewMediaUsecase(repo MediaRepository, storage blob.Storage) MediaUsecase {
+	return &usecase{
+		repo:    repo,
+		storage: storage,
+	}
+}
+
+func (uc *usecase) Upload(ctx context.Context, user *users.User, data []byte) (*Attachment, error) {
+	img, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := auth.RandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attachment{
+		UserID:       user.ID,
+		ContentType:  img.ContentType,
+		Size:         len(img.Data),
+		Width:        img.Width,
+		Height:       img.Height,
+		Key:          fmt.Sprintf("attachments/%d/%s%s", user.ID, name, img.Ext),
+		ThumbnailKey: fmt.Sprintf("attachments/%d/%s_thumb%s", user.ID, name, img.ThumbnailExt),
+	}
+
+	if err := uc.storage.Put(ctx, a.Key, img.ContentType, img.Data); err != nil {
+		return nil, err
+	}
+
+	if err := uc.storage.Put(ctx, a.ThumbnailKey, img.ThumbnailType, img.Thumbnail); err != nil {
+		uc.removeFiles(ctx, a)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.Create(ctx, a); err != nil {
+		uc.removeFiles(ctx, a)
+		return nil, err
+	}
+
+	uc.setURLs(a)
+
+	return a, nil
+}
+
+func (uc *usecase) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	a, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	uc.setURLs(a)
+
+	return a, nil
+}
+
+func (uc *usecase) Delete(ctx context.Context, user *users.User, id int64) error {
+	a, err := uc.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if a.UserID != user.ID {
+		return commons.ErrForbidden
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.Delete(ctx, id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	uc.removeFiles(ctx, a)
+
+	return nil
+}
+
+// Attach runs inside the post's transaction. Every id must be an unused
+// upload of userID.
+func (uc *usecase) Attach(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) error {
+	ids = unique(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if len(ids) > MaxAttachments {
+		return fmt.Errorf("%w: at most %d attachments per post", commons.ErrInvalidAttachment, MaxAttachments)
+	}
+
+	n, err := uc.repo.Attach(ctx, tx, postID, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	if n != int64(len(ids)) {
+		return fmt.Errorf("%w: unknown or already used upload", commons.ErrInvalidAttachment)
+	}
+
+	return nil
+}
+
+func (uc *usecase) ListByPosts(ctx context.Context, postIDs ...int64) (map[int64][]Attachment, error) {
+	result := make(map[int64][]Attachment)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	attachments, err := uc.repo.ListByPosts(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		uc.setURLs(&attachments[i])
+		postID := *attachments[i].PostID
+		result[postID] = append(result[postID], attachments[i])
+	}
+
+	return result, nil
+}
+
+// CleanupOrphans deletes up to limit uploads that never made it into a
+// post, or lost theirs, before before. Files go first so a failure leaves
+// the row to retry.
+func (uc *usecase) CleanupOrphans(ctx context.Context, before time.Time, limit int) (int, error) {
+	orphans, err := uc.repo.ListOrphans(ctx, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for i := range orphans {
+		a := &orphans[i]
+
+		if err := uc.storage.Delete(ctx, a.Key); err != nil {
+			log.Println("failed to delete orphaned upload:", a.ID, err)
+			continue
+		}
+
+		if err := uc.storage.Delete(ctx, a.ThumbnailKey); err != nil {
+			log.Println("failed to delete orphaned upload:", a.ID, err)
+			continue
+		}
+
+		if err := uc.repo.Delete(ctx, a.ID); err != nil && err != sql.ErrNoRows {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (uc *usecase) setURLs(a *Attachment) {
+	a.URL = uc.storage.URL(a.Key)
+	a.ThumbnailURL = uc.storage.URL(a.ThumbnailKey)
+}
+
+func (uc *usecase) removeFiles(ctx context.Context, a *Attachment) {
+	for _, key := range []string{a.Key, a.ThumbnailKey} {
+		if err := uc.storage.Delete(ctx, key); err != nil {
+			log.Println("failed to delete upload:", key, err)
+		}
+	}
+}
+
+func unique(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+
+	var out []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
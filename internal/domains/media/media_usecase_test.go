@@ -0,0 +1,217 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+	"github.com/codepnw/gopher-social/internal/store/blob/blobtest"
+)
+
+// fakeRepository keeps attachments in memory, orphans are the ones
+// without a post.
+type fakeRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   map[int64]Attachment
+
+	createErr error
+}
+
+func newFakeRepository(rows ...Attachment) *fakeRepository {
+	r := &fakeRepository{rows: make(map[int64]Attachment)}
+	for _, a := range rows {
+		r.rows[a.ID] = a
+		r.nextID = max(r.nextID, a.ID)
+	}
+	return r
+}
+
+func (r *fakeRepository) Create(ctx context.Context, a *Attachment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.createErr != nil {
+		return r.createErr
+	}
+
+	r.nextID++
+	a.ID = r.nextID
+	r.rows[a.ID] = *a
+	return nil
+}
+
+func (r *fakeRepository) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.rows[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &a, nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rows[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.rows, id)
+	return nil
+}
+
+func (r *fakeRepository) Attach(ctx context.Context, tx *sql.Tx, postID, userID int64, ids []int64) (int64, error) {
+	panic("not used")
+}
+
+func (r *fakeRepository) ListByPosts(ctx context.Context, postIDs []int64) ([]Attachment, error) {
+	panic("not used")
+}
+
+func (r *fakeRepository) ListOrphans(ctx context.Context, before time.Time, limit int) ([]Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Attachment
+	for id := int64(1); id <= r.nextID && len(out) < limit; id++ {
+		if a, ok := r.rows[id]; ok && a.PostID == nil {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func newTestUsecase(t *testing.T, repo MediaRepository) (MediaUsecase, *blobtest.S3) {
+	t.Helper()
+
+	stub := blobtest.NewS3(t, "media")
+
+	storage, err := blob.NewS3Storage(stub.Config(), "https://cdn.example.com", stub.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewMediaUsecase(repo, storage), stub
+}
+
+func TestUpload(t *testing.T) {
+	repo := newFakeRepository()
+	uc, stub := newTestUsecase(t, repo)
+
+	a, err := uc.Upload(context.Background(), &users.User{ID: 7}, encodePNG(t, testImage(800, 400)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, contentType := range map[string]string{a.Key: "image/png", a.ThumbnailKey: "image/png"} {
+		o, ok := stub.Object(key)
+		if !ok {
+			t.Fatalf("%s was not stored", key)
+		}
+		if o.ContentType != contentType {
+			t.Errorf("%s stored as %s", key, o.ContentType)
+		}
+	}
+
+	if _, err := repo.GetByID(context.Background(), a.ID); err != nil {
+		t.Errorf("no row for the upload: %v", err)
+	}
+	if a.URL != "https://cdn.example.com/"+a.Key {
+		t.Errorf("URL = %q", a.URL)
+	}
+}
+
+func TestUploadRemovesFilesOnFailure(t *testing.T) {
+	repo := newFakeRepository()
+	repo.createErr = errors.New("insert failed")
+	uc, stub := newTestUsecase(t, repo)
+
+	if _, err := uc.Upload(context.Background(), &users.User{ID: 7}, encodePNG(t, testImage(800, 400))); err == nil {
+		t.Fatal("Upload succeeded without a row")
+	}
+	if n := stub.Len(); n != 0 {
+		t.Errorf("Upload left %d objects behind", n)
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	postID := int64(1)
+	repo := newFakeRepository(
+		Attachment{ID: 1, Key: "attachments/1/a.png", ThumbnailKey: "attachments/1/a_thumb.png"},
+		Attachment{ID: 2, Key: "attachments/1/b.png", ThumbnailKey: "attachments/1/b_thumb.png"},
+		Attachment{ID: 3, Key: "attachments/1/c.png", ThumbnailKey: "attachments/1/c_thumb.png", PostID: &postID},
+		Attachment{ID: 4, Key: "attachments/1/d.png", ThumbnailKey: "attachments/1/d_thumb.png"},
+	)
+	uc, stub := newTestUsecase(t, repo)
+
+	for _, a := range repo.rows {
+		stub.SetObject(a.Key, blobtest.Object{Data: []byte("x")})
+		stub.SetObject(a.ThumbnailKey, blobtest.Object{Data: []byte("x")})
+	}
+
+	// the thumbnail of 2 cannot be deleted, its row stays for the next run
+	stub.Fail("attachments/1/b_thumb.png", http.StatusInternalServerError)
+
+	removed, err := uc.CleanupOrphans(context.Background(), time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d, want 2", removed)
+	}
+
+	tests := []struct {
+		id      int64
+		key     string
+		removed bool
+	}{
+		{1, "attachments/1/a.png", true},
+		{2, "attachments/1/b_thumb.png", false},
+		{3, "attachments/1/c.png", false},
+		{4, "attachments/1/d_thumb.png", true},
+	}
+
+	for _, tt := range tests {
+		_, err := repo.GetByID(context.Background(), tt.id)
+		if gone := err == sql.ErrNoRows; gone != tt.removed {
+			t.Errorf("row %d removed = %v, want %v", tt.id, gone, tt.removed)
+		}
+
+		if tt.removed {
+			if _, ok := stub.Object(tt.key); ok {
+				t.Errorf("%s was not deleted", tt.key)
+			}
+		}
+	}
+
+	// the post's files are untouched
+	if _, ok := stub.Object("attachments/1/c_thumb.png"); !ok {
+		t.Error("cleanup deleted the files of an attached upload")
+	}
+}
+
+func TestCleanupOrphansLimit(t *testing.T) {
+	repo := newFakeRepository(
+		Attachment{ID: 1, Key: "a", ThumbnailKey: "a_thumb"},
+		Attachment{ID: 2, Key: "b", ThumbnailKey: "b_thumb"},
+		Attachment{ID: 3, Key: "c", ThumbnailKey: "c_thumb"},
+	)
+	uc, _ := newTestUsecase(t, repo)
+
+	removed, err := uc.CleanupOrphans(context.Background(), time.Now(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || len(repo.rows) != 1 {
+		t.Errorf("removed %d, %d rows left, want 2 and 1", removed, len(repo.rows))
+	}
+}
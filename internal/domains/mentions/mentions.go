@@ -0,0 +1,7 @@
+package mentions
+
+import "database/sql"
+
+func NewMentions(db *sql.DB) MentionUsecase {
+	return NewMentionUsecase(NewMentionRepository(db))
+}
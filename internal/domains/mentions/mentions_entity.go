@@ -0,0 +1,15 @@
+package mentions
+
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+)
+
+// Mention is an @username in post or comment content. Offset and Length
+// count characters, not bytes, and include the leading @.
+type Mention struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
@@ -0,0 +1,106 @@
+package mentions
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type MentionRepository interface {
+	ResolveUsernames(ctx context.Context, authorID int64, usernames []string) (map[string]int64, error)
+	Replace(ctx context.Context, tx *sql.Tx, targetType string, targetID, authorID int64, mentions []Mention) error
+	List(ctx context.Context, targetType string, targetIDs []int64) (map[int64][]Mention, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewMentionRepository(db *sql.DB) MentionRepository {
+	return &repository{db: db}
+}
+
+// ResolveUsernames maps lowercased usernames to active accounts. Users who
+// blocked the author, or were blocked by them, are left out.
+func (r *repository) ResolveUsernames(ctx context.Context, authorID int64, usernames []string) (map[string]int64, error) {
+	query := `
+		SELECT u.id, lower(u.username) FROM users u
+		WHERE
+			lower(u.username) = ANY($1) AND
+			u.is_active = true AND u.banned_at IS NULL AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = u.id AND b.blocked_id = $2)
+					OR (b.blocker_id = $2 AND b.blocked_id = u.id)
+			)
+	`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(usernames), authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var (
+			id       int64
+			username string
+		)
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		ids[username] = id
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *repository) Replace(ctx context.Context, tx *sql.Tx, targetType string, targetID, authorID int64, mentions []Mention) error {
+	query := `DELETE FROM mentions WHERE target_type = $1 AND target_id = $2`
+	if _, err := tx.ExecContext(ctx, query, targetType, targetID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO mentions (target_type, target_id, author_id, user_id, "offset", length)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	for _, m := range mentions {
+		_, err := tx.ExecContext(ctx, query, targetType, targetID, authorID, m.UserID, m.Offset, m.Length)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) List(ctx context.Context, targetType string, targetIDs []int64) (map[int64][]Mention, error) {
+	query := `
+		SELECT m.target_id, m.user_id, u.username, m."offset", m.length
+		FROM mentions m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.target_type = $1 AND m.target_id = ANY($2)
+		ORDER BY m.target_id, m."offset"
+	`
+	rows, err := r.db.QueryContext(ctx, query, targetType, pq.Array(targetIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := make(map[int64][]Mention)
+	for rows.Next() {
+		var (
+			targetID int64
+			m        Mention
+		)
+		if err := rows.Scan(&targetID, &m.UserID, &m.Username, &m.Offset, &m.Length); err != nil {
+			return nil, err
+		}
+		mentions[targetID] = append(mentions[targetID], m)
+	}
+
+	return mentions, rows.Err()
+}
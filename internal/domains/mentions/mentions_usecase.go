@@ -0,0 +1,117 @@
+package mentions
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+// maxMentions caps how many users a single post or comment can mention.
+const maxMentions = 20
+
+// a mention starts at the beginning of the text or after a character that
+// cannot be part of an email address or another handle.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9_]+(?:[.-][A-Za-z0-9_]+)*)`)
+
+type MentionUsecase interface {
+	// Save parses content, resolves the mentioned users and replaces the
+	// stored mentions of the target inside tx.
+	Save(ctx context.Context, tx *sql.Tx, targetType string, targetID, authorID int64, content string) ([]Mention, error)
+	List(ctx context.Context, targetType string, targetIDs ...int64) (map[int64][]Mention, error)
+}
+
+type usecase struct {
+	repo MentionRepository
+}
+
+func NewMentionUsecase(repo MentionRepository) MentionUsecase {
+	return &usecase{repo: repo}
+}
+
+func (uc *usecase) Save(ctx context.Context, tx *sql.Tx, targetType string, targetID, authorID int64, content string) ([]Mention, error) {
+	found := Parse(content)
+
+	mentions := []Mention{}
+	if len(found) > 0 {
+		usernames := make([]string, len(found))
+		for i, m := range found {
+			usernames[i] = strings.ToLower(m.Username)
+		}
+
+		ids, err := uc.repo.ResolveUsernames(ctx, authorID, usernames)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range found {
+			id, ok := ids[strings.ToLower(m.Username)]
+			if !ok || id == authorID {
+				continue
+			}
+			m.UserID = id
+			mentions = append(mentions, m)
+		}
+	}
+
+	if err := uc.repo.Replace(ctx, tx, targetType, targetID, authorID, mentions); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+func (uc *usecase) List(ctx context.Context, targetType string, targetIDs ...int64) (map[int64][]Mention, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx, targetType, targetIDs)
+}
+
+// Parse finds the @username handles in content, without resolving them.
+func Parse(content string) []Mention {
+	var mentions []Mention
+
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		if len(mentions) == maxMentions {
+			break
+		}
+
+		// loc[2]:loc[3] is the username, the @ sits right before it
+		start, end := loc[2]-1, loc[3]
+		mentions = append(mentions, Mention{
+			Username: content[loc[2]:loc[3]],
+			Offset:   utf8.RuneCountInString(content[:start]),
+			Length:   utf8.RuneCountInString(content[start:end]),
+		})
+	}
+
+	return mentions
+}
+
+// Notify tells every user in mentioned, who was not already in previous,
+// that authorID mentioned them.
+func Notify(notifier commons.Notifier, authorID int64, targetType string, targetID int64, mentioned, previous []Mention) {
+	seen := make(map[int64]bool, len(previous))
+	for _, m := range previous {
+		seen[m.UserID] = true
+	}
+
+	for _, m := range mentioned {
+		if seen[m.UserID] {
+			continue
+		}
+		seen[m.UserID] = true
+
+		notifier.Notify(commons.NotifyEvent{
+			UserID:     m.UserID,
+			ActorID:    authorID,
+			Type:       commons.NotifyMention,
+			TargetType: targetType,
+			TargetID:   targetID,
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package moderation
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/cache"
+)
+
+func InitModerationDomain(db *sql.DB, cfg config.Config, cache *cache.Storage) ModerationHandler {
+	repo := NewModerationRepository(db)
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	userrepo := users.NewUserRepository(db)
+
+	uc := NewModerationUsecase(db, repo, userrepo, auditUC, cache)
+	hdl := NewModerationHandler(uc)
+
+	return hdl
+}
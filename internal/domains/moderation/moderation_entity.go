@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TargetPost    = "post"
+	TargetComment = "comment"
+
+	StatusOpen      = "open"
+	StatusDismissed = "dismissed"
+	StatusActioned  = "actioned"
+
+	ActionDismiss = "dismiss"
+	ActionHide    = "hide"
+	ActionDelete  = "delete"
+	ActionWarn    = "warn"
+	ActionSuspend = "suspend"
+
+	defaultSuspendDays = 7
+)
+
+type Report struct {
+	ID             int64   `json:"id"`
+	ReporterID     int64   `json:"reporter_id"`
+	TargetType     string  `json:"target_type"`
+	TargetID       int64   `json:"target_id"`
+	TargetUserID   int64   `json:"target_user_id"`
+	Reason         string  `json:"reason"`
+	Details        string  `json:"details"`
+	Status         string  `json:"status"`
+	Resolution     *string `json:"resolution"`
+	ResolutionNote string  `json:"resolution_note"`
+	ResolvedBy     *int64  `json:"resolved_by"`
+	ResolvedAt     *string `json:"resolved_at"`
+	CreatedAt      string  `json:"created_at"`
+	ReportsCount   int     `json:"reports_count"`
+}
+
+type CreateReportPayload struct {
+	TargetType string `json:"target_type" binding:"required,oneof=post comment"`
+	TargetID   int64  `json:"target_id" binding:"required,gt=0"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment hate_speech violence sexual_content misinformation self_harm other"`
+	Details    string `json:"details" binding:"max=1000"`
+}
+
+type ResolveReportPayload struct {
+	Action      string `json:"action" binding:"required,oneof=dismiss hide delete warn suspend"`
+	Note        string `json:"note" binding:"max=1000"`
+	SuspendDays int    `json:"suspend_days" binding:"omitempty,gte=1,lte=365"`
+}
+
+type ReportQuery struct {
+	Status     string `json:"status" binding:"omitempty,oneof=open dismissed actioned"`
+	TargetType string `json:"target_type" binding:"omitempty,oneof=post comment"`
+	Limit      int    `json:"limit" binding:"gte=1,lte=100"`
+	Offset     int    `json:"offset" binding:"gte=0"`
+}
+
+func (q ReportQuery) Parse(c *gin.Context) (ReportQuery, error) {
+	if status := c.Query("status"); status != "" {
+		q.Status = status
+	}
+
+	if targetType := c.Query("target_type"); targetType != "" {
+		q.TargetType = targetType
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
@@ -0,0 +1,107 @@
+package moderation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type ModerationHandler interface {
+	CreateReportHandler(c *gin.Context)
+	ListReportsHandler(c *gin.Context)
+	GetReportHandler(c *gin.Context)
+	ResolveReportHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc ModerationUsecase
+}
+
+func NewModerationHandler(uc ModerationUsecase) ModerationHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) CreateReportHandler(c *gin.Context) {
+	var payload CreateReportPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	report, err := h.uc.Report(c, users.GetAuthUserFromContext(c), &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, report)
+}
+
+func (h *handler) ListReportsHandler(c *gin.Context) {
+	q := ReportQuery{
+		Status: StatusOpen,
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	reports, err := h.uc.List(c, q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, reports)
+}
+
+func (h *handler) GetReportHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	report, err := h.uc.GetByID(c, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, report)
+}
+
+func (h *handler) ResolveReportHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload ResolveReportPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	report, err := h.uc.Resolve(c, users.GetAuthUserFromContext(c), id, &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, report)
+}
@@ -0,0 +1,201 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type ModerationRepository interface {
+	GetTargetAuthor(ctx context.Context, targetType string, targetID int64) (int64, error)
+	Create(ctx context.Context, report *Report) error
+	GetByID(ctx context.Context, id int64) (*Report, error)
+	List(ctx context.Context, q ReportQuery) ([]Report, error)
+	Resolve(ctx context.Context, tx *sql.Tx, report *Report, status, resolution, note string, moderatorID int64) error
+	HideContent(ctx context.Context, tx *sql.Tx, targetType string, targetID int64) error
+	DeleteContent(ctx context.Context, tx *sql.Tx, targetType string, targetID, moderatorID int64) error
+	CreateWarning(ctx context.Context, tx *sql.Tx, userID, moderatorID, reportID int64, message string) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewModerationRepository(db *sql.DB) ModerationRepository {
+	return &repository{db: db}
+}
+
+// target types are validated by the payload, so the table name is never
+// user input
+func targetTable(targetType string) (string, error) {
+	switch targetType {
+	case TargetPost:
+		return "posts", nil
+	case TargetComment:
+		return "comments", nil
+	default:
+		return "", fmt.Errorf("unknown report target %q", targetType)
+	}
+}
+
+func (r *repository) GetTargetAuthor(ctx context.Context, targetType string, targetID int64) (int64, error) {
+	table, err := targetTable(targetType)
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int64
+	err = r.db.QueryRowContext(ctx, `SELECT user_id FROM `+table+` WHERE id = $1 AND deleted_at IS NULL`, targetID).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *repository) Create(ctx context.Context, report *Report) error {
+	query := `
+		INSERT INTO reports (reporter_id, target_type, target_id, target_user_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, status, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		report.ReporterID,
+		report.TargetType,
+		report.TargetID,
+		report.TargetUserID,
+		report.Reason,
+		report.Details,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const reportColumns = `
+	r.id, r.reporter_id, r.target_type, r.target_id, r.target_user_id, r.reason, r.details,
+	r.status, r.resolution, r.resolution_note, r.resolved_by, r.resolved_at, r.created_at,
+	COUNT(*) OVER (PARTITION BY r.target_type, r.target_id, r.status) AS reports_count
+`
+
+func scanReport(row interface{ Scan(...any) error }, report *Report) error {
+	return row.Scan(
+		&report.ID,
+		&report.ReporterID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.TargetUserID,
+		&report.Reason,
+		&report.Details,
+		&report.Status,
+		&report.Resolution,
+		&report.ResolutionNote,
+		&report.ResolvedBy,
+		&report.ResolvedAt,
+		&report.CreatedAt,
+		&report.ReportsCount,
+	)
+}
+
+func (r *repository) GetByID(ctx context.Context, id int64) (*Report, error) {
+	query := `
+		SELECT * FROM (SELECT ` + reportColumns + ` FROM reports r) r
+		WHERE r.id = $1
+	`
+	var report Report
+	if err := scanReport(r.db.QueryRowContext(ctx, query, id), &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (r *repository) List(ctx context.Context, q ReportQuery) ([]Report, error) {
+	query := `
+		SELECT ` + reportColumns + `
+		FROM reports r
+		WHERE
+			($1 = '' OR r.status = $1) AND
+			($2 = '' OR r.target_type = $2)
+		ORDER BY r.created_at ASC, r.id ASC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, q.Status, q.TargetType, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+		if err := scanReport(rows, &report); err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, rows.Err()
+}
+
+// Resolve closes every open report on the same target, so the queue does
+// not keep duplicates of an already handled item.
+func (r *repository) Resolve(ctx context.Context, tx *sql.Tx, report *Report, status, resolution, note string, moderatorID int64) error {
+	query := `
+		UPDATE reports SET
+			status = $1, resolution = $2, resolution_note = $3,
+			resolved_by = $4, resolved_at = NOW()
+		WHERE target_type = $5 AND target_id = $6 AND status = 'open'
+	`
+	res, err := tx.ExecContext(ctx, query, status, resolution, note, moderatorID, report.TargetType, report.TargetID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *repository) HideContent(ctx context.Context, tx *sql.Tx, targetType string, targetID int64) error {
+	table, err := targetTable(targetType)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE `+table+` SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`, targetID)
+	return err
+}
+
+// DeleteContent soft deletes the target, the purge job removes it later
+// along with the comments of a post. The author cannot restore it.
+func (r *repository) DeleteContent(ctx context.Context, tx *sql.Tx, targetType string, targetID, moderatorID int64) error {
+	table, err := targetTable(targetType)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE ` + table + ` SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL`
+	_, err = tx.ExecContext(ctx, query, targetID, moderatorID)
+	return err
+}
+
+func (r *repository) CreateWarning(ctx context.Context, tx *sql.Tx, userID, moderatorID, reportID int64, message string) error {
+	query := `
+		INSERT INTO user_warnings (user_id, moderator_id, report_id, message)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := tx.ExecContext(ctx, query, userID, moderatorID, reportID, message)
+	return err
+}
@@ -0,0 +1,238 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/store/cache"
+)
+
+type ModerationUsecase interface {
+	Report(ctx context.Context, reporter *users.User, payload *CreateReportPayload) (*Report, error)
+	List(ctx context.Context, q ReportQuery) ([]Report, error)
+	GetByID(ctx context.Context, id int64) (*Report, error)
+	Resolve(ctx context.Context, moderator *users.User, reportID int64, payload *ResolveReportPayload) (*Report, error)
+}
+
+type usecase struct {
+	db       *sql.DB
+	repo     ModerationRepository
+	userRepo users.UserRepository
+	audit    audit.AuditUsecase
+	cache    *cache.Storage
+}
+
+// NewModerationUsecase accepts a nil cache.
+func NewModerationUsecase(db *sql.DB, repo ModerationRepository, userRepo users.UserRepository, audit audit.AuditUsecase, cache *cache.Storage) ModerationUsecase {
+	return &usecase{
+		db:       db,
+		repo:     repo,
+		userRepo: userRepo,
+		audit:    audit,
+		cache:    cache,
+	}
+}
+
+func (uc *usecase) Report(ctx context.Context, reporter *users.User, payload *CreateReportPayload) (*Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	authorID, err := uc.repo.GetTargetAuthor(ctx, payload.TargetType, payload.TargetID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if authorID == reporter.ID {
+		return nil, commons.ErrForbidden
+	}
+
+	report := &Report{
+		ReporterID:   reporter.ID,
+		TargetType:   payload.TargetType,
+		TargetID:     payload.TargetID,
+		TargetUserID: authorID,
+		Reason:       payload.Reason,
+		Details:      payload.Details,
+	}
+
+	if err := uc.repo.Create(ctx, report); err != nil {
+		return nil, commons.DBError(err)
+	}
+
+	return report, nil
+}
+
+func (uc *usecase) List(ctx context.Context, q ReportQuery) ([]Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx, q)
+}
+
+func (uc *usecase) GetByID(ctx context.Context, id int64) (*Report, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	report, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (uc *usecase) Resolve(ctx context.Context, moderator *users.User, reportID int64, payload *ResolveReportPayload) (*Report, error) {
+	report, err := uc.GetByID(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Status != StatusOpen {
+		return nil, commons.ErrConflict
+	}
+
+	if payload.Action == ActionWarn || payload.Action == ActionSuspend {
+		author, err := uc.userRepo.GetAccount(ctx, report.TargetUserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if author.Role.Level >= moderator.Role.Level {
+			return nil, commons.ErrForbidden
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		event, err := uc.apply(ctx, tx, moderator, report, payload)
+		if err != nil {
+			return err
+		}
+
+		status := StatusActioned
+		if payload.Action == ActionDismiss {
+			status = StatusDismissed
+		}
+
+		if err := uc.repo.Resolve(ctx, tx, report, status, payload.Action, payload.Note, moderator.ID); err != nil {
+			return err
+		}
+
+		event.ActorID = audit.ID(moderator.ID)
+		if event.Diff == nil {
+			event.Diff = map[string]audit.Change{}
+		}
+		event.Diff["report_id"] = audit.Change{To: report.ID}
+		if payload.Note != "" {
+			event.Diff["note"] = audit.Change{To: payload.Note}
+		}
+
+		return uc.audit.Record(ctx, tx, event)
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			// resolved concurrently by another moderator
+			return nil, commons.ErrConflict
+		default:
+			return nil, err
+		}
+	}
+
+	// the middleware would let a suspended user in until the cached
+	// account expires
+	if payload.Action == ActionSuspend && uc.cache != nil {
+		if err := uc.cache.Users.Delete(ctx, report.TargetUserID); err != nil {
+			log.Println("failed to evict cached user:", report.TargetUserID, err)
+		}
+	}
+
+	return uc.GetByID(ctx, reportID)
+}
+
+func (uc *usecase) apply(ctx context.Context, tx *sql.Tx, moderator *users.User, report *Report, payload *ResolveReportPayload) (*audit.Event, error) {
+	contentEvent := func(action string) *audit.Event {
+		return &audit.Event{
+			Action:     action,
+			TargetType: report.TargetType,
+			TargetID:   audit.ID(report.TargetID),
+			Diff: map[string]audit.Change{
+				"user_id": {From: report.TargetUserID},
+			},
+		}
+	}
+
+	switch payload.Action {
+	case ActionDismiss:
+		return contentEvent(audit.ActionModerationDismiss), nil
+
+	case ActionHide:
+		if err := uc.repo.HideContent(ctx, tx, report.TargetType, report.TargetID); err != nil {
+			return nil, err
+		}
+		event := contentEvent(audit.ActionModerationHide)
+		event.Diff["hidden"] = audit.Change{From: false, To: true}
+		return event, nil
+
+	case ActionDelete:
+		if err := uc.repo.DeleteContent(ctx, tx, report.TargetType, report.TargetID, moderator.ID); err != nil {
+			return nil, err
+		}
+		return contentEvent(audit.ActionModerationDelete), nil
+
+	case ActionWarn:
+		message := payload.Note
+		if message == "" {
+			message = "your content was reported for " + report.Reason
+		}
+		if err := uc.repo.CreateWarning(ctx, tx, report.TargetUserID, moderator.ID, report.ID, message); err != nil {
+			return nil, err
+		}
+		return &audit.Event{
+			Action:     audit.ActionModerationWarn,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(report.TargetUserID),
+			Diff: map[string]audit.Change{
+				"warning": {To: message},
+			},
+		}, nil
+
+	case ActionSuspend:
+		days := payload.SuspendDays
+		if days == 0 {
+			days = defaultSuspendDays
+		}
+		until := time.Now().AddDate(0, 0, days)
+		if err := uc.userRepo.SetSuspended(ctx, tx, report.TargetUserID, &until); err != nil {
+			return nil, err
+		}
+		return &audit.Event{
+			Action:     audit.ActionModerationSuspend,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(report.TargetUserID),
+			Diff: map[string]audit.Change{
+				"suspended_until": {To: until.Format(time.RFC3339)},
+			},
+		}, nil
+	}
+
+	return nil, errors.New("unknown moderation action " + payload.Action)
+}
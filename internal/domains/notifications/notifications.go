@@ -0,0 +1,19 @@
+package notifications
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+func InitNotificationDomain(db *sql.DB) NotificationHandler {
+	repo := NewNotificationRepository(db)
+	uc := NewNotificationUsecase(db, repo)
+	hdl := NewNotificationHandler(uc)
+
+	return hdl
+}
+
+func NewNotifier(db *sql.DB, publisher commons.Publisher) *Dispatcher {
+	return NewDispatcher(NewNotificationRepository(db), publisher)
+}
@@ -0,0 +1,105 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+const (
+	queueSize   = 1024
+	workerCount = 4
+	saveTimeout = 5 * time.Second
+)
+
+// Dispatcher stores notifications in the background so write endpoints do
+// not wait on them, and pushes every stored one to the user's open streams.
+// Events are dropped, and logged, when the queue is full.
+type Dispatcher struct {
+	repo      NotificationRepository
+	publisher commons.Publisher
+	queue     chan commons.NotifyEvent
+	wg        sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewDispatcher(repo NotificationRepository, publisher commons.Publisher) *Dispatcher {
+	d := &Dispatcher{
+		repo:      repo,
+		publisher: publisher,
+		queue:     make(chan commons.NotifyEvent, queueSize),
+	}
+
+	for i := 0; i < workerCount; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+
+	return d
+}
+
+func (d *Dispatcher) Notify(e commons.NotifyEvent) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	select {
+	case d.queue <- e:
+	default:
+		log.Println("notification queue full, dropped:", e.Type, e.UserID)
+	}
+}
+
+// Close stops accepting events and waits until the queue is drained.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for e := range d.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+		d.save(ctx, e)
+		cancel()
+	}
+}
+
+func (d *Dispatcher) save(ctx context.Context, e commons.NotifyEvent) {
+	stored, err := d.repo.Upsert(ctx, e)
+	if err != nil {
+		log.Println("failed to save notification:", e.Type, e.UserID, err)
+		return
+	}
+
+	if !stored || d.publisher == nil {
+		return
+	}
+
+	err = d.publisher.Publish(ctx, []int64{e.UserID}, commons.EventNotification, PushedNotification{
+		Type:       e.Type,
+		ActorID:    e.ActorID,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+	})
+	if err != nil {
+		log.Println("failed to push notification:", e.Type, e.UserID, err)
+	}
+}
@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/gin-gonic/gin"
+)
+
+// Types every user can switch on or off, all are on by default.
+var Types = []string{
+	commons.NotifyFollow,
+	commons.NotifyComment,
+	commons.NotifyReply,
+	commons.NotifyMention,
+	commons.NotifyReaction,
+	commons.NotifyRepost,
+	commons.NotifyQuote,
+	commons.NotifyPollClosed,
+}
+
+type Notification struct {
+	ID          int64   `json:"id"`
+	UserID      int64   `json:"user_id"`
+	Type        string  `json:"type"`
+	TargetType  string  `json:"target_type"`
+	TargetID    int64   `json:"target_id"`
+	ActorIDs    []int64 `json:"actor_ids"`
+	ActorCount  int     `json:"actor_count"`
+	LatestActor string  `json:"latest_actor"`
+	Message     string  `json:"message"`
+	ReadAt      *string `json:"read_at"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+type UnreadCount struct {
+	Unread int `json:"unread"`
+}
+
+// PushedNotification is sent to realtime streams, clients refetch the
+// grouped notification from the list endpoint.
+type PushedNotification struct {
+	Type       string `json:"type"`
+	ActorID    int64  `json:"actor_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+}
+
+type PreferencesPayload struct {
+	Preferences map[string]bool `json:"preferences" binding:"required,dive,keys,oneof=follow comment reply mention reaction repost quote poll_closed,endkeys"`
+}
+
+type NotificationQuery struct {
+	Unread bool `json:"unread"`
+	Limit  int  `json:"limit" binding:"gte=1,lte=50"`
+	Offset int  `json:"offset" binding:"gte=0"`
+}
+
+func (q NotificationQuery) Parse(c *gin.Context) (NotificationQuery, error) {
+	if unread := c.Query("unread"); unread != "" {
+		u, err := strconv.ParseBool(unread)
+		if err != nil {
+			return q, err
+		}
+		q.Unread = u
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+// groupKey decides which unread notifications collapse into one row.
+func groupKey(e commons.NotifyEvent) string {
+	return fmt.Sprintf("%s:%s:%d", e.Type, e.TargetType, e.TargetID)
+}
+
+var verbs = map[string]string{
+	commons.NotifyFollow:     "followed you",
+	commons.NotifyComment:    "commented on your post",
+	commons.NotifyReply:      "replied to your comment",
+	commons.NotifyMention:    "mentioned you",
+	commons.NotifyReaction:   "reacted to your post",
+	commons.NotifyRepost:     "reposted your post",
+	commons.NotifyQuote:      "quoted your post",
+	commons.NotifyPollClosed: "closed a poll you voted in",
+}
+
+// buildMessage renders "alice and 4 others reacted to your post".
+func (n *Notification) buildMessage() {
+	actor := n.LatestActor
+	if actor == "" {
+		actor = "someone"
+	}
+
+	switch others := n.ActorCount - 1; {
+	case others == 1:
+		actor += " and 1 other"
+	case others > 1:
+		actor += fmt.Sprintf(" and %d others", others)
+	}
+
+	n.Message = actor + " " + verbs[n.Type]
+}
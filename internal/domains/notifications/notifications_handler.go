@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type NotificationHandler interface {
+	ListNotificationsHandler(c *gin.Context)
+	UnreadCountHandler(c *gin.Context)
+	MarkReadHandler(c *gin.Context)
+	MarkAllReadHandler(c *gin.Context)
+	GetPreferencesHandler(c *gin.Context)
+	UpdatePreferencesHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc NotificationUsecase
+}
+
+func NewNotificationHandler(uc NotificationUsecase) NotificationHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) ListNotificationsHandler(c *gin.Context) {
+	q := NotificationQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	notifications, err := h.uc.List(c, users.GetAuthUserFromContext(c).ID, q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, notifications)
+}
+
+func (h *handler) UnreadCountHandler(c *gin.Context) {
+	count, err := h.uc.UnreadCount(c, users.GetAuthUserFromContext(c).ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, UnreadCount{Unread: count})
+}
+
+func (h *handler) MarkReadHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.MarkRead(c, users.GetAuthUserFromContext(c).ID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) MarkAllReadHandler(c *gin.Context) {
+	if err := h.uc.MarkAllRead(c, users.GetAuthUserFromContext(c).ID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) GetPreferencesHandler(c *gin.Context) {
+	prefs, err := h.uc.GetPreferences(c, users.GetAuthUserFromContext(c).ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, prefs)
+}
+
+func (h *handler) UpdatePreferencesHandler(c *gin.Context) {
+	var payload PreferencesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	prefs, err := h.uc.SetPreferences(c, users.GetAuthUserFromContext(c).ID, payload.Preferences)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, prefs)
+}
@@ -0,0 +1,196 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/lib/pq"
+)
+
+// maxActors bounds how many actor ids a grouped notification keeps.
+const maxActors = 20
+
+type NotificationRepository interface {
+	Upsert(ctx context.Context, e commons.NotifyEvent) (bool, error)
+	List(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error)
+	UnreadCount(ctx context.Context, userID int64) (int, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+	GetPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+	SetPreferences(ctx context.Context, tx *sql.Tx, userID int64, prefs map[string]bool) error
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &repository{db: db}
+}
+
+// Upsert adds the actor to the unread notification of the same group, or
+// starts a new one. Nothing is stored for self actions, disabled types or
+// when either user blocked the other, which is reported as false.
+func (r *repository) Upsert(ctx context.Context, e commons.NotifyEvent) (bool, error) {
+	query := `
+		INSERT INTO notifications (user_id, type, target_type, target_id, group_key, actor_ids)
+		SELECT $1, $2, $3, $4, $5, ARRAY[$6::bigint]
+		WHERE
+			$1 <> $6 AND
+			NOT EXISTS (
+				SELECT 1 FROM notification_preferences
+				WHERE user_id = $1 AND type = $2 AND enabled = false
+			) AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = $1 AND b.blocked_id = $6)
+					OR (b.blocker_id = $6 AND b.blocked_id = $1)
+			)
+		ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE SET
+			actor_ids = (array_prepend($6::bigint, array_remove(notifications.actor_ids, $6::bigint)))[1:$7],
+			actor_count = notifications.actor_count +
+				CASE WHEN $6 = ANY(notifications.actor_ids) THEN 0 ELSE 1 END,
+			updated_at = NOW()
+		RETURNING id
+	`
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		e.UserID,
+		e.Type,
+		e.TargetType,
+		e.TargetID,
+		groupKey(e),
+		e.ActorID,
+		maxActors,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (r *repository) List(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error) {
+	query := `
+		SELECT
+			n.id, n.user_id, n.type, n.target_type, n.target_id, n.actor_ids, n.actor_count,
+			COALESCE(u.username, ''), n.read_at, n.created_at, n.updated_at
+		FROM notifications n
+		LEFT JOIN users u ON u.id = n.actor_ids[1]
+		WHERE n.user_id = $1 AND (n.read_at IS NULL OR NOT $2)
+		ORDER BY n.updated_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, q.Unread, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.Type,
+			&n.TargetType,
+			&n.TargetID,
+			pq.Array(&n.ActorIDs),
+			&n.ActorCount,
+			&n.LatestActor,
+			&n.ReadAt,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		n.buildMessage()
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (r *repository) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+func (r *repository) MarkRead(ctx context.Context, userID, id int64) error {
+	query := `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *repository) MarkAllRead(ctx context.Context, userID int64) error {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *repository) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	prefs := make(map[string]bool, len(Types))
+	for _, t := range Types {
+		prefs[t] = true
+	}
+
+	query := `SELECT type, enabled FROM notification_preferences WHERE user_id = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			t       string
+			enabled bool
+		)
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		prefs[t] = enabled
+	}
+
+	return prefs, rows.Err()
+}
+
+func (r *repository) SetPreferences(ctx context.Context, tx *sql.Tx, userID int64, prefs map[string]bool) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, type, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled
+	`
+	for t, enabled := range prefs {
+		if _, err := tx.ExecContext(ctx, query, userID, t, enabled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+type NotificationUsecase interface {
+	List(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error)
+	UnreadCount(ctx context.Context, userID int64) (int, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+	GetPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error)
+}
+
+type usecase struct {
+	db   *sql.DB
+	repo NotificationRepository
+}
+
+func NewNotificationUsecase(db *sql.DB, repo NotificationRepository) NotificationUsecase {
+	return &usecase{
+		db:   db,
+		repo: repo,
+	}
+}
+
+func (uc *usecase) List(ctx context.Context, userID int64, q NotificationQuery) ([]Notification, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.List(ctx, userID, q)
+}
+
+func (uc *usecase) UnreadCount(ctx context.Context, userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.UnreadCount(ctx, userID)
+}
+
+func (uc *usecase) MarkRead(ctx context.Context, userID, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.MarkRead(ctx, userID, id); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *usecase) MarkAllRead(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.MarkAllRead(ctx, userID)
+}
+
+func (uc *usecase) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.GetPreferences(ctx, userID)
+}
+
+func (uc *usecase) SetPreferences(ctx context.Context, userID int64, prefs map[string]bool) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.repo.SetPreferences(ctx, tx, userID, prefs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.repo.GetPreferences(ctx, userID)
+}
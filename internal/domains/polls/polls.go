@@ -0,0 +1,22 @@
+package polls
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+func InitPollDomain(db *sql.DB, notifier commons.Notifier) PollHandler {
+	uc := NewPolls(db, notifier)
+	hdl := NewPollHandler(uc)
+
+	return hdl
+}
+
+func NewPolls(db *sql.DB, notifier commons.Notifier) PollUsecase {
+	return NewPollUsecase(db, NewPollRepository(db), notifier)
+}
+
+func NewPollCloser(db *sql.DB, notifier commons.Notifier) *Closer {
+	return NewCloser(NewPolls(db, notifier))
+}
@@ -0,0 +1,69 @@
+package polls
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	closeInterval  = time.Minute
+	closeBatchSize = 100
+)
+
+// Closer periodically notifies the voters of polls that closed.
+type Closer struct {
+	uc PollUsecase
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewCloser(uc PollUsecase) *Closer {
+	return &Closer{uc: uc}
+}
+
+func (cl *Closer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cl.cancel = cancel
+
+	cl.wg.Add(1)
+	go func() {
+		defer cl.wg.Done()
+
+		ticker := time.NewTicker(closeInterval)
+		defer ticker.Stop()
+
+		for {
+			cl.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (cl *Closer) Close() {
+	if cl.cancel != nil {
+		cl.cancel()
+	}
+	cl.wg.Wait()
+}
+
+func (cl *Closer) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		closed, err := cl.uc.NotifyClosed(ctx, closeBatchSize)
+		if err != nil {
+			log.Println("failed to notify closed polls:", err)
+			return
+		}
+
+		if closed < closeBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package polls
+
+import "time"
+
+const (
+	MinOptions = 2
+	MaxOptions = 4
+
+	minDuration = 5 * time.Minute
+	maxDuration = 30 * 24 * time.Hour
+)
+
+// Poll results, Votes and Voters, are only set once the viewer voted or
+// the poll closed.
+type Poll struct {
+	ID       int64    `json:"id"`
+	PostID   int64    `json:"-"`
+	Multiple bool     `json:"multiple"`
+	ClosesAt string   `json:"closes_at"`
+	Closed   bool     `json:"closed"`
+	Options  []Option `json:"options"`
+	Voters   *int     `json:"voters,omitempty"`
+	Voted    bool     `json:"voted"`
+	MyVotes  []int64  `json:"my_votes,omitempty"`
+}
+
+type Option struct {
+	ID    int64  `json:"id"`
+	Label string `json:"label"`
+	Votes *int   `json:"votes,omitempty"`
+}
+
+// ClosedPoll is a poll whose voters are due a notification.
+type ClosedPoll struct {
+	ID       int64
+	PostID   int64
+	AuthorID int64
+}
+
+type CreatePollPayload struct {
+	Options  []string  `json:"options" binding:"required,min=2,max=4,dive,required,max=100"`
+	Multiple bool      `json:"multiple"`
+	ClosesAt time.Time `json:"closes_at" binding:"required"`
+}
+
+type VotePayload struct {
+	OptionIDs []int64 `json:"option_ids" binding:"required,min=1,max=4"`
+}
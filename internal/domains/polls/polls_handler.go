@@ -0,0 +1,46 @@
+package polls
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type PollHandler interface {
+	VoteHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc PollUsecase
+}
+
+func NewPollHandler(uc PollUsecase) PollHandler {
+	return &handler{uc: uc}
+}
+
+// VoteHandler runs behind the post context middleware, which already
+// checked the post exists and is visible.
+func (h *handler) VoteHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	var payload VotePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	poll, err := h.uc.Vote(c, users.GetAuthUserFromContext(c), postID, payload.OptionIDs)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, poll)
+}
@@ -0,0 +1,237 @@
+package polls
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PollRepository interface {
+	Create(ctx context.Context, tx *sql.Tx, poll *Poll, closesAt time.Time) error
+	ListByPosts(ctx context.Context, postIDs []int64) (map[int64]*Poll, error)
+	VotedOptions(ctx context.Context, userID int64, pollIDs []int64) (map[int64][]int64, error)
+	AddVoter(ctx context.Context, tx *sql.Tx, pollID, userID int64) (open, added bool, err error)
+	AddVotes(ctx context.Context, tx *sql.Tx, pollID, userID int64, optionIDs []int64) (int64, error)
+	ClaimClosed(ctx context.Context, now time.Time, limit int) ([]ClosedPoll, error)
+	ListVoters(ctx context.Context, pollID int64) ([]int64, error)
+	ClosesAt(ctx context.Context, postID int64) (time.Time, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewPollRepository(db *sql.DB) PollRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, tx *sql.Tx, poll *Poll, closesAt time.Time) error {
+	query := `
+		INSERT INTO polls (post_id, multiple, closes_at) VALUES ($1, $2, $3)
+		RETURNING id, closes_at
+	`
+	err := tx.QueryRowContext(ctx, query, poll.PostID, poll.Multiple, closesAt).Scan(&poll.ID, &poll.ClosesAt)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO poll_options (poll_id, position, label) VALUES ($1, $2, $3) RETURNING id`
+	for i := range poll.Options {
+		err := tx.QueryRowContext(ctx, query, poll.ID, i, poll.Options[i].Label).Scan(&poll.Options[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListByPosts returns the polls of the given posts, keyed by post id, with
+// every result filled in.
+func (r *repository) ListByPosts(ctx context.Context, postIDs []int64) (map[int64]*Poll, error) {
+	query := `
+		SELECT
+			p.id, p.post_id, p.multiple, p.closes_at, p.closes_at <= NOW(),
+			(SELECT COUNT(*) FROM poll_voters pv WHERE pv.poll_id = p.id),
+			o.id, o.label,
+			(SELECT COUNT(*) FROM poll_votes v WHERE v.option_id = o.id)
+		FROM polls p
+		JOIN poll_options o ON o.poll_id = p.id
+		WHERE p.post_id = ANY($1)
+		ORDER BY p.id, o.position
+	`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	polls := make(map[int64]*Poll)
+	for rows.Next() {
+		var (
+			p      Poll
+			o      Option
+			voters int
+			votes  int
+		)
+		err := rows.Scan(
+			&p.ID,
+			&p.PostID,
+			&p.Multiple,
+			&p.ClosesAt,
+			&p.Closed,
+			&voters,
+			&o.ID,
+			&o.Label,
+			&votes,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		poll, ok := polls[p.PostID]
+		if !ok {
+			p.Voters = &voters
+			poll = &p
+			polls[p.PostID] = poll
+		}
+
+		o.Votes = &votes
+		poll.Options = append(poll.Options, o)
+	}
+
+	return polls, rows.Err()
+}
+
+// VotedOptions returns, per poll, the options userID voted for.
+func (r *repository) VotedOptions(ctx context.Context, userID int64, pollIDs []int64) (map[int64][]int64, error) {
+	query := `
+		SELECT poll_id, option_id FROM poll_votes
+		WHERE user_id = $1 AND poll_id = ANY($2)
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, pq.Array(pollIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	voted := make(map[int64][]int64)
+	for rows.Next() {
+		var pollID, optionID int64
+		if err := rows.Scan(&pollID, &optionID); err != nil {
+			return nil, err
+		}
+		voted[pollID] = append(voted[pollID], optionID)
+	}
+
+	return voted, rows.Err()
+}
+
+// AddVoter reports whether the poll was still open and whether userID was
+// added, false when they already voted. Both come from the same statement,
+// so a poll closing in between is not mistaken for a second vote. A
+// concurrent vote by the same user waits on the primary key and then gets
+// false.
+func (r *repository) AddVoter(ctx context.Context, tx *sql.Tx, pollID, userID int64) (open, added bool, err error) {
+	query := `
+		WITH open_poll AS (
+			SELECT id FROM polls WHERE id = $1 AND closes_at > NOW()
+		), inserted AS (
+			INSERT INTO poll_voters (poll_id, user_id)
+			SELECT id, $2 FROM open_poll
+			ON CONFLICT DO NOTHING
+			RETURNING poll_id
+		)
+		SELECT EXISTS (SELECT 1 FROM open_poll), EXISTS (SELECT 1 FROM inserted)
+	`
+	err = tx.QueryRowContext(ctx, query, pollID, userID).Scan(&open, &added)
+
+	return open, added, err
+}
+
+// AddVotes returns how many of optionIDs belong to the poll and were
+// recorded.
+func (r *repository) AddVotes(ctx context.Context, tx *sql.Tx, pollID, userID int64, optionIDs []int64) (int64, error) {
+	query := `
+		INSERT INTO poll_votes (option_id, poll_id, user_id)
+		SELECT id, poll_id, $3 FROM poll_options
+		WHERE poll_id = $1 AND id = ANY($2)
+	`
+	res, err := tx.ExecContext(ctx, query, pollID, pq.Array(optionIDs), userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// ClaimClosed marks up to limit closed polls as notified and returns them.
+// Polls of deleted posts are claimed too but left out, nobody is told about
+// them. Rows locked by another instance are skipped.
+func (r *repository) ClaimClosed(ctx context.Context, now time.Time, limit int) ([]ClosedPoll, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE polls pl SET notified_at = NOW()
+			FROM posts p
+			WHERE p.id = pl.post_id AND pl.id IN (
+				SELECT id FROM polls
+				WHERE closes_at <= $1 AND notified_at IS NULL
+				ORDER BY closes_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING pl.id, pl.post_id, p.user_id, p.deleted_at
+		)
+		SELECT id, post_id, user_id FROM claimed WHERE deleted_at IS NULL
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var closed []ClosedPoll
+	for rows.Next() {
+		var cp ClosedPoll
+		if err := rows.Scan(&cp.ID, &cp.PostID, &cp.AuthorID); err != nil {
+			return nil, err
+		}
+		closed = append(closed, cp)
+	}
+
+	return closed, rows.Err()
+}
+
+func (r *repository) ListVoters(ctx context.Context, pollID int64) ([]int64, error) {
+	query := `SELECT user_id FROM poll_voters WHERE poll_id = $1`
+
+	rows, err := r.db.QueryContext(ctx, query, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var voters []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		voters = append(voters, id)
+	}
+
+	return voters, rows.Err()
+}
+
+// ClosesAt returns when the poll of postID closes, sql.ErrNoRows when the
+// post has none.
+func (r *repository) ClosesAt(ctx context.Context, postID int64) (time.Time, error) {
+	query := `SELECT closes_at FROM polls WHERE post_id = $1`
+
+	var closesAt time.Time
+	err := r.db.QueryRowContext(ctx, query, postID).Scan(&closesAt)
+
+	return closesAt, err
+}
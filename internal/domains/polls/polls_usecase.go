@@ -0,0 +1,244 @@
+package polls
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+type PollUsecase interface {
+	Create(ctx context.Context, tx *sql.Tx, postID int64, payload *CreatePollPayload, opensAt time.Time) (*Poll, error)
+	ListByPosts(ctx context.Context, viewer *users.User, postIDs ...int64) (map[int64]*Poll, error)
+	Vote(ctx context.Context, user *users.User, postID int64, optionIDs []int64) (*Poll, error)
+	NotifyClosed(ctx context.Context, limit int) (int, error)
+	CheckOpensAt(ctx context.Context, postID int64, opensAt time.Time) error
+}
+
+type usecase struct {
+	db       *sql.DB
+	repo     PollRepository
+	notifier commons.Notifier
+}
+
+func NewPollUsecase(db *sql.DB, repo PollRepository, notifier commons.Notifier) PollUsecase {
+	return &usecase{
+		db:       db,
+		repo:     repo,
+		notifier: notifier,
+	}
+}
+
+// Create runs inside the post's transaction. opensAt is when the post goes
+// out, the poll must stay open for a while after that.
+func (uc *usecase) Create(ctx context.Context, tx *sql.Tx, postID int64, payload *CreatePollPayload, opensAt time.Time) (*Poll, error) {
+	if payload.ClosesAt.Before(opensAt.Add(minDuration)) || payload.ClosesAt.After(opensAt.Add(maxDuration)) {
+		return nil, fmt.Errorf("%w: a poll runs between %s and %s", commons.ErrInvalidPoll, minDuration, maxDuration)
+	}
+
+	poll := &Poll{
+		PostID:   postID,
+		Multiple: payload.Multiple,
+	}
+
+	seen := make(map[string]bool, len(payload.Options))
+	for _, label := range payload.Options {
+		label = strings.TrimSpace(label)
+		key := strings.ToLower(label)
+
+		if label == "" || seen[key] {
+			return nil, fmt.Errorf("%w: options must be unique and not empty", commons.ErrInvalidPoll)
+		}
+		seen[key] = true
+
+		poll.Options = append(poll.Options, Option{Label: label})
+	}
+
+	if len(poll.Options) < MinOptions || len(poll.Options) > MaxOptions {
+		return nil, fmt.Errorf("%w: a poll has %d to %d options", commons.ErrInvalidPoll, MinOptions, MaxOptions)
+	}
+
+	if err := uc.repo.Create(ctx, tx, poll, payload.ClosesAt); err != nil {
+		return nil, err
+	}
+
+	return poll, nil
+}
+
+// CheckOpensAt makes sure the poll of postID, if it has one, still runs
+// for minDuration when the post is published at opensAt instead.
+func (uc *usecase) CheckOpensAt(ctx context.Context, postID int64, opensAt time.Time) error {
+	closesAt, err := uc.repo.ClosesAt(ctx, postID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil
+		default:
+			return err
+		}
+	}
+
+	if closesAt.Before(opensAt.Add(minDuration)) {
+		return fmt.Errorf("%w: the poll would close less than %s after the post is published", commons.ErrInvalidPoll, minDuration)
+	}
+
+	return nil
+}
+
+// ListByPosts returns the polls of postIDs as viewer sees them.
+func (uc *usecase) ListByPosts(ctx context.Context, viewer *users.User, postIDs ...int64) (map[int64]*Poll, error) {
+	if len(postIDs) == 0 {
+		return map[int64]*Poll{}, nil
+	}
+
+	polls, err := uc.repo.ListByPosts(ctx, postIDs)
+	if err != nil || len(polls) == 0 {
+		return polls, err
+	}
+
+	pollIDs := make([]int64, 0, len(polls))
+	for _, p := range polls {
+		pollIDs = append(pollIDs, p.ID)
+	}
+
+	voted, err := uc.repo.VotedOptions(ctx, viewer.ID, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range polls {
+		present(p, voted[p.ID])
+	}
+
+	return polls, nil
+}
+
+func (uc *usecase) Vote(ctx context.Context, user *users.User, postID int64, optionIDs []int64) (*Poll, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	polls, err := uc.repo.ListByPosts(ctx, []int64{postID})
+	if err != nil {
+		return nil, err
+	}
+
+	poll, ok := polls[postID]
+	if !ok {
+		return nil, commons.ErrNotFound
+	}
+
+	if poll.Closed {
+		return nil, commons.ErrPollClosed
+	}
+
+	optionIDs = unique(optionIDs)
+	if len(optionIDs) == 0 || (!poll.Multiple && len(optionIDs) > 1) {
+		return nil, fmt.Errorf("%w: pick one option", commons.ErrInvalidVote)
+	}
+
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		open, added, err := uc.repo.AddVoter(ctx, tx, poll.ID, user.ID)
+		if err != nil {
+			return err
+		}
+
+		// the poll may have closed since it was read above
+		if !open {
+			return commons.ErrPollClosed
+		}
+
+		if !added {
+			return commons.ErrAlreadyVoted
+		}
+
+		n, err := uc.repo.AddVotes(ctx, tx, poll.ID, user.ID, optionIDs)
+		if err != nil {
+			return err
+		}
+
+		if n != int64(len(optionIDs)) {
+			return fmt.Errorf("%w: unknown option", commons.ErrInvalidVote)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	polls, err = uc.ListByPosts(ctx, user, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	return polls[postID], nil
+}
+
+// NotifyClosed tells the voters of up to limit newly closed polls. A poll
+// is claimed before its voters are notified, so they are notified at most
+// once.
+func (uc *usecase) NotifyClosed(ctx context.Context, limit int) (int, error) {
+	closed, err := uc.repo.ClaimClosed(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, cp := range closed {
+		voters, err := uc.repo.ListVoters(ctx, cp.ID)
+		if err != nil {
+			log.Println("failed to load poll voters:", cp.ID, err)
+			continue
+		}
+
+		for _, voter := range voters {
+			if voter == cp.AuthorID {
+				continue
+			}
+
+			uc.notifier.Notify(commons.NotifyEvent{
+				UserID:     voter,
+				ActorID:    cp.AuthorID,
+				Type:       commons.NotifyPollClosed,
+				TargetType: "post",
+				TargetID:   cp.PostID,
+			})
+		}
+	}
+
+	return len(closed), nil
+}
+
+// present hides the results from viewers who have not voted while the
+// poll is open.
+func present(p *Poll, votes []int64) {
+	p.MyVotes = votes
+	p.Voted = len(votes) > 0
+
+	if p.Voted || p.Closed {
+		return
+	}
+
+	p.Voters = nil
+	for i := range p.Options {
+		p.Options[i].Votes = nil
+	}
+}
+
+func unique(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+
+	var out []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,131 @@
+package polls
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+// nopDriver only opens and commits transactions, the fake repository
+// ignores tx.
+type nopDriver struct{}
+
+func (nopDriver) Open(string) (driver.Conn, error) { return nopConn{}, nil }
+
+type nopConn struct{}
+
+func (nopConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (nopConn) Close() error                        { return nil }
+func (nopConn) Begin() (driver.Tx, error)           { return nopConn{}, nil }
+func (nopConn) Commit() error                       { return nil }
+func (nopConn) Rollback() error                     { return nil }
+
+func init() {
+	sql.Register("polls-nop", nopDriver{})
+}
+
+// fakeRepository holds the poll of post 1. The embedded interface panics
+// on anything the tests do not use.
+type fakeRepository struct {
+	PollRepository
+
+	closesAt time.Time
+	// what AddVoter sees, the poll may close after ListByPosts read it
+	open, added bool
+}
+
+func (r *fakeRepository) ListByPosts(ctx context.Context, postIDs []int64) (map[int64]*Poll, error) {
+	return map[int64]*Poll{1: {
+		ID:      10,
+		PostID:  1,
+		Closed:  !r.closesAt.After(time.Now()),
+		Options: []Option{{ID: 100}, {ID: 101}},
+	}}, nil
+}
+
+func (r *fakeRepository) VotedOptions(ctx context.Context, userID int64, pollIDs []int64) (map[int64][]int64, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) AddVoter(ctx context.Context, tx *sql.Tx, pollID, userID int64) (bool, bool, error) {
+	return r.open, r.added, nil
+}
+
+func (r *fakeRepository) AddVotes(ctx context.Context, tx *sql.Tx, pollID, userID int64, optionIDs []int64) (int64, error) {
+	return int64(len(optionIDs)), nil
+}
+
+func (r *fakeRepository) ClosesAt(ctx context.Context, postID int64) (time.Time, error) {
+	if postID != 1 {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return r.closesAt, nil
+}
+
+func newTestUsecase(t *testing.T, repo PollRepository) PollUsecase {
+	t.Helper()
+
+	db, err := sql.Open("polls-nop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewPollUsecase(db, repo, nil)
+}
+
+func TestVote(t *testing.T) {
+	tests := []struct {
+		name        string
+		open, added bool
+		want        error
+	}{
+		{"first vote", true, true, nil},
+		{"second vote", true, false, commons.ErrAlreadyVoted},
+		{"closed after it was read", false, false, commons.ErrPollClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{closesAt: time.Now().Add(time.Hour), open: tt.open, added: tt.added}
+			uc := newTestUsecase(t, repo)
+
+			_, err := uc.Vote(context.Background(), &users.User{ID: 7}, 1, []int64{100})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Vote error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckOpensAt(t *testing.T) {
+	now := time.Now()
+	uc := newTestUsecase(t, &fakeRepository{closesAt: now.Add(time.Hour)})
+
+	tests := []struct {
+		name    string
+		postID  int64
+		opensAt time.Time
+		want    error
+	}{
+		{"well before closing", 1, now.Add(10 * time.Minute), nil},
+		{"exactly the minimum", 1, now.Add(time.Hour - minDuration), nil},
+		{"too close to closing", 1, now.Add(time.Hour - minDuration + time.Second), commons.ErrInvalidPoll},
+		{"after closing", 1, now.Add(2 * time.Hour), commons.ErrInvalidPoll},
+		{"post without a poll", 2, now.Add(48 * time.Hour), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := uc.CheckOpensAt(context.Background(), tt.postID, tt.opensAt); !errors.Is(err, tt.want) {
+				t.Errorf("CheckOpensAt error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,31 @@ package posts
 
 import (
 	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/filters"
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
+	"github.com/codepnw/gopher-social/internal/store/blob"
 )
 
-func InitPostDomain(db *sql.DB) PostHandler {
+func InitPostDomain(db *sql.DB, cfg config.Config, filter filters.Filter, storage blob.Storage, links *previews.Queue, renderer *Renderer, notifier commons.Notifier, publisher commons.Publisher) PostHandler {
 	postrepo := NewPostRepository(db)
-	postusecase := NewPostUsecase(postrepo)
+	auditusecase := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	postusecase := NewPostUsecase(db, postrepo, auditusecase, filter, mentions.NewMentions(db), media.NewMedia(db, storage), previews.NewPreviews(db, links), polls.NewPolls(db, notifier), notifier, publisher, renderer, cfg)
 	posthandler := NewPostHandler(postusecase)
 
 	return posthandler
 }
+
+func NewPostScheduler(db *sql.DB, cfg config.Config, notifier commons.Notifier, publisher commons.Publisher) *Scheduler {
+	return NewScheduler(NewPostRepository(db), mentions.NewMentions(db), notifier, publisher, cfg.Posts.ScheduleInterval)
+}
+
+func NewPostPurger(db *sql.DB, cfg config.Config) *Purger {
+	return NewPurger(NewPostRepository(db), cfg.Posts.RestoreWindow)
+}
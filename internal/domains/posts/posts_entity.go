@@ -6,21 +6,86 @@ import (
 	"time"
 
 	"github.com/codepnw/gopher-social/internal/domains/comments"
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
 	"github.com/codepnw/gopher-social/internal/domains/users"
 	"github.com/gin-gonic/gin"
 )
 
 type Post struct {
-	ID        int64              `json:"id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	UserID    int64              `json:"user_id"`
-	Tags      []string           `json:"tags"`
-	CreatedAt string             `json:"created_at"`
-	UpdatedAt string             `json:"updated_at"`
-	Version   int                `json:"version"`
-	Comments  []comments.Comment `json:"comments"`
-	User      users.User         `json:"user"`
+	ID          int64              `json:"id"`
+	Title       string             `json:"title"`
+	Content     string             `json:"content"`
+	Format      string             `json:"format"`
+	ContentHTML string             `json:"content_html"`
+	UserID      int64              `json:"user_id"`
+	Tags        []string           `json:"tags"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+	Version     int                `json:"version"`
+	HiddenAt    *string            `json:"hidden_at,omitempty"`
+	PublishAt   *string            `json:"publish_at,omitempty"`
+	Mentions    []mentions.Mention `json:"mentions"`
+	Reactions   map[string]int     `json:"reactions,omitempty"`
+	Attachments []media.Attachment `json:"attachments"`
+	Preview     *previews.Preview  `json:"preview,omitempty"`
+	Poll        *polls.Poll        `json:"poll,omitempty"`
+	Comments    []comments.Comment `json:"comments"`
+	User        users.User         `json:"user"`
+
+	IsQuote      bool   `json:"-"`
+	QuotedPostID *int64 `json:"quoted_post_id,omitempty"`
+	Quote        *Quote `json:"quote,omitempty"`
+	RepostsCount int    `json:"reposts_count"`
+	QuotesCount  int    `json:"quotes_count"`
+}
+
+// Quote is the original a quote post refers to. Once the original is
+// deleted, hidden or scheduled only Unavailable is set.
+type Quote struct {
+	ID          int64  `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Content     string `json:"content,omitempty"`
+	UserID      int64  `json:"user_id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+// Reposter attributes a feed item to the user who reposted it.
+type Reposter struct {
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	RepostedAt string `json:"reposted_at"`
+}
+
+// NewPostEvent is pushed to the realtime streams of the post's feed
+// audience.
+type NewPostEvent struct {
+	ID        int64    `json:"id"`
+	Title     string   `json:"title"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Tags      []string `json:"tags"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// VisibleTo hides moderated posts from everyone except the author and
+// moderators, and scheduled posts from everyone except the author.
+func (p *Post) VisibleTo(viewer *users.User) bool {
+	if p.PublishAt != nil {
+		return viewer.ID == p.UserID
+	}
+
+	return p.HiddenAt == nil || viewer.ID == p.UserID || viewer.IsModerator()
+}
+
+// Public reports whether the post is out for everyone, which is when
+// mentions and followers are notified.
+func (p *Post) Public() bool {
+	return p.HiddenAt == nil && p.PublishAt == nil
 }
 
 type PostWithMetaData struct {
@@ -29,14 +94,44 @@ type PostWithMetaData struct {
 }
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" binding:"required,max=100"`
-	Content string   `json:"content" binding:"required,max=300"`
-	Tags    []string `json:"tags"`
+	Title         string   `json:"title" binding:"required"`
+	Content       string   `json:"content" binding:"required"`
+	Format        string   `json:"format" binding:"omitempty,oneof=plain markdown"`
+	Tags          []string `json:"tags"`
+	AttachmentIDs []int64  `json:"attachment_ids" binding:"max=4"`
+	// QuotePostID makes the post a quote of another post
+	QuotePostID *int64 `json:"quote_post_id" binding:"omitempty,gte=1"`
+	// PublishAt schedules the post, nil publishes it now
+	PublishAt *time.Time               `json:"publish_at"`
+	Poll      *polls.CreatePollPayload `json:"poll"`
 }
 
 type UpdatePostPayload struct {
-	Title   *string `json:"title" binding:"omitempty,max=100"`
-	Content *string `json:"content" binding:"omitempty,max=300"`
+	Title   *string   `json:"title"`
+	Content *string   `json:"content"`
+	Format  *string   `json:"format" binding:"omitempty,oneof=plain markdown"`
+	Tags    *[]string `json:"tags"`
+	// Version comes from the If-Match header, nil updates whatever is stored
+	Version *int `json:"-"`
+}
+
+type SchedulePayload struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
+}
+
+type ReactionPayload struct {
+	Kind string `json:"kind" binding:"required,oneof=like love laugh sad angry"`
+}
+
+type Revision struct {
+	ID        int64    `json:"id"`
+	PostID    int64    `json:"post_id"`
+	Version   int      `json:"version"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	EditedBy  *int64   `json:"edited_by"`
+	CreatedAt string   `json:"created_at"`
 }
 
 type PaginatedFeedQuery struct {
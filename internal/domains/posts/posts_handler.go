@@ -4,8 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
 	"github.com/codepnw/gopher-social/internal/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +17,15 @@ type PostHandler interface {
 	GetPostHandler(c *gin.Context)
 	UpdatePostHandler(c *gin.Context)
 	DeletePostHandler(c *gin.Context)
+	RestorePostHandler(c *gin.Context)
+	ListRevisionsHandler(c *gin.Context)
+	ReactHandler(c *gin.Context)
+	UnreactHandler(c *gin.Context)
+	RepostHandler(c *gin.Context)
+	UnrepostHandler(c *gin.Context)
+	ListScheduledHandler(c *gin.Context)
+	RescheduleHandler(c *gin.Context)
+	CancelScheduledHandler(c *gin.Context)
 	PostContextMiddleware() gin.HandlerFunc
 }
 
@@ -33,20 +44,20 @@ func (h *handler) CreatePostHandler(c *gin.Context) {
 		return
 	}
 
-	post, err := h.uc.Create(c, &payload)
+	post, err := h.uc.Create(c, users.GetAuthUserFromContext(c), &payload)
 	if err != nil {
-		response.InternalServerError(c, err)
+		response.Error(c, err)
 		return
 	}
 
+	c.Header("ETag", postETag(post))
 	response.ResponseData(c, http.StatusOK, post)
 }
 
 func (h *handler) GetPostHandler(c *gin.Context) {
 	post := h.getPostContext(c)
 
-	// TODO: get comments later
-
+	c.Header("ETag", postETag(post))
 	response.ResponseData(c, http.StatusOK, post)
 }
 
@@ -59,20 +70,150 @@ func (h *handler) UpdatePostHandler(c *gin.Context) {
 		return
 	}
 
-	p, err := h.uc.Update(c, post.ID, &payload)
+	version, err := parseIfMatch(c.GetHeader("If-Match"))
 	if err != nil {
-		response.InternalServerError(c, err)
+		response.BadRequestResponse(c, err)
+		return
+	}
+	payload.Version = version
+
+	p, err := h.uc.Update(c, users.GetAuthUserFromContext(c), post, &payload)
+	if err != nil {
+		if errors.Is(err, commons.ErrEditConflict) {
+			c.Header("ETag", postETag(post))
+		}
+		response.Error(c, err)
 		return
 	}
 
+	c.Header("ETag", postETag(p))
 	response.ResponseData(c, http.StatusOK, p)
 }
 
+func (h *handler) ListRevisionsHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	revisions, err := h.uc.GetRevisions(c, post.ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, revisions)
+}
+
 func (h *handler) DeletePostHandler(c *gin.Context) {
-	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	post := h.getPostContext(c)
+
+	if err := h.uc.Delete(c, users.GetAuthUserFromContext(c), post); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+// RestorePostHandler runs without PostContextMiddleware, a deleted post is
+// not found there.
+func (h *handler) RestorePostHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	post, err := h.uc.Restore(c, users.GetAuthUserFromContext(c), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, post)
+}
+
+func (h *handler) ReactHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	var payload ReactionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.React(c, users.GetAuthUserFromContext(c), post, payload.Kind); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UnreactHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	if err := h.uc.Unreact(c, users.GetAuthUserFromContext(c), post); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) RepostHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	if err := h.uc.Repost(c, users.GetAuthUserFromContext(c), post); err != nil {
+		response.Error(c, err)
+		return
+	}
 
-	if err := h.uc.Delete(c, id); err != nil {
-		response.InternalServerError(c, err)
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UnrepostHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	if err := h.uc.Unrepost(c, users.GetAuthUserFromContext(c), post); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) ListScheduledHandler(c *gin.Context) {
+	scheduled, err := h.uc.ListScheduled(c, users.GetAuthUserFromContext(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, scheduled)
+}
+
+func (h *handler) RescheduleHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	var payload SchedulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	p, err := h.uc.Reschedule(c, users.GetAuthUserFromContext(c), post, payload.PublishAt)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, p)
+}
+
+func (h *handler) CancelScheduledHandler(c *gin.Context) {
+	post := h.getPostContext(c)
+
+	if err := h.uc.CancelScheduled(c, users.GetAuthUserFromContext(c), post); err != nil {
+		response.Error(c, err)
 		return
 	}
 
@@ -83,18 +224,18 @@ func (h *handler) PostContextMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
-			response.InternalServerError(c, err)
+			response.Abort(c, err)
 			return
 		}
 
-		post, err := h.uc.GetByID(c, id)
+		post, err := h.uc.GetByID(c, users.GetAuthUserFromContext(c), id)
 		if err != nil {
-			switch {
-			case errors.Is(err, commons.ErrNotFound):
-				response.NotFoundResponse(c, err)
-			default:
-				response.InternalServerError(c, err)
-			}
+			response.Abort(c, err)
+			return
+		}
+
+		if !post.VisibleTo(users.GetAuthUserFromContext(c)) {
+			response.Abort(c, commons.ErrNotFound)
 			return
 		}
 
@@ -107,3 +248,26 @@ func (h *handler) getPostContext(c *gin.Context) *Post {
 	post, _ := c.Get(commons.ContextPostKey)
 	return post.(*Post)
 }
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+func postETag(post *Post) string {
+	return `"` + strconv.Itoa(post.Version) + `"`
+}
+
+// parseIfMatch reads the post version from an If-Match header. An empty
+// header or "*" matches any version.
+func parseIfMatch(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+
+	return &version, nil
+}
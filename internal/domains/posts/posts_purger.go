@@ -0,0 +1,87 @@
+package posts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+const (
+	purgeInterval  = time.Hour
+	purgeBatchSize = 100
+)
+
+// Purger hard-deletes posts and comments once their restore window has
+// passed.
+type Purger struct {
+	repo   PostRepository
+	window time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPurger(repo PostRepository, window time.Duration) *Purger {
+	return &Purger{
+		repo:   repo,
+		window: window,
+	}
+}
+
+func (p *Purger) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			p.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *Purger) Close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+}
+
+func (p *Purger) run(ctx context.Context) {
+	before := time.Now().Add(-p.window)
+
+	for ctx.Err() == nil {
+		purged, err := p.purge(ctx, before)
+		if err != nil {
+			log.Println("failed to purge deleted posts:", err)
+			return
+		}
+
+		// each batch covers posts and comments, only a full one of either
+		// may leave more behind
+		if purged < purgeBatchSize {
+			return
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return p.repo.Purge(ctx, before, purgeBatchSize)
+}
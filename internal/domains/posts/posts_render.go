@@ -0,0 +1,105 @@
+package posts
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/codepnw/gopher-social/internal/utils/markdown"
+)
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// Renderer fills Post.ContentHTML, keeping the most recently rendered posts
+// in an LRU cache. Entries are keyed by post version, every update bumps
+// the version so a stale render is never served.
+type Renderer struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[int64]*list.Element
+}
+
+type rendered struct {
+	postID  int64
+	version int
+	html    string
+}
+
+func NewRenderer(size int) *Renderer {
+	return &Renderer{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+}
+
+func (r *Renderer) Render(p *Post) {
+	if html, ok := r.get(p.ID, p.Version); ok {
+		p.ContentHTML = html
+		return
+	}
+
+	switch p.Format {
+	case FormatMarkdown:
+		p.ContentHTML = markdown.Render(p.Content)
+	default:
+		p.ContentHTML = markdown.Plain(p.Content)
+	}
+
+	r.put(p.ID, p.Version, p.ContentHTML)
+}
+
+// Invalidate drops the cached render of postID.
+func (r *Renderer) Invalidate(postID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.entries[postID]; ok {
+		r.order.Remove(el)
+		delete(r.entries, postID)
+	}
+}
+
+func (r *Renderer) get(postID int64, version int) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[postID]
+	if !ok || el.Value.(*rendered).version != version {
+		return "", false
+	}
+
+	r.order.MoveToFront(el)
+	return el.Value.(*rendered).html, true
+}
+
+func (r *Renderer) put(postID int64, version int, html string) {
+	if r.size <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if el, ok := r.entries[postID]; ok {
+		// a slow reader of an older version must not replace a newer render
+		if el.Value.(*rendered).version > version {
+			return
+		}
+		el.Value = &rendered{postID: postID, version: version, html: html}
+		r.order.MoveToFront(el)
+		return
+	}
+
+	r.entries[postID] = r.order.PushFront(&rendered{postID: postID, version: version, html: html})
+
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*rendered).postID)
+	}
+}
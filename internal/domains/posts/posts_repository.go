@@ -3,16 +3,31 @@ package posts
 import (
 	"context"
 	"database/sql"
+	"time"
 
-	"github.com/codepnw/gopher-social/internal/domains/feed"
 	"github.com/lib/pq"
 )
 
 type PostRepository interface {
-	Create(ctx context.Context, post *Post) error
+	Create(ctx context.Context, tx *sql.Tx, post *Post, hidden bool) error
 	GetByID(ctx context.Context, id int64) (*Post, error)
-	Delete(ctx context.Context, postID int64) error
-	Update(ctx context.Context, post *Post) error
+	Delete(ctx context.Context, tx *sql.Tx, postID, actorID int64) error
+	Restore(ctx context.Context, postID, userID int64, deletedAfter time.Time) error
+	Purge(ctx context.Context, before time.Time, limit int) (int64, error)
+	Update(ctx context.Context, tx *sql.Tx, post *Post, hide bool) error
+	CreateRevision(ctx context.Context, tx *sql.Tx, rev *Revision) error
+	ListRevisions(ctx context.Context, postID int64) ([]Revision, error)
+	React(ctx context.Context, postID, userID int64, kind string) error
+	Unreact(ctx context.Context, postID, userID int64) error
+	ReactionCounts(ctx context.Context, postID int64) (map[string]int, error)
+	FeedAudience(ctx context.Context, post *Post) ([]int64, error)
+	ListScheduled(ctx context.Context, userID int64) ([]Post, error)
+	Reschedule(ctx context.Context, postID int64, publishAt time.Time) (*string, error)
+	DeleteScheduled(ctx context.Context, postID int64) error
+	PublishDue(ctx context.Context, now time.Time, limit int) ([]Post, error)
+	Repost(ctx context.Context, postID, userID int64) (bool, error)
+	Unrepost(ctx context.Context, postID, userID int64) error
+	ListQuoted(ctx context.Context, ids []int64) (map[int64]*Quote, error)
 }
 
 type postRepository struct {
@@ -23,19 +38,25 @@ func NewPostRepository(db *sql.DB) PostRepository {
 	return &postRepository{db: db}
 }
 
-func (r *postRepository) Create(ctx context.Context, post *Post) error {
+func (r *postRepository) Create(ctx context.Context, tx *sql.Tx, post *Post, hidden bool) error {
 	query := `
-		INSERT INTO posts (title, content, user_id, tags)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+		INSERT INTO posts (title, content, user_id, tags, hidden_at, format, publish_at, is_quote, quoted_post_id)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at, version, hidden_at, publish_at
 	`
-	err := r.db.QueryRowContext(
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		post.Title,
 		post.Content,
 		post.UserID,
 		pq.Array(post.Tags),
-	).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+		hidden,
+		post.Format,
+		post.PublishAt,
+		post.IsQuote,
+		post.QuotedPostID,
+	).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt, &post.Version, &post.HiddenAt, &post.PublishAt)
 
 	if err != nil {
 		return err
@@ -46,8 +67,12 @@ func (r *postRepository) Create(ctx context.Context, post *Post) error {
 
 func (r *postRepository) GetByID(ctx context.Context, id int64) (*Post, error) {
 	query := `
-		SELECT id, title, content, user_id, tags, created_at, updated_at, version
-		FROM posts WHERE id = $1
+		SELECT
+			id, title, content, format, user_id, tags, created_at, updated_at, version, hidden_at, publish_at,
+			is_quote, quoted_post_id,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id),
+			(SELECT COUNT(*) FROM posts q WHERE q.quoted_post_id = p.id AND q.hidden_at IS NULL AND q.publish_at IS NULL AND q.deleted_at IS NULL)
+		FROM posts p WHERE id = $1 AND deleted_at IS NULL
 	`
 	var post Post
 
@@ -55,11 +80,18 @@ func (r *postRepository) GetByID(ctx context.Context, id int64) (*Post, error) {
 		&post.ID,
 		&post.Title,
 		&post.Content,
+		&post.Format,
 		&post.UserID,
 		pq.Array(&post.Tags),
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.Version,
+		&post.HiddenAt,
+		&post.PublishAt,
+		&post.IsQuote,
+		&post.QuotedPostID,
+		&post.RepostsCount,
+		&post.QuotesCount,
 	)
 	if err != nil {
 		return nil, err
@@ -68,34 +100,138 @@ func (r *postRepository) GetByID(ctx context.Context, id int64) (*Post, error) {
 	return &post, nil
 }
 
-func (r *postRepository) Delete(ctx context.Context, postID int64) error {
-	res, err := r.db.ExecContext(ctx, "DELETE FROM posts WHERE id = $1", postID)
+// Delete marks the post as deleted, it is removed for good by Purge.
+func (r *postRepository) Delete(ctx context.Context, tx *sql.Tx, postID, actorID int64) error {
+	query := `UPDATE posts SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL`
+
+	res, err := tx.ExecContext(ctx, query, postID, actorID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Restore brings back a post its author deleted after deletedAfter. Posts
+// removed by a moderator cannot be restored.
+func (r *postRepository) Restore(ctx context.Context, postID, userID int64, deletedAfter time.Time) error {
+	query := `
+		UPDATE posts SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND user_id = $2 AND deleted_by = $2 AND deleted_at > $3
+	`
+	res, err := r.db.ExecContext(ctx, query, postID, userID, deletedAfter)
 	if err != nil {
 		return err
 	}
 
-	_, err = res.RowsAffected()
+	rows, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
 
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
 	return nil
 }
 
-func (r *postRepository) Update(ctx context.Context, post *Post) error {
+// Purge hard-deletes up to limit posts and comments deleted before before,
+// along with the comments of the purged posts and the mentions and
+// notifications that point at anything purged. Replies to a purged comment
+// are kept as top level comments. Rows locked by another instance are
+// skipped.
+func (r *postRepository) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
 	query := `
-		UPDATE posts SET title = $1, content = $2, version = version + 1
-		WHERE id = $3 AND version = $4
-		RETURNING version
+		WITH doomed AS (
+			SELECT id FROM posts
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), post_comments AS (
+			DELETE FROM comments WHERE post_id IN (SELECT id FROM doomed)
+			RETURNING id
+		), purged_mentions AS (
+			DELETE FROM mentions
+			WHERE (target_type = 'post' AND target_id IN (SELECT id FROM doomed))
+				OR (target_type = 'comment' AND target_id IN (SELECT id FROM post_comments))
+		), purged_notifications AS (
+			DELETE FROM notifications
+			WHERE (target_type = 'post' AND target_id IN (SELECT id FROM doomed))
+				OR (target_type = 'comment' AND target_id IN (SELECT id FROM post_comments))
+		)
+		DELETE FROM posts WHERE id IN (SELECT id FROM doomed)
 	`
-	err := r.db.QueryRowContext(
+	res, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	posts, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		WITH doomed AS (
+			SELECT id FROM comments
+			WHERE deleted_at < $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), replies AS (
+			UPDATE comments SET parent_id = NULL
+			WHERE parent_id IN (SELECT id FROM doomed) AND id NOT IN (SELECT id FROM doomed)
+		), purged_mentions AS (
+			DELETE FROM mentions
+			WHERE target_type = 'comment' AND target_id IN (SELECT id FROM doomed)
+		), purged_notifications AS (
+			DELETE FROM notifications
+			WHERE target_type = 'comment' AND target_id IN (SELECT id FROM doomed)
+		)
+		DELETE FROM comments WHERE id IN (SELECT id FROM doomed)
+	`
+	res, err = r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	comments, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return posts + comments, nil
+}
+
+func (r *postRepository) Update(ctx context.Context, tx *sql.Tx, post *Post, hide bool) error {
+	query := `
+		UPDATE posts SET title = $1, content = $2, tags = $6, format = $7, version = version + 1, updated_at = NOW(),
+			hidden_at = CASE WHEN $5 THEN COALESCE(hidden_at, NOW()) ELSE hidden_at END
+		WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+		RETURNING version, updated_at, hidden_at
+	`
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		post.Title,
 		post.Content,
 		post.ID,
 		post.Version,
-	).Scan(&post.Version)
+		hide,
+		pq.Array(post.Tags),
+		post.Format,
+	).Scan(&post.Version, &post.UpdatedAt, &post.HiddenAt)
 	if err != nil {
 		return err
 	}
@@ -103,49 +239,296 @@ func (r *postRepository) Update(ctx context.Context, post *Post) error {
 	return nil
 }
 
-func (r *postRepository) GetUserFeed(ctx context.Context, userID int64, fq feed.PaginatedFeedQuery) ([]feed.PostWithMetaData, error) {
+func (r *postRepository) CreateRevision(ctx context.Context, tx *sql.Tx, rev *Revision) error {
 	query := `
-		SELECT 
-			p.id, p.user_id, p.title, p.content, p.created_at, p.version, p.tags,
-			u.username, COUNT(c.id) AS comments_count
-		FROM posts p
-		LEFT JOIN comments c ON c.post_id = p.id
-		LEFT JOIN users u ON p.user_id = u.id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-		WHERE 
-			f.user_id = $1 AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at ` + fq.Sort + `
-		LIMIT $2 OFFSET $3
+		INSERT INTO post_revisions (post_id, version, title, content, tags, edited_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
 	`
-	rows, err := r.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		rev.PostID,
+		rev.Version,
+		rev.Title,
+		rev.Content,
+		pq.Array(rev.Tags),
+		rev.EditedBy,
+	).Scan(&rev.ID, &rev.CreatedAt)
+}
+
+func (r *postRepository) ListRevisions(ctx context.Context, postID int64) ([]Revision, error) {
+	query := `
+		SELECT id, post_id, version, title, content, tags, edited_by, created_at
+		FROM post_revisions WHERE post_id = $1
+		ORDER BY version DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, postID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var feed []feed.PostWithMetaData
+	revisions := []Revision{}
 	for rows.Next() {
-		var p feed.PostWithMetaData
+		var rev Revision
+		err := rows.Scan(
+			&rev.ID,
+			&rev.PostID,
+			&rev.Version,
+			&rev.Title,
+			&rev.Content,
+			pq.Array(&rev.Tags),
+			&rev.EditedBy,
+			&rev.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (r *postRepository) React(ctx context.Context, postID, userID int64, kind string) error {
+	query := `
+		INSERT INTO post_reactions (post_id, user_id, kind) VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET kind = EXCLUDED.kind, created_at = NOW()
+	`
+	_, err := r.db.ExecContext(ctx, query, postID, userID, kind)
+	return err
+}
+
+func (r *postRepository) Unreact(ctx context.Context, postID, userID int64) error {
+	query := `DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, postID, userID)
+	return err
+}
+
+func (r *postRepository) ReactionCounts(ctx context.Context, postID int64) (map[string]int, error) {
+	query := `SELECT kind, COUNT(*) FROM post_reactions WHERE post_id = $1 GROUP BY kind`
+
+	rows, err := r.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			kind  string
+			count int
+		)
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, err
+		}
+		counts[kind] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// FeedAudience returns the users whose feed now contains post: followers of
+// the author and followers of its tags, minus blocks in either direction.
+func (r *postRepository) FeedAudience(ctx context.Context, post *Post) ([]int64, error) {
+	query := `
+		SELECT a.user_id FROM (
+			SELECT follower_id AS user_id FROM followers WHERE user_id = $1
+			UNION
+			SELECT user_id FROM tag_follows WHERE tag = ANY($2)
+		) a
+		WHERE
+			a.user_id <> $1 AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = a.user_id AND b.blocked_id = $1)
+					OR (b.blocker_id = $1 AND b.blocked_id = a.user_id)
+			)
+	`
+	rows, err := r.db.QueryContext(ctx, query, post.UserID, pq.Array(post.Tags))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *postRepository) ListScheduled(ctx context.Context, userID int64) ([]Post, error) {
+	query := `
+		SELECT id, title, content, format, user_id, tags, created_at, updated_at, version, hidden_at, publish_at
+		FROM posts WHERE user_id = $1 AND publish_at IS NOT NULL AND deleted_at IS NULL
+		ORDER BY publish_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := []Post{}
+	for rows.Next() {
+		var p Post
 		err := rows.Scan(
 			&p.ID,
-			&p.UserID,
 			&p.Title,
 			&p.Content,
+			&p.Format,
+			&p.UserID,
+			pq.Array(&p.Tags),
 			&p.CreatedAt,
+			&p.UpdatedAt,
 			&p.Version,
+			&p.HiddenAt,
+			&p.PublishAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, p)
+	}
+
+	return scheduled, rows.Err()
+}
+
+// Reschedule moves a post that is still scheduled, sql.ErrNoRows means it
+// was published in the meantime.
+func (r *postRepository) Reschedule(ctx context.Context, postID int64, publishAt time.Time) (*string, error) {
+	query := `
+		UPDATE posts SET publish_at = $2, updated_at = NOW()
+		WHERE id = $1 AND publish_at IS NOT NULL AND deleted_at IS NULL
+		RETURNING publish_at
+	`
+	var scheduled *string
+	if err := r.db.QueryRowContext(ctx, query, postID, publishAt).Scan(&scheduled); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+func (r *postRepository) DeleteScheduled(ctx context.Context, postID int64) error {
+	query := `DELETE FROM posts WHERE id = $1 AND publish_at IS NOT NULL AND deleted_at IS NULL`
+
+	res, err := r.db.ExecContext(ctx, query, postID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// PublishDue publishes up to limit posts whose time has come. created_at
+// moves to the publish time so the post lands at the top of feeds. Rows
+// locked by another instance are skipped.
+func (r *postRepository) PublishDue(ctx context.Context, now time.Time, limit int) ([]Post, error) {
+	query := `
+		UPDATE posts p SET publish_at = NULL, created_at = NOW(), updated_at = NOW()
+		FROM users u
+		WHERE u.id = p.user_id AND p.id IN (
+			SELECT id FROM posts
+			WHERE publish_at <= $1 AND deleted_at IS NULL
+			ORDER BY publish_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING p.id, p.title, p.user_id, p.tags, p.created_at, p.hidden_at, p.is_quote, p.quoted_post_id, u.username
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var published []Post
+	for rows.Next() {
+		var p Post
+		err := rows.Scan(
+			&p.ID,
+			&p.Title,
+			&p.UserID,
 			pq.Array(&p.Tags),
+			&p.CreatedAt,
+			&p.HiddenAt,
+			&p.IsQuote,
+			&p.QuotedPostID,
 			&p.User.Username,
-			&p.CommentsCount,
 		)
 		if err != nil {
 			return nil, err
 		}
+		published = append(published, p)
+	}
+
+	return published, rows.Err()
+}
+
+// Repost reports whether the repost is new, reposting twice is a no-op.
+func (r *postRepository) Repost(ctx context.Context, postID, userID int64) (bool, error) {
+	query := `INSERT INTO reposts (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	res, err := r.db.ExecContext(ctx, query, postID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
 
-		feed = append(feed, p)
+	return rows > 0, nil
+}
+
+func (r *postRepository) Unrepost(ctx context.Context, postID, userID int64) error {
+	query := `DELETE FROM reposts WHERE post_id = $1 AND user_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, postID, userID)
+	return err
+}
+
+// ListQuoted returns the given posts that can still be shown in a quote.
+func (r *postRepository) ListQuoted(ctx context.Context, ids []int64) (map[int64]*Quote, error) {
+	query := `
+		SELECT p.id, p.title, p.content, p.user_id, u.username, p.created_at
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = ANY($1) AND p.hidden_at IS NULL AND p.publish_at IS NULL AND p.deleted_at IS NULL
+	`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quoted := make(map[int64]*Quote)
+	for rows.Next() {
+		var q Quote
+		if err := rows.Scan(&q.ID, &q.Title, &q.Content, &q.UserID, &q.Username, &q.CreatedAt); err != nil {
+			return nil, err
+		}
+		quoted[q.ID] = &q
 	}
 
-	return feed, nil
+	return quoted, rows.Err()
 }
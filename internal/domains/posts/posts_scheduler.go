@@ -0,0 +1,121 @@
+package posts
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+)
+
+const scheduleBatchSize = 100
+
+// Scheduler publishes scheduled posts once they are due and runs the side
+// effects skipped at creation: mention and quote notifications and the
+// realtime feed fan-out.
+type Scheduler struct {
+	repo      PostRepository
+	mentions  mentions.MentionUsecase
+	notifier  commons.Notifier
+	publisher commons.Publisher
+	interval  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(repo PostRepository, mentions mentions.MentionUsecase, notifier commons.Notifier, publisher commons.Publisher, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		mentions:  mentions,
+		notifier:  notifier,
+		publisher: publisher,
+		interval:  interval,
+	}
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the scheduler and waits for the current run to finish.
+func (s *Scheduler) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := s.publishDue(ctx)
+		if err != nil {
+			log.Println("failed to publish scheduled posts:", err)
+			return
+		}
+
+		if published < scheduleBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) publishDue(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	published, err := s.repo.PublishDue(ctx, time.Now(), scheduleBatchSize)
+	if err != nil || len(published) == 0 {
+		return 0, err
+	}
+
+	ids := make([]int64, len(published))
+	ps := make([]*Post, len(published))
+	for i := range published {
+		ids[i] = published[i].ID
+		ps[i] = &published[i]
+	}
+
+	// the posts are out already, a failure here only loses notifications
+	found, err := s.mentions.List(ctx, mentions.TargetPost, ids...)
+	if err != nil {
+		log.Println("failed to load mentions of scheduled posts:", err)
+	}
+
+	if err := LoadQuotes(ctx, s.repo, ps...); err != nil {
+		log.Println("failed to load quotes of scheduled posts:", err)
+	}
+
+	for _, p := range ps {
+		if !p.Public() {
+			continue
+		}
+
+		mentions.Notify(s.notifier, p.UserID, mentions.TargetPost, p.ID, found[p.ID], nil)
+		notifyQuoted(s.notifier, p)
+		go publishNewPost(s.repo, s.publisher, *p, p.User.Username)
+	}
+
+	return len(published), nil
+}
@@ -0,0 +1,53 @@
+package posts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+const (
+	MaxTags      = 10
+	MaxTagLength = 50
+)
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+// NormalizeTag lowercases and trims a tag, a leading # is dropped.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+
+	if tag == "" || len([]rune(tag)) > MaxTagLength || !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: %q", commons.ErrInvalidTag, tag)
+	}
+
+	return tag, nil
+}
+
+// NormalizeTags normalizes every tag and drops duplicates, keeping the
+// original order.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, t := range tags {
+		tag, err := NormalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags per post", commons.ErrInvalidTag, MaxTags)
+	}
+
+	return normalized, nil
+}
@@ -3,75 +3,302 @@ package posts
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
 	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/filters"
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/mentions"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+const (
+	publishTimeout = 5 * time.Second
+
+	// how far ahead a post can be scheduled
+	maxScheduleAhead = 365 * 24 * time.Hour
 )
 
 type PostUsecase interface {
-	Create(ctx context.Context, post *CreatePostPayload) (*Post, error)
-	GetByID(ctx context.Context, postID int64) (*Post, error)
-	Update(ctx context.Context, id int64, newPost *UpdatePostPayload) (*Post, error)
-	Delete(ctx context.Context, postID int64) error
+	Create(ctx context.Context, author *users.User, post *CreatePostPayload) (*Post, error)
+	GetByID(ctx context.Context, viewer *users.User, postID int64) (*Post, error)
+	Update(ctx context.Context, actor *users.User, current *Post, newPost *UpdatePostPayload) (*Post, error)
+	Delete(ctx context.Context, actor *users.User, post *Post) error
+	Restore(ctx context.Context, actor *users.User, postID int64) (*Post, error)
+	GetRevisions(ctx context.Context, postID int64) ([]Revision, error)
+	React(ctx context.Context, user *users.User, post *Post, kind string) error
+	Unreact(ctx context.Context, user *users.User, post *Post) error
+	ListScheduled(ctx context.Context, user *users.User) ([]Post, error)
+	Reschedule(ctx context.Context, actor *users.User, post *Post, publishAt time.Time) (*Post, error)
+	CancelScheduled(ctx context.Context, actor *users.User, post *Post) error
+	Repost(ctx context.Context, user *users.User, post *Post) error
+	Unrepost(ctx context.Context, user *users.User, post *Post) error
 }
 
 type usecase struct {
-	repo PostRepository
+	db        *sql.DB
+	repo      PostRepository
+	audit     audit.AuditUsecase
+	filter    filters.Filter
+	mentions  mentions.MentionUsecase
+	media     media.MediaUsecase
+	previews  previews.PreviewUsecase
+	polls     polls.PollUsecase
+	notifier  commons.Notifier
+	publisher commons.Publisher
+	renderer  *Renderer
+	cfg       config.PostsConfig
 }
 
-func NewPostUsecase(repo PostRepository) PostUsecase {
-	return &usecase{repo: repo}
+func NewPostUsecase(db *sql.DB, repo PostRepository, audit audit.AuditUsecase, filter filters.Filter, mentions mentions.MentionUsecase, media media.MediaUsecase, previews previews.PreviewUsecase, polls polls.PollUsecase, notifier commons.Notifier, publisher commons.Publisher, renderer *Renderer, cfg config.Config) PostUsecase {
+	return &usecase{
+		db:        db,
+		repo:      repo,
+		audit:     audit,
+		filter:    filter,
+		mentions:  mentions,
+		media:     media,
+		previews:  previews,
+		polls:     polls,
+		notifier:  notifier,
+		publisher: publisher,
+		renderer:  renderer,
+		cfg:       cfg.Posts,
+	}
 }
 
-func (uc *usecase) Create(ctx context.Context, post *CreatePostPayload) (*Post, error) {
+func (uc *usecase) Create(ctx context.Context, author *users.User, post *CreatePostPayload) (*Post, error) {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
+	if err := uc.checkLength(post.Title, post.Content); err != nil {
+		return &Post{}, err
+	}
+
+	tags, err := NormalizeTags(post.Tags)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	var publishAt *string
+	if post.PublishAt != nil {
+		if err := checkSchedule(*post.PublishAt); err != nil {
+			return &Post{}, err
+		}
+		s := post.PublishAt.UTC().Format(time.RFC3339)
+		publishAt = &s
+	}
+
+	result, err := uc.filter.Check(ctx, post.Title, post.Content)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	if result.Action == filters.ActionReject {
+		return &Post{}, uc.reject(ctx, author.ID, nil, result)
+	}
+
 	p := &Post{
-		Title:   post.Title,
-		Content: post.Content,
-		Tags:    post.Tags,
-		// TODO: change after
-		UserID: 58,
+		Title:     result.Fields[0],
+		Content:   result.Fields[1],
+		Format:    post.Format,
+		Tags:      tags,
+		UserID:    author.ID,
+		PublishAt: publishAt,
+	}
+
+	if p.Format == "" {
+		p.Format = FormatPlain
+	}
+
+	if post.QuotePostID != nil {
+		if err := uc.checkShareable(ctx, *post.QuotePostID); err != nil {
+			return &Post{}, err
+		}
+		p.IsQuote, p.QuotedPostID = true, post.QuotePostID
+	}
+
+	// a scheduled poll opens when its post is published
+	opensAt := time.Now()
+	if post.PublishAt != nil {
+		opensAt = *post.PublishAt
 	}
 
-	if err := uc.repo.Create(ctx, p); err != nil {
+	var link string
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if err := uc.repo.Create(ctx, tx, p, result.Action == filters.ActionHold); err != nil {
+			return err
+		}
+
+		if err := uc.repo.CreateRevision(ctx, tx, newRevision(p, author.ID)); err != nil {
+			return err
+		}
+
+		if err := uc.media.Attach(ctx, tx, p.ID, author.ID, post.AttachmentIDs); err != nil {
+			return err
+		}
+
+		if link, err = uc.previews.Link(ctx, tx, p.ID, p.Content); err != nil {
+			return err
+		}
+
+		if post.Poll != nil {
+			if p.Poll, err = uc.polls.Create(ctx, tx, p.ID, post.Poll, opensAt); err != nil {
+				return err
+			}
+		}
+
+		p.Mentions, err = uc.mentions.Save(ctx, tx, mentions.TargetPost, p.ID, p.UserID, p.Content)
+		return err
+	})
+	if err != nil {
+		return &Post{}, err
+	}
+
+	attachments, err := uc.media.ListByPosts(ctx, p.ID)
+	if err != nil {
 		return &Post{}, err
 	}
+	p.Attachments = attachments[p.ID]
+	uc.renderer.Render(p)
+
+	if err := LoadQuotes(ctx, uc.repo, p); err != nil {
+		return &Post{}, err
+	}
+
+	// the preview is fetched in the background, it shows up on the next read
+	uc.previews.Refresh(link)
+
+	uc.recordMatches(ctx, p, result)
+
+	// held posts notify nobody until a moderator approves them, scheduled
+	// posts wait for the scheduler
+	if p.Public() {
+		mentions.Notify(uc.notifier, p.UserID, mentions.TargetPost, p.ID, p.Mentions, nil)
+		notifyQuoted(uc.notifier, p)
+		go publishNewPost(uc.repo, uc.publisher, *p, author.Username)
+	}
 
 	return p, nil
 }
 
-func (uc *usecase) Update(ctx context.Context, id int64, newPost *UpdatePostPayload) (*Post, error) {
+func (uc *usecase) Update(ctx context.Context, actor *users.User, current *Post, newPost *UpdatePostPayload) (*Post, error) {
+	if actor.ID != current.UserID && !actor.IsModerator() {
+		return nil, commons.ErrForbidden
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	var post Post
+	// PATCH only carries the fields that change
+	post := *current
 
 	if newPost.Title != nil {
+		if err := uc.checkLength(*newPost.Title, ""); err != nil {
+			return &Post{}, err
+		}
 		post.Title = *newPost.Title
 	}
 
 	if newPost.Content != nil {
+		if err := uc.checkLength("", *newPost.Content); err != nil {
+			return &Post{}, err
+		}
 		post.Content = *newPost.Content
 	}
 
-	post.ID = id
-	post.UpdatedAt = time.Now().String()
+	if newPost.Format != nil {
+		post.Format = *newPost.Format
+	}
+
+	if newPost.Tags != nil {
+		tags, err := NormalizeTags(*newPost.Tags)
+		if err != nil {
+			return &Post{}, err
+		}
+		post.Tags = tags
+	}
+
+	if newPost.Version != nil {
+		post.Version = *newPost.Version
+	}
+
+	result, err := uc.filter.Check(ctx, post.Title, post.Content)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	if result.Action == filters.ActionReject {
+		return &Post{}, uc.reject(ctx, actor.ID, &current.ID, result)
+	}
+
+	post.Title, post.Content = result.Fields[0], result.Fields[1]
 
-	if err := uc.repo.Update(ctx, &post); err != nil {
+	var link string
+	err = commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if err := uc.repo.Update(ctx, tx, &post, result.Action == filters.ActionHold); err != nil {
+			return err
+		}
+
+		if err := uc.repo.CreateRevision(ctx, tx, newRevision(&post, actor.ID)); err != nil {
+			return err
+		}
+
+		post.Mentions, err = uc.mentions.Save(ctx, tx, mentions.TargetPost, post.ID, post.UserID, post.Content)
+		if err != nil {
+			return err
+		}
+
+		if link, err = uc.previews.Link(ctx, tx, post.ID, post.Content); err != nil {
+			return err
+		}
+
+		// only moderation of someone else's post is audited
+		if actor.ID == current.UserID {
+			return nil
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(actor.ID),
+			Action:     audit.ActionPostUpdate,
+			TargetType: audit.TargetPost,
+			TargetID:   audit.ID(current.ID),
+			Diff:       postDiff(current, &post),
+		})
+	})
+	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
-			return &Post{}, commons.ErrNotFound
+			// the post was loaded by the middleware, so the version moved on
+			return &Post{}, commons.ErrEditConflict
 		default:
 			return &Post{}, err
 		}
 	}
 
+	uc.previews.Refresh(link)
+
+	uc.renderer.Invalidate(post.ID)
+	uc.renderer.Render(&post)
+
+	uc.recordMatches(ctx, &post, result)
+
+	if post.Public() {
+		mentions.Notify(uc.notifier, post.UserID, mentions.TargetPost, post.ID, post.Mentions, current.Mentions)
+	}
+
 	return &post, nil
 }
 
-func (uc *usecase) GetByID(ctx context.Context, postID int64) (*Post, error) {
+func (uc *usecase) GetByID(ctx context.Context, viewer *users.User, postID int64) (*Post, error) {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
@@ -85,14 +312,76 @@ func (uc *usecase) GetByID(ctx context.Context, postID int64) (*Post, error) {
 		}
 	}
 
-	return post, err
+	found, err := uc.mentions.List(ctx, mentions.TargetPost, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	post.Mentions = found[post.ID]
+
+	post.Reactions, err = uc.repo.ReactionCounts(ctx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := uc.media.ListByPosts(ctx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	post.Attachments = attachments[post.ID]
+
+	if err := LoadQuotes(ctx, uc.repo, post); err != nil {
+		return nil, err
+	}
+
+	linked, err := uc.previews.ListByPosts(ctx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	post.Preview = linked[post.ID]
+
+	attached, err := uc.polls.ListByPosts(ctx, viewer, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	post.Poll = attached[post.ID]
+
+	uc.renderer.Render(post)
+
+	return post, nil
 }
 
-func (uc *usecase) Delete(ctx context.Context, postID int64) error {
+// Delete soft deletes the post. Its author can restore it within the
+// restore window.
+func (uc *usecase) Delete(ctx context.Context, actor *users.User, post *Post) error {
+	if actor.ID != post.UserID && !actor.IsModerator() {
+		return commons.ErrForbidden
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	if err := uc.repo.Delete(ctx, postID); err != nil {
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if err := uc.repo.Delete(ctx, tx, post.ID, actor.ID); err != nil {
+			return err
+		}
+
+		if actor.ID == post.UserID {
+			return nil
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(actor.ID),
+			Action:     audit.ActionPostDelete,
+			TargetType: audit.TargetPost,
+			TargetID:   audit.ID(post.ID),
+			Diff: map[string]audit.Change{
+				"title":   {From: post.Title},
+				"content": {From: post.Content},
+				"user_id": {From: post.UserID},
+			},
+		})
+	})
+	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
 			return commons.ErrNotFound
@@ -101,5 +390,340 @@ func (uc *usecase) Delete(ctx context.Context, postID int64) error {
 		}
 	}
 
+	uc.renderer.Invalidate(post.ID)
+
 	return nil
 }
+
+// Restore undeletes a post the actor deleted themselves.
+func (uc *usecase) Restore(ctx context.Context, actor *users.User, postID int64) (*Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.Restore(ctx, postID, actor.ID, time.Now().Add(-uc.cfg.RestoreWindow)); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return uc.GetByID(ctx, actor, postID)
+}
+
+func (uc *usecase) GetRevisions(ctx context.Context, postID int64) ([]Revision, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.ListRevisions(ctx, postID)
+}
+
+func (uc *usecase) React(ctx context.Context, user *users.User, post *Post, kind string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.React(ctx, post.ID, user.ID, kind); err != nil {
+		return err
+	}
+
+	uc.notifier.Notify(commons.NotifyEvent{
+		UserID:     post.UserID,
+		ActorID:    user.ID,
+		Type:       commons.NotifyReaction,
+		TargetType: "post",
+		TargetID:   post.ID,
+	})
+
+	return nil
+}
+
+func (uc *usecase) Unreact(ctx context.Context, user *users.User, post *Post) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.Unreact(ctx, post.ID, user.ID)
+}
+
+func (uc *usecase) Repost(ctx context.Context, user *users.User, post *Post) error {
+	if !post.Public() {
+		return commons.ErrNotShareable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	added, err := uc.repo.Repost(ctx, post.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if added && user.ID != post.UserID {
+		uc.notifier.Notify(commons.NotifyEvent{
+			UserID:     post.UserID,
+			ActorID:    user.ID,
+			Type:       commons.NotifyRepost,
+			TargetType: "post",
+			TargetID:   post.ID,
+		})
+	}
+
+	return nil
+}
+
+func (uc *usecase) Unrepost(ctx context.Context, user *users.User, post *Post) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.Unrepost(ctx, post.ID, user.ID)
+}
+
+// checkShareable makes sure postID exists and is public before it is
+// quoted.
+func (uc *usecase) checkShareable(ctx context.Context, postID int64) error {
+	quoted, err := uc.repo.GetByID(ctx, postID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotShareable
+		default:
+			return err
+		}
+	}
+
+	if !quoted.Public() {
+		return commons.ErrNotShareable
+	}
+
+	return nil
+}
+
+func (uc *usecase) ListScheduled(ctx context.Context, user *users.User) ([]Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	scheduled, err := uc.repo.ListScheduled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range scheduled {
+		uc.renderer.Render(&scheduled[i])
+	}
+
+	return scheduled, nil
+}
+
+func (uc *usecase) Reschedule(ctx context.Context, actor *users.User, post *Post, publishAt time.Time) (*Post, error) {
+	if err := checkScheduleOwner(actor, post); err != nil {
+		return nil, err
+	}
+
+	if err := checkSchedule(publishAt); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	// the poll opens with the post and has to stay open long enough
+	if err := uc.polls.CheckOpensAt(ctx, post.ID, publishAt); err != nil {
+		return nil, err
+	}
+
+	scheduled, err := uc.repo.Reschedule(ctx, post.ID, publishAt)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotScheduled
+		default:
+			return nil, err
+		}
+	}
+
+	p := *post
+	p.PublishAt = scheduled
+
+	return &p, nil
+}
+
+// CancelScheduled deletes a post that has not been published yet.
+func (uc *usecase) CancelScheduled(ctx context.Context, actor *users.User, post *Post) error {
+	if err := checkScheduleOwner(actor, post); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.DeleteScheduled(ctx, post.ID); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotScheduled
+		default:
+			return err
+		}
+	}
+
+	uc.renderer.Invalidate(post.ID)
+
+	return nil
+}
+
+func checkSchedule(publishAt time.Time) error {
+	now := time.Now()
+	if !publishAt.After(now) || publishAt.After(now.Add(maxScheduleAhead)) {
+		return commons.ErrInvalidSchedule
+	}
+
+	return nil
+}
+
+func checkScheduleOwner(actor *users.User, post *Post) error {
+	if actor.ID != post.UserID {
+		return commons.ErrForbidden
+	}
+
+	if post.PublishAt == nil {
+		return commons.ErrNotScheduled
+	}
+
+	return nil
+}
+
+func newRevision(post *Post, editorID int64) *Revision {
+	return &Revision{
+		PostID:   post.ID,
+		Version:  post.Version,
+		Title:    post.Title,
+		Content:  post.Content,
+		Tags:     post.Tags,
+		EditedBy: &editorID,
+	}
+}
+
+func postDiff(before, after *Post) map[string]audit.Change {
+	diff := map[string]audit.Change{}
+
+	if after.Title != before.Title {
+		diff["title"] = audit.Change{From: before.Title, To: after.Title}
+	}
+
+	if after.Content != before.Content {
+		diff["content"] = audit.Change{From: before.Content, To: after.Content}
+	}
+
+	if strings.Join(after.Tags, ",") != strings.Join(before.Tags, ",") {
+		diff["tags"] = audit.Change{From: before.Tags, To: after.Tags}
+	}
+
+	return diff
+}
+
+// reject records the matches of rejected content and returns
+// ErrContentRejected unless the write fails.
+func (uc *usecase) reject(ctx context.Context, userID int64, postID *int64, result *filters.Result) error {
+	if err := uc.filter.Record(ctx, filters.TargetPost, postID, userID, result); err != nil {
+		return err
+	}
+
+	return commons.ErrContentRejected
+}
+
+// publishNewPost runs after the request returns, a large audience must not
+// hold up the author.
+func publishNewPost(repo PostRepository, publisher commons.Publisher, post Post, username string) {
+	if publisher == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	audience, err := repo.FeedAudience(ctx, &post)
+	if err != nil {
+		log.Println("failed to load feed audience:", post.ID, err)
+		return
+	}
+
+	err = publisher.Publish(ctx, audience, commons.EventNewPost, NewPostEvent{
+		ID:        post.ID,
+		Title:     post.Title,
+		UserID:    post.UserID,
+		Username:  username,
+		Tags:      post.Tags,
+		CreatedAt: post.CreatedAt,
+	})
+	if err != nil {
+		log.Println("failed to publish new post:", post.ID, err)
+	}
+}
+
+// checkLength enforces the configured limits, in characters. Empty values
+// are not checked.
+func (uc *usecase) checkLength(title, content string) error {
+	if utf8.RuneCountInString(title) > uc.cfg.MaxTitleLength {
+		return fmt.Errorf("%w: title is longer than %d characters", commons.ErrTooLong, uc.cfg.MaxTitleLength)
+	}
+
+	if utf8.RuneCountInString(content) > uc.cfg.MaxContentLength {
+		return fmt.Errorf("%w: content is longer than %d characters", commons.ErrTooLong, uc.cfg.MaxContentLength)
+	}
+
+	return nil
+}
+
+// recordMatches runs after the post is stored, a failure here must not fail
+// the request.
+func (uc *usecase) recordMatches(ctx context.Context, post *Post, result *filters.Result) {
+	if err := uc.filter.Record(ctx, filters.TargetPost, &post.ID, post.UserID, result); err != nil {
+		log.Println("failed to record filter matches:", post.ID, err)
+	}
+}
+
+// LoadQuotes fills Quote on the quote posts among ps. Originals that were
+// deleted or are no longer public show as unavailable.
+func LoadQuotes(ctx context.Context, repo PostRepository, ps ...*Post) error {
+	var ids []int64
+	for _, p := range ps {
+		if p.QuotedPostID != nil {
+			ids = append(ids, *p.QuotedPostID)
+		}
+	}
+
+	quoted := map[int64]*Quote{}
+	if len(ids) > 0 {
+		var err error
+		if quoted, err = repo.ListQuoted(ctx, ids); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range ps {
+		if !p.IsQuote {
+			continue
+		}
+
+		if p.QuotedPostID != nil && quoted[*p.QuotedPostID] != nil {
+			p.Quote = quoted[*p.QuotedPostID]
+		} else {
+			p.Quote = &Quote{Unavailable: true}
+		}
+	}
+
+	return nil
+}
+
+func notifyQuoted(notifier commons.Notifier, post *Post) {
+	if post.Quote == nil || post.Quote.Unavailable || post.Quote.UserID == post.UserID {
+		return
+	}
+
+	notifier.Notify(commons.NotifyEvent{
+		UserID:     post.Quote.UserID,
+		ActorID:    post.UserID,
+		Type:       commons.NotifyQuote,
+		TargetType: "post",
+		TargetID:   post.Quote.ID,
+	})
+}
@@ -0,0 +1,11 @@
+package previews
+
+import "database/sql"
+
+func NewPreviews(db *sql.DB, queue *Queue) PreviewUsecase {
+	return NewPreviewUsecase(NewPreviewRepository(db), queue)
+}
+
+func NewPreviewQueue(db *sql.DB) *Queue {
+	return NewQueue(NewPreviewRepository(db), NewFetcher(nil))
+}
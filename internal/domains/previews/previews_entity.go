@@ -0,0 +1,78 @@
+package previews
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	StatusPending = "pending"
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+
+	maxURLLength = 2048
+
+	// how long a fetched preview is reused before it is refetched
+	okTTL     = 24 * time.Hour
+	failedTTL = time.Hour
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+type Preview struct {
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	ImageURL    string     `json:"image_url,omitempty"`
+	SiteName    string     `json:"site_name,omitempty"`
+	Status      string     `json:"-"`
+	FetchedAt   *time.Time `json:"-"`
+}
+
+// Stale reports whether the cached preview should be fetched again.
+func (p *Preview) Stale(now time.Time) bool {
+	if p.FetchedAt == nil {
+		return true
+	}
+
+	age := now.Sub(*p.FetchedAt)
+	switch p.Status {
+	case StatusOK:
+		return age > okTTL
+	case StatusFailed:
+		return age > failedTTL
+	default:
+		return true
+	}
+}
+
+// FirstURL returns the first http(s) link in content, normalized, or "".
+func FirstURL(content string) string {
+	for _, raw := range urlPattern.FindAllString(content, -1) {
+		raw = strings.TrimRight(raw, ".,;:!?)]}'")
+		if u := normalizeURL(raw); u != "" {
+			return u
+		}
+	}
+
+	return ""
+}
+
+func normalizeURL(raw string) string {
+	if len(raw) > maxURLLength {
+		return ""
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ""
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+
+	return u.String()
+}
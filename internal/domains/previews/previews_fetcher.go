@@ -0,0 +1,303 @@
+package previews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	fetchTimeout   = 5 * time.Second
+	maxBodySize    = 512 << 10
+	maxRedirects   = 3
+	maxFieldLength = 500
+)
+
+var (
+	ErrBlockedAddress = errors.New("link preview: address is not allowed")
+	ErrNotHTML        = errors.New("link preview: not an html page")
+)
+
+// blockedPrefixes are non-public ranges not covered by the netip.Addr
+// helpers used in publicAddr.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a fetcher that only connects to public addresses on
+// ports 80 and 443. The check runs on the address actually dialed, after
+// DNS resolution and on every redirect, so rebinding a hostname to an
+// internal address does not get through. Pass a client to lift the guard,
+// e.g. for httptest servers.
+func NewFetcher(client *http.Client) *Fetcher {
+	if client == nil {
+		client = guardedClient(nil, checkAddress)
+	}
+
+	return &Fetcher{client: client}
+}
+
+// guardedClient dials through resolver, nil for the default one, and asks
+// check about every address before connecting.
+func guardedClient(resolver *net.Resolver, check func(addr netip.Addr, port string) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:  fetchTimeout,
+		Resolver: resolver,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return ErrBlockedAddress
+			}
+
+			return check(addr, port)
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    fetchTimeout,
+		ResponseHeaderTimeout:  fetchTimeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		DisableKeepAlives:      true,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   2 * fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("link preview: too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+}
+
+func checkAddress(addr netip.Addr, port string) error {
+	if !publicAddr(addr) || (port != "80" && port != "443") {
+		return ErrBlockedAddress
+	}
+
+	return nil
+}
+
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return !addr.Is4() || addr != netip.AddrFrom4([4]byte{255, 255, 255, 255})
+}
+
+// Fetch downloads rawURL and extracts its OpenGraph and Twitter card
+// metadata, falling back to <title> and the description meta tag.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "GopherSocialBot/1.0 (+link preview)")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("link preview: unexpected status %d", res.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	p := parseHTML(io.LimitReader(res.Body, maxBodySize))
+	p.URL = rawURL
+
+	if p.ImageURL != "" {
+		p.ImageURL = resolveURL(res.Request.URL, p.ImageURL)
+	}
+
+	if p.SiteName == "" {
+		p.SiteName = res.Request.URL.Hostname()
+	}
+
+	if p.Title == "" {
+		return nil, errors.New("link preview: page has no title")
+	}
+
+	return p, nil
+}
+
+// parseHTML reads the <head> only, stopping at <body> or the size limit.
+func parseHTML(r io.Reader) *Preview {
+	var (
+		p        Preview
+		title    string
+		desc     string
+		inTitle  bool
+		fallback = map[string]string{}
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				break loop
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+
+				var key, content string
+				for {
+					k, v, more := z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(string(v))
+						}
+					case "content":
+						content = string(v)
+					}
+					if !more {
+						break
+					}
+				}
+
+				switch key {
+				case "og:title":
+					p.Title = content
+				case "og:description":
+					p.Description = content
+				case "og:image", "og:image:url":
+					if p.ImageURL == "" {
+						p.ImageURL = content
+					}
+				case "og:site_name":
+					p.SiteName = content
+				case "twitter:title", "twitter:description", "twitter:image":
+					fallback[key] = content
+				case "description":
+					desc = content
+				}
+			}
+
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(z.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		}
+	}
+
+	p.Title = firstNonEmpty(p.Title, fallback["twitter:title"], title)
+	p.Description = firstNonEmpty(p.Description, fallback["twitter:description"], desc)
+	p.ImageURL = firstNonEmpty(p.ImageURL, fallback["twitter:image"])
+
+	p.Title = truncate(p.Title)
+	p.Description = truncate(p.Description)
+	p.SiteName = truncate(p.SiteName)
+
+	return &p
+}
+
+// resolveURL makes ref absolute against base, dropping anything that is
+// not an http(s) URL.
+func resolveURL(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.String()) > maxURLLength {
+		return ""
+	}
+
+	return u.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+
+	runes := []rune(s)
+	if len(runes) > maxFieldLength {
+		return string(runes[:maxFieldLength-1]) + "…"
+	}
+
+	return s
+}
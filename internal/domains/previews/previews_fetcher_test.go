@@ -0,0 +1,255 @@
+package previews
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubResolver answers A queries from hosts and fails everything else, so
+// tests control what a hostname resolves to without a real DNS server.
+func stubResolver(hosts map[string]string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go serveDNS(server, hosts)
+			return client, nil
+		},
+	}
+}
+
+// serveDNS speaks DNS over a stream, each message prefixed with its length.
+func serveDNS(conn net.Conn, hosts map[string]string) {
+	defer conn.Close()
+
+	for {
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(msg)
+		if err != nil {
+			return
+		}
+		q, err := p.Question()
+		if err != nil {
+			return
+		}
+
+		res := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeNameError},
+			Questions: []dnsmessage.Question{q},
+		}
+
+		if ip, ok := hosts[strings.TrimSuffix(q.Name.String(), ".")]; ok {
+			res.Header.RCode = dnsmessage.RCodeSuccess
+			if q.Type == dnsmessage.TypeA {
+				res.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: netip.MustParseAddr(ip).As4()},
+				}}
+			}
+		}
+
+		out, err := res.Pack()
+		if err != nil {
+			return
+		}
+
+		binary.BigEndian.PutUint16(size[:], uint16(len(out)))
+		if _, err := conn.Write(append(size[:], out...)); err != nil {
+			return
+		}
+	}
+}
+
+// allowServer lets the guard through to srv as if it were a public site,
+// every other address still goes through checkAddress.
+func allowServer(srv *httptest.Server) func(netip.Addr, string) error {
+	allowed := netip.MustParseAddrPort(srv.Listener.Addr().String())
+
+	return func(addr netip.Addr, port string) error {
+		if addr == allowed.Addr() && port == strconv.Itoa(int(allowed.Port())) {
+			return nil
+		}
+		return checkAddress(addr, port)
+	}
+}
+
+func TestFetchBlocksNonPublicAddresses(t *testing.T) {
+	resolver := stubResolver(map[string]string{
+		"internal.test":  "10.0.0.5",
+		"metadata.test":  "169.254.169.254",
+		"public.test":    "93.184.216.34",
+		"carrier.test":   "100.64.0.1",
+		"localhost.test": "127.0.0.1",
+	})
+	f := &Fetcher{client: guardedClient(resolver, checkAddress)}
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"hostname resolves to a private address", "http://internal.test/"},
+		{"hostname resolves to link-local metadata", "http://metadata.test/latest/meta-data/"},
+		{"hostname resolves to carrier-grade nat", "https://carrier.test/"},
+		{"hostname resolves to loopback", "http://localhost.test/"},
+		{"loopback literal", "http://127.0.0.1/"},
+		{"ipv6 loopback literal", "http://[::1]/"},
+		{"ipv4-mapped private literal", "http://[::ffff:192.168.1.1]/"},
+		{"public address on another port", "http://public.test:8080/"},
+		{"public address on ssh port", "http://93.184.216.34:22/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.Fetch(context.Background(), tt.url)
+			if !errors.Is(err, ErrBlockedAddress) {
+				t.Fatalf("Fetch(%q) error = %v, want %v", tt.url, err, ErrBlockedAddress)
+			}
+		})
+	}
+}
+
+func TestFetchBlocksRedirectToLoopback(t *testing.T) {
+	var hit bool
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer internal.Close()
+
+	targets := []string{
+		"http://127.0.0.1/",
+		"http://localhost/admin",
+		internal.URL + "/secret",
+	}
+
+	for _, target := range targets {
+		t.Run(target, func(t *testing.T) {
+			site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, target, http.StatusFound)
+			}))
+			defer site.Close()
+
+			f := &Fetcher{client: guardedClient(nil, allowServer(site))}
+
+			_, err := f.Fetch(context.Background(), site.URL)
+			if !errors.Is(err, ErrBlockedAddress) {
+				t.Fatalf("Fetch error = %v, want %v", err, ErrBlockedAddress)
+			}
+		})
+	}
+
+	if hit {
+		t.Fatal("redirect reached the loopback server")
+	}
+}
+
+func TestFetchLimitsBody(t *testing.T) {
+	padding := strings.Repeat(`<meta name="x" content="padding">`, maxBodySize/32)
+
+	tests := []struct {
+		name      string
+		page      string
+		wantTitle string
+	}{
+		{
+			name:      "title within the limit",
+			page:      `<html><head><title>small</title>` + padding + `</head></html>`,
+			wantTitle: "small",
+		},
+		{
+			name: "title past the limit",
+			page: `<html><head>` + padding + `<title>too far</title></head></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				io.WriteString(w, tt.page)
+			}))
+			defer site.Close()
+
+			f := &Fetcher{client: guardedClient(nil, allowServer(site))}
+
+			p, err := f.Fetch(context.Background(), site.URL)
+			if tt.wantTitle == "" {
+				if err == nil {
+					t.Fatalf("Fetch read past the body limit, title %q", p.Title)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", p.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"title":"json"}`)
+	}))
+	defer site.Close()
+
+	f := &Fetcher{client: guardedClient(nil, allowServer(site))}
+
+	if _, err := f.Fetch(context.Background(), site.URL); !errors.Is(err, ErrNotHTML) {
+		t.Fatalf("Fetch error = %v, want %v", err, ErrNotHTML)
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.0.1", false},
+		{"127.0.0.1", false},
+		{"0.0.0.0", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package previews
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	queueSize      = 256
+	workerCount    = 2
+	refreshTimeout = 15 * time.Second
+)
+
+// Queue fetches previews in the background so posting a link does not wait
+// on a third-party site. URLs already queued are not queued again, and
+// URLs are dropped, and logged, when the queue is full.
+type Queue struct {
+	repo    PreviewRepository
+	fetcher *Fetcher
+	queue   chan string
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	inflight map[string]bool
+}
+
+func NewQueue(repo PreviewRepository, fetcher *Fetcher) *Queue {
+	q := &Queue{
+		repo:     repo,
+		fetcher:  fetcher,
+		queue:    make(chan string, queueSize),
+		inflight: make(map[string]bool),
+	}
+
+	for i := 0; i < workerCount; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return q
+}
+
+func (q *Queue) Enqueue(url string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.inflight[url] {
+		return
+	}
+
+	select {
+	case q.queue <- url:
+		q.inflight[url] = true
+	default:
+		log.Println("link preview queue full, dropped:", url)
+	}
+}
+
+// Close stops accepting URLs and waits until the queue is drained.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.queue)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for url := range q.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		q.refresh(ctx, url)
+		cancel()
+
+		q.mu.Lock()
+		delete(q.inflight, url)
+		q.mu.Unlock()
+	}
+}
+
+func (q *Queue) refresh(ctx context.Context, url string) {
+	cached, err := q.repo.Get(ctx, url)
+	if err == nil && !cached.Stale(time.Now()) {
+		return
+	}
+
+	p, err := q.fetcher.Fetch(ctx, url)
+	if err != nil {
+		log.Println("failed to fetch link preview:", url, err)
+		p = &Preview{URL: url, Status: StatusFailed}
+	} else {
+		p.Status = StatusOK
+	}
+
+	if err := q.repo.Save(ctx, p); err != nil {
+		log.Println("failed to save link preview:", url, err)
+	}
+}
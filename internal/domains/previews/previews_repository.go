@@ -0,0 +1,135 @@
+package previews
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type PreviewRepository interface {
+	Get(ctx context.Context, url string) (*Preview, error)
+	Save(ctx context.Context, p *Preview) error
+	SetPostLink(ctx context.Context, tx *sql.Tx, postID int64, url string) error
+	ListByPosts(ctx context.Context, postIDs []int64) (map[int64]*Preview, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewPreviewRepository(db *sql.DB) PreviewRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) Get(ctx context.Context, url string) (*Preview, error) {
+	query := `
+		SELECT url, status, title, description, image_url, site_name, fetched_at
+		FROM link_previews WHERE url = $1
+	`
+	var p Preview
+	err := r.db.QueryRowContext(ctx, query, url).Scan(
+		&p.URL,
+		&p.Status,
+		&p.Title,
+		&p.Description,
+		&p.ImageURL,
+		&p.SiteName,
+		&p.FetchedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Save stores a fetch result. A failed refetch keeps the last good
+// metadata so a flaky site does not lose its card.
+func (r *repository) Save(ctx context.Context, p *Preview) error {
+	query := `
+		INSERT INTO link_previews (url, status, title, description, image_url, site_name, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (url) DO UPDATE SET
+			status = CASE
+				WHEN EXCLUDED.status = 'failed' AND link_previews.status = 'ok' THEN 'ok'
+				ELSE EXCLUDED.status
+			END,
+			title = CASE WHEN EXCLUDED.status = 'ok' THEN EXCLUDED.title ELSE link_previews.title END,
+			description = CASE WHEN EXCLUDED.status = 'ok' THEN EXCLUDED.description ELSE link_previews.description END,
+			image_url = CASE WHEN EXCLUDED.status = 'ok' THEN EXCLUDED.image_url ELSE link_previews.image_url END,
+			site_name = CASE WHEN EXCLUDED.status = 'ok' THEN EXCLUDED.site_name ELSE link_previews.site_name END,
+			fetched_at = NOW()
+	`
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		p.URL,
+		p.Status,
+		p.Title,
+		p.Description,
+		p.ImageURL,
+		p.SiteName,
+	)
+	return err
+}
+
+// SetPostLink points postID at url, or removes its link when url is empty.
+func (r *repository) SetPostLink(ctx context.Context, tx *sql.Tx, postID int64, url string) error {
+	if url == "" {
+		_, err := tx.ExecContext(ctx, `DELETE FROM post_links WHERE post_id = $1`, postID)
+		return err
+	}
+
+	query := `INSERT INTO link_previews (url) VALUES ($1) ON CONFLICT (url) DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, url); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO post_links (post_id, url) VALUES ($1, $2)
+		ON CONFLICT (post_id) DO UPDATE SET url = EXCLUDED.url
+	`
+	_, err := tx.ExecContext(ctx, query, postID, url)
+	return err
+}
+
+// ListByPosts returns the fetched previews of the given posts, pending and
+// failed links are left out.
+func (r *repository) ListByPosts(ctx context.Context, postIDs []int64) (map[int64]*Preview, error) {
+	query := `
+		SELECT pl.post_id, lp.url, lp.status, lp.title, lp.description, lp.image_url, lp.site_name, lp.fetched_at
+		FROM post_links pl
+		JOIN link_previews lp ON lp.url = pl.url
+		WHERE pl.post_id = ANY($1) AND lp.status = 'ok'
+	`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	previews := make(map[int64]*Preview)
+	for rows.Next() {
+		var (
+			postID int64
+			p      Preview
+		)
+		err := rows.Scan(
+			&postID,
+			&p.URL,
+			&p.Status,
+			&p.Title,
+			&p.Description,
+			&p.ImageURL,
+			&p.SiteName,
+			&p.FetchedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		previews[postID] = &p
+	}
+
+	return previews, rows.Err()
+}
@@ -0,0 +1,52 @@
+package previews
+
+import (
+	"context"
+	"database/sql"
+)
+
+type PreviewUsecase interface {
+	Link(ctx context.Context, tx *sql.Tx, postID int64, content string) (string, error)
+	Refresh(url string)
+	ListByPosts(ctx context.Context, postIDs ...int64) (map[int64]*Preview, error)
+}
+
+type usecase struct {
+	repo  PreviewRepository
+	queue *Queue
+}
+
+func NewPreviewUsecase(repo PreviewRepository, queue *Queue) PreviewUsecase {
+	return &usecase{
+		repo:  repo,
+		queue: queue,
+	}
+}
+
+// Link stores the first URL of content as the post's link, inside the
+// post's transaction. Call Refresh with the returned URL once committed.
+func (uc *usecase) Link(ctx context.Context, tx *sql.Tx, postID int64, content string) (string, error) {
+	url := FirstURL(content)
+
+	if err := uc.repo.SetPostLink(ctx, tx, postID, url); err != nil {
+		return "", err
+	}
+
+	return url, nil
+}
+
+func (uc *usecase) Refresh(url string) {
+	if url == "" || uc.queue == nil {
+		return
+	}
+
+	uc.queue.Enqueue(url)
+}
+
+func (uc *usecase) ListByPosts(ctx context.Context, postIDs ...int64) (map[int64]*Preview, error) {
+	if len(postIDs) == 0 {
+		return map[int64]*Preview{}, nil
+	}
+
+	return uc.repo.ListByPosts(ctx, postIDs)
+}
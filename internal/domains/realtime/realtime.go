@@ -0,0 +1,18 @@
+package realtime
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+func InitRealtimeDomain(db *sql.DB, cfg config.Config, gateway *Gateway) RealtimeHandler {
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	useruc := users.NewUserUsecase(db, users.NewUserRepository(db), auditUC, nil, nil, nil, cfg)
+
+	hdl := NewRealtimeHandler(gateway, useruc)
+
+	return hdl
+}
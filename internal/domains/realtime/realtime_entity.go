@@ -0,0 +1,51 @@
+package realtime
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventHeartbeat = "heartbeat"
+
+	heartbeatInterval = 25 * time.Second
+	writeWait         = 10 * time.Second
+	clientBuffer      = 64
+	maxMessageSize    = 4096
+)
+
+type Event struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+type Ticket struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// envelope is what travels over Redis between API instances.
+type envelope struct {
+	UserIDs []int64 `json:"user_ids"`
+	Event   Event   `json:"event"`
+}
+
+func newEvent(eventType string, data any) (Event, error) {
+	e := Event{
+		Type:   eventType,
+		SentAt: time.Now().UTC(),
+	}
+
+	if data == nil {
+		return e, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	e.Data = raw
+
+	return e, nil
+}
@@ -0,0 +1,165 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	eventsChannel = "realtime:events"
+	ticketPrefix  = "realtime:ticket:"
+	ticketTTL     = 30 * time.Second
+)
+
+type ticket struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+// Gateway fans events out to the streams of every API instance. With Redis
+// each instance publishes to a shared channel and delivers whatever it
+// receives to its own clients; without Redis delivery is local only.
+type Gateway struct {
+	hub    *hub
+	rdb    *redis.Client
+	pubsub *redis.PubSub
+	done   chan struct{}
+	once   sync.Once
+
+	mu      sync.Mutex
+	tickets map[string]ticket
+}
+
+func NewGateway(rdb *redis.Client) *Gateway {
+	g := &Gateway{
+		hub:     newHub(),
+		rdb:     rdb,
+		done:    make(chan struct{}),
+		tickets: make(map[string]ticket),
+	}
+
+	if rdb == nil {
+		close(g.done)
+		return g
+	}
+
+	g.pubsub = rdb.Subscribe(context.Background(), eventsChannel)
+	go g.listen()
+
+	return g
+}
+
+func (g *Gateway) Publish(ctx context.Context, userIDs []int64, eventType string, data any) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	e, err := newEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	if g.rdb == nil {
+		g.hub.deliver(userIDs, e)
+		return nil
+	}
+
+	payload, err := json.Marshal(envelope{UserIDs: userIDs, Event: e})
+	if err != nil {
+		return err
+	}
+
+	return g.rdb.Publish(ctx, eventsChannel, payload).Err()
+}
+
+// Close unsubscribes from Redis and disconnects every client. Call it
+// before shutting the HTTP server down, open streams would hold it up.
+func (g *Gateway) Close() {
+	g.once.Do(func() {
+		if g.pubsub != nil {
+			if err := g.pubsub.Close(); err != nil {
+				log.Println("failed to close realtime subscription:", err)
+			}
+		}
+		<-g.done
+
+		g.hub.close()
+	})
+}
+
+// IssueTicket returns a short-lived, single-use ticket for clients that
+// cannot send an Authorization header, i.e. browser WebSocket and
+// EventSource. Tickets are shared through Redis so any instance can
+// redeem them.
+func (g *Gateway) IssueTicket(ctx context.Context, userID int64) (string, error) {
+	t, err := auth.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	if g.rdb != nil {
+		if err := g.rdb.Set(ctx, ticketPrefix+t, userID, ticketTTL).Err(); err != nil {
+			return "", err
+		}
+		return t, nil
+	}
+
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for k, v := range g.tickets {
+		if now.After(v.expiresAt) {
+			delete(g.tickets, k)
+		}
+	}
+	g.tickets[t] = ticket{userID: userID, expiresAt: now.Add(ticketTTL)}
+
+	return t, nil
+}
+
+func (g *Gateway) RedeemTicket(ctx context.Context, t string) (int64, error) {
+	if g.rdb != nil {
+		val, err := g.rdb.GetDel(ctx, ticketPrefix+t).Result()
+		if err == redis.Nil {
+			return 0, commons.ErrInvalidToken
+		} else if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(val, 10, 64)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	v, ok := g.tickets[t]
+	delete(g.tickets, t)
+	if !ok || time.Now().After(v.expiresAt) {
+		return 0, commons.ErrInvalidToken
+	}
+
+	return v.userID, nil
+}
+
+func (g *Gateway) listen() {
+	defer close(g.done)
+
+	for msg := range g.pubsub.Channel() {
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			log.Println("invalid realtime event:", err)
+			continue
+		}
+
+		g.hub.deliver(env.UserIDs, env.Event)
+	}
+}
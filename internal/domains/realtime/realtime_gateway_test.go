@@ -0,0 +1,121 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/redis/go-redis/v9"
+)
+
+// newGateways starts two gateways on the same Redis, the way two API
+// instances run.
+func newGateways(t *testing.T) (*Gateway, *Gateway) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	gateways := make([]*Gateway, 2)
+	for i := range gateways {
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+
+		g := NewGateway(rdb)
+		t.Cleanup(g.Close)
+		gateways[i] = g
+	}
+
+	// Subscribe returns before Redis has registered the subscription
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumSub(eventsChannel)[eventsChannel] < len(gateways) {
+		if time.Now().After(deadline) {
+			t.Fatal("gateways did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return gateways[0], gateways[1]
+}
+
+func receive(t *testing.T, c *client) Event {
+	t.Helper()
+
+	select {
+	case e, ok := <-c.events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return Event{}
+}
+
+func TestGatewayFansOutAcrossInstances(t *testing.T) {
+	a, b := newGateways(t)
+	ctx := context.Background()
+
+	onA, _ := a.hub.subscribe(7)
+	onB, _ := b.hub.subscribe(7)
+	other, _ := b.hub.subscribe(8)
+
+	if err := a.Publish(ctx, []int64{7}, "notification", map[string]int{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*client{onA, onB} {
+		e := receive(t, c)
+
+		var data map[string]int
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		if e.Type != "notification" || data["id"] != 1 {
+			t.Errorf("received %s %s", e.Type, e.Data)
+		}
+	}
+
+	select {
+	case e := <-other.events:
+		t.Errorf("user 8 received %s", e.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestGatewayTicketsAcrossInstances(t *testing.T) {
+	a, b := newGateways(t)
+	ctx := context.Background()
+
+	ticket, err := a.IssueTicket(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := b.RedeemTicket(ctx, ticket)
+	if err != nil || userID != 7 {
+		t.Fatalf("RedeemTicket on the other instance = %d, %v", userID, err)
+	}
+
+	if _, err := a.RedeemTicket(ctx, ticket); !errors.Is(err, commons.ErrInvalidToken) {
+		t.Errorf("second redeem error = %v, want %v", err, commons.ErrInvalidToken)
+	}
+}
+
+func TestGatewayCloseEndsStreams(t *testing.T) {
+	a, _ := newGateways(t)
+
+	c, _ := a.hub.subscribe(7)
+	a.Close()
+
+	if _, ok := <-c.events; ok {
+		t.Error("stream still open after Close")
+	}
+	if _, ok := a.hub.subscribe(7); ok {
+		t.Error("subscribed after Close")
+	}
+}
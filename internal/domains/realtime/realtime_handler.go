@@ -0,0 +1,201 @@
+package realtime
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+type RealtimeHandler interface {
+	CreateTicketHandler(c *gin.Context)
+	TicketMiddleware() gin.HandlerFunc
+	WebSocketHandler(c *gin.Context)
+	EventStreamHandler(c *gin.Context)
+}
+
+type handler struct {
+	gateway *Gateway
+	users   users.UserUsecase
+}
+
+func NewRealtimeHandler(gateway *Gateway, users users.UserUsecase) RealtimeHandler {
+	return &handler{
+		gateway: gateway,
+		users:   users,
+	}
+}
+
+func (h *handler) CreateTicketHandler(c *gin.Context) {
+	t, err := h.gateway.IssueTicket(c, users.GetAuthUserFromContext(c).ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, Ticket{
+		Ticket:    t,
+		ExpiresIn: int(ticketTTL.Seconds()),
+	})
+}
+
+// TicketMiddleware authenticates ?ticket= and leaves requests without one
+// to AuthTokenMiddleware.
+func (h *handler) TicketMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t := c.Query("ticket")
+		if t == "" {
+			c.Next()
+			return
+		}
+
+		userID, err := h.gateway.RedeemTicket(c, t)
+		if err != nil {
+			response.Abort(c, err)
+			return
+		}
+
+		user, err := h.users.GetByID(c, userID)
+		if err != nil {
+			response.Abort(c, commons.ErrInvalidToken.Wrap(err))
+			return
+		}
+
+		c.Set(commons.ContextAuthUserKey, user)
+		c.Next()
+	}
+}
+
+func (h *handler) WebSocketHandler(c *gin.Context) {
+	cl, ok := h.gateway.hub.subscribe(users.GetAuthUserFromContext(c).ID)
+	if !ok {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	defer h.gateway.hub.unsubscribe(cl)
+
+	// the client authenticated with a bearer token or a one-time ticket,
+	// never a cookie, so there is no cross-site origin to check
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxMessageSize
+			serveWebSocket(ws, cl)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func serveWebSocket(ws *websocket.Conn, cl *client) {
+	defer ws.Close()
+
+	// the hijacked connection keeps the server's read deadline
+	if err := ws.SetReadDeadline(time.Time{}); err != nil {
+		return
+	}
+
+	// clients do not send anything, reading only notices them leaving
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var msg string
+		for {
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		var e Event
+		select {
+		case <-gone:
+			return
+		case ev, ok := <-cl.events:
+			if !ok {
+				return
+			}
+			e = ev
+		case <-ticker.C:
+			e = Event{Type: EventHeartbeat, SentAt: time.Now().UTC()}
+		}
+
+		if err := ws.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+			return
+		}
+
+		if err := websocket.JSON.Send(ws, e); err != nil {
+			return
+		}
+	}
+}
+
+func (h *handler) EventStreamHandler(c *gin.Context) {
+	cl, ok := h.gateway.hub.subscribe(users.GetAuthUserFromContext(c).ID)
+	if !ok {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+		return
+	}
+	defer h.gateway.hub.unsubscribe(cl)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// the server WriteTimeout would otherwise cut the stream off
+	rc := http.NewResponseController(c.Writer)
+	extendDeadline := func() bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(heartbeatInterval + writeWait)); err != nil {
+			log.Println("failed to extend event stream deadline:", err)
+			return false
+		}
+		return true
+	}
+
+	if !extendDeadline() {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-cl.events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Type, sseData(e))
+		case <-ticker.C:
+			c.SSEvent(EventHeartbeat, sseData(Event{Type: EventHeartbeat, SentAt: time.Now().UTC()}))
+		}
+
+		return extendDeadline()
+	})
+}
+
+// sseData pre-encodes the event so the stream carries the same JSON as the
+// WebSocket.
+func sseData(e Event) string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package realtime
+
+import (
+	"log"
+	"sync"
+)
+
+type client struct {
+	userID int64
+	events chan Event
+}
+
+// hub keeps the streams connected to this instance. A client whose buffer
+// is full is dropped instead of slowing down delivery to everybody else,
+// it can reconnect and refetch what it missed.
+type hub struct {
+	mu      sync.Mutex
+	clients map[int64]map[*client]struct{}
+	closed  bool
+}
+
+func newHub() *hub {
+	return &hub{clients: make(map[int64]map[*client]struct{})}
+}
+
+func (h *hub) subscribe(userID int64) (*client, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, false
+	}
+
+	c := &client{
+		userID: userID,
+		events: make(chan Event, clientBuffer),
+	}
+
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*client]struct{})
+	}
+	h.clients[userID][c] = struct{}{}
+
+	return c, true
+}
+
+func (h *hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c)
+}
+
+func (h *hub) deliver(userIDs []int64, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range userIDs {
+		for c := range h.clients[id] {
+			select {
+			case c.events <- e:
+			default:
+				log.Println("realtime client too slow, disconnected:", c.userID)
+				h.remove(c)
+			}
+		}
+	}
+}
+
+// close ends every stream, handlers see the closed channel and return.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, set := range h.clients {
+		for c := range set {
+			h.remove(c)
+		}
+	}
+}
+
+// remove must be called with mu held. Closing events tells the handler to
+// hang up.
+func (h *hub) remove(c *client) {
+	set, ok := h.clients[c.userID]
+	if !ok {
+		return
+	}
+
+	if _, ok := set[c]; !ok {
+		return
+	}
+
+	delete(set, c)
+	if len(set) == 0 {
+		delete(h.clients, c.userID)
+	}
+	close(c.events)
+}
@@ -0,0 +1,11 @@
+package search
+
+import "database/sql"
+
+func InitSearchDomain(db *sql.DB) SearchHandler {
+	repo := NewSearchRepository(db)
+	uc := NewSearchUsecase(repo)
+	hdl := NewSearchHandler(uc)
+
+	return hdl
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TypePosts    = "posts"
+	TypeComments = "comments"
+	TypeUsers    = "users"
+
+	SortRelevance = "relevance"
+	SortRecent    = "recent"
+)
+
+type SearchQuery struct {
+	Query  string   `json:"q" binding:"required,max=100"`
+	Type   string   `json:"type" binding:"oneof=posts comments users"`
+	Sort   string   `json:"sort" binding:"oneof=relevance recent"`
+	Tags   []string `json:"tags" binding:"max=5"`
+	Author string   `json:"author" binding:"max=100"`
+	Limit  int      `json:"limit" binding:"gte=1,lte=50"`
+	Offset int      `json:"offset" binding:"gte=0"`
+}
+
+type Hit struct {
+	Type      string   `json:"type"`
+	ID        int64    `json:"id"`
+	PostID    *int64   `json:"post_id,omitempty"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Title     string   `json:"title,omitempty"`
+	Snippet   string   `json:"snippet,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Rank      float64  `json:"rank"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Facets struct {
+	Tags    []Facet `json:"tags"`
+	Authors []Facet `json:"authors"`
+}
+
+type Results struct {
+	Hits   []Hit   `json:"hits"`
+	Facets *Facets `json:"facets,omitempty"`
+}
+
+func (q SearchQuery) Parse(c *gin.Context) (SearchQuery, error) {
+	q.Query = strings.TrimSpace(c.Query("q"))
+
+	if t := c.Query("type"); t != "" {
+		q.Type = t
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		q.Sort = sort
+	}
+
+	if tags := c.Query("tags"); tags != "" {
+		q.Tags = strings.Split(tags, ",")
+	}
+
+	if author := c.Query("author"); author != "" {
+		q.Author = author
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"net/http"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type SearchHandler interface {
+	SearchHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc SearchUsecase
+}
+
+func NewSearchHandler(uc SearchUsecase) SearchHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) SearchHandler(c *gin.Context) {
+	q := SearchQuery{
+		Type:   TypePosts,
+		Sort:   SortRelevance,
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	results, err := h.uc.Search(c, q, users.GetAuthUserFromContext(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, results)
+}
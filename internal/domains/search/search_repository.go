@@ -0,0 +1,255 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/lib/pq"
+)
+
+type SearchRepository interface {
+	SearchPosts(ctx context.Context, q SearchQuery, viewer *users.User) ([]Hit, error)
+	PostFacets(ctx context.Context, q SearchQuery, viewer *users.User) (*Facets, error)
+	SearchComments(ctx context.Context, q SearchQuery, viewer *users.User) ([]Hit, error)
+	SearchUsers(ctx context.Context, tsquery string, q SearchQuery, viewer *users.User) ([]Hit, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) SearchRepository {
+	return &repository{db: db}
+}
+
+// highlight markers are control characters so user content can be escaped
+// before they are turned into <mark> tags.
+const (
+	headlineOptions      = "StartSel=\x02, StopSel=\x03, MaxFragments=2, MaxWords=30, MinWords=10"
+	titleHeadlineOptions = "StartSel=\x02, StopSel=\x03, HighlightAll=true"
+)
+
+// notBlocked filters out rows written by anyone the viewer blocked or who
+// blocked the viewer.
+func notBlocked(column, viewer string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM user_blocks b
+		WHERE (b.blocker_id = ` + viewer + ` AND b.blocked_id = ` + column + `)
+			OR (b.blocker_id = ` + column + ` AND b.blocked_id = ` + viewer + `)
+	)`
+}
+
+// matchingPosts takes $1 query, $2 tags, $3 author, $4 viewer id and
+// $5 whether the viewer is a moderator.
+var matchingPosts = `
+	FROM posts p
+	JOIN users u ON u.id = p.user_id,
+	websearch_to_tsquery('english', $1) query
+	WHERE
+		p.search_vector @@ query AND
+		(p.tags @> $2 OR $2 = '{}') AND
+		($3 = '' OR u.username = $3) AND
+		(p.hidden_at IS NULL OR p.user_id = $4 OR $5) AND
+		p.publish_at IS NULL AND
+		p.deleted_at IS NULL AND
+		` + notBlocked("p.user_id", "$4")
+
+func orderBy(sort, rank, createdAt string) string {
+	if sort == SortRecent {
+		return createdAt + ` DESC`
+	}
+
+	return rank + ` DESC, ` + createdAt + ` DESC`
+}
+
+func (r *repository) SearchPosts(ctx context.Context, q SearchQuery, viewer *users.User) ([]Hit, error) {
+	query := `
+		SELECT
+			p.id, p.user_id, u.username,
+			ts_headline('english', p.title, query, $9),
+			ts_headline('english', p.content, query, $8),
+			p.tags, ts_rank_cd(p.search_vector, query), p.created_at
+	` + matchingPosts + `
+		ORDER BY ` + orderBy(q.Sort, "ts_rank_cd(p.search_vector, query)", "p.created_at") + `
+		LIMIT $6 OFFSET $7
+	`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		q.Query,
+		pq.Array(q.Tags),
+		q.Author,
+		viewer.ID,
+		viewer.IsModerator(),
+		q.Limit,
+		q.Offset,
+		headlineOptions,
+		titleHeadlineOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		hit := Hit{Type: TypePosts}
+		err := rows.Scan(
+			&hit.ID,
+			&hit.UserID,
+			&hit.Username,
+			&hit.Title,
+			&hit.Snippet,
+			pq.Array(&hit.Tags),
+			&hit.Rank,
+			&hit.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+func (r *repository) PostFacets(ctx context.Context, q SearchQuery, viewer *users.User) (*Facets, error) {
+	facets := &Facets{}
+
+	tagQuery := `
+		SELECT t, COUNT(*) FROM (SELECT p.tags ` + matchingPosts + `) m,
+		unnest(m.tags) t
+		GROUP BY t ORDER BY COUNT(*) DESC, t LIMIT 10
+	`
+	authorQuery := `
+		SELECT u.username, COUNT(*) ` + matchingPosts + `
+		GROUP BY u.username ORDER BY COUNT(*) DESC, u.username LIMIT 10
+	`
+
+	var err error
+	args := []any{q.Query, pq.Array(q.Tags), q.Author, viewer.ID, viewer.IsModerator()}
+
+	if facets.Tags, err = r.facet(ctx, tagQuery, args); err != nil {
+		return nil, err
+	}
+
+	if facets.Authors, err = r.facet(ctx, authorQuery, args); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+func (r *repository) facet(ctx context.Context, query string, args []any) ([]Facet, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []Facet{}
+	for rows.Next() {
+		var f Facet
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+
+	return facets, rows.Err()
+}
+
+func (r *repository) SearchComments(ctx context.Context, q SearchQuery, viewer *users.User) ([]Hit, error) {
+	query := `
+		SELECT
+			c.id, c.post_id, c.user_id, u.username,
+			ts_headline('english', c.content, query, $8),
+			ts_rank_cd(c.search_vector, query), c.created_at
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN posts p ON p.id = c.post_id,
+		websearch_to_tsquery('english', $1) query
+		WHERE
+			c.search_vector @@ query AND
+			(p.tags @> $2 OR $2 = '{}') AND
+			($3 = '' OR u.username = $3) AND
+			(c.hidden_at IS NULL OR c.user_id = $4 OR $5) AND
+			(p.hidden_at IS NULL OR p.user_id = $4 OR $5) AND
+			p.publish_at IS NULL AND
+			c.deleted_at IS NULL AND
+			p.deleted_at IS NULL AND
+			` + notBlocked("c.user_id", "$4") + ` AND
+			` + notBlocked("p.user_id", "$4") + `
+		ORDER BY ` + orderBy(q.Sort, "ts_rank_cd(c.search_vector, query)", "c.created_at") + `
+		LIMIT $6 OFFSET $7
+	`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		q.Query,
+		pq.Array(q.Tags),
+		q.Author,
+		viewer.ID,
+		viewer.IsModerator(),
+		q.Limit,
+		q.Offset,
+		headlineOptions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		hit := Hit{Type: TypeComments}
+		err := rows.Scan(
+			&hit.ID,
+			&hit.PostID,
+			&hit.UserID,
+			&hit.Username,
+			&hit.Snippet,
+			&hit.Rank,
+			&hit.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+// SearchUsers matches usernames by prefix, tsquery is already in to_tsquery
+// syntax.
+func (r *repository) SearchUsers(ctx context.Context, tsquery string, q SearchQuery, viewer *users.User) ([]Hit, error) {
+	query := `
+		SELECT u.id, u.username, ts_rank_cd(u.search_vector, query), u.created_at
+		FROM users u, to_tsquery('simple', $1) query
+		WHERE
+			u.search_vector @@ query AND
+			u.is_active = true AND u.banned_at IS NULL AND
+			` + notBlocked("u.id", "$2") + `
+		ORDER BY ` + orderBy(q.Sort, "ts_rank_cd(u.search_vector, query)", "u.created_at") + `
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, tsquery, viewer.ID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		hit := Hit{Type: TypeUsers}
+		if err := rows.Scan(&hit.ID, &hit.Username, &hit.Rank, &hit.CreatedAt); err != nil {
+			return nil, err
+		}
+		hit.UserID = hit.ID
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+type SearchUsecase interface {
+	Search(ctx context.Context, q SearchQuery, viewer *users.User) (*Results, error)
+}
+
+type usecase struct {
+	repo SearchRepository
+}
+
+func NewSearchUsecase(repo SearchRepository) SearchUsecase {
+	return &usecase{repo: repo}
+}
+
+var highlighter = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+func (uc *usecase) Search(ctx context.Context, q SearchQuery, viewer *users.User) (*Results, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	var (
+		results = &Results{}
+		err     error
+	)
+
+	switch q.Type {
+	case TypeComments:
+		results.Hits, err = uc.repo.SearchComments(ctx, q, viewer)
+
+	case TypeUsers:
+		tsquery := prefixQuery(q.Query)
+		if tsquery == "" {
+			results.Hits = []Hit{}
+			return results, nil
+		}
+		results.Hits, err = uc.repo.SearchUsers(ctx, tsquery, q, viewer)
+
+	default:
+		results.Hits, err = uc.repo.SearchPosts(ctx, q, viewer)
+		if err == nil {
+			results.Facets, err = uc.repo.PostFacets(ctx, q, viewer)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results.Hits {
+		results.Hits[i].Title = highlight(results.Hits[i].Title)
+		results.Hits[i].Snippet = highlight(results.Hits[i].Snippet)
+	}
+
+	return results, nil
+}
+
+// highlight escapes user content and turns the ts_headline markers into
+// <mark> tags, so snippets are safe to render as HTML.
+func highlight(s string) string {
+	return highlighter.Replace(html.EscapeString(s))
+}
+
+// prefixQuery turns free text into a to_tsquery prefix match on every word,
+// "jo do" becomes "jo:* & do:*".
+func prefixQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
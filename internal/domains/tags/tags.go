@@ -0,0 +1,15 @@
+package tags
+
+import (
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+)
+
+func InitTagDomain(db *sql.DB, renderer *posts.Renderer) TagHandler {
+	repo := NewTagRepository(db)
+	uc := NewTagUsecase(repo, posts.NewPostRepository(db), renderer)
+	hdl := NewTagHandler(uc)
+
+	return hdl
+}
@@ -0,0 +1,72 @@
+package tags
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trending windows, each is compared with the window right before it
+var windows = map[string]string{
+	"1h":  "1 hour",
+	"24h": "24 hours",
+	"7d":  "7 days",
+}
+
+type TrendingTag struct {
+	Tag      string  `json:"tag"`
+	Posts    int     `json:"posts"`
+	Previous int     `json:"previous"`
+	Score    float64 `json:"score"`
+}
+
+type TagFollow struct {
+	Tag       string `json:"tag"`
+	CreatedAt string `json:"created_at"`
+}
+
+type TagQuery struct {
+	Limit  int `json:"limit" binding:"gte=1,lte=50"`
+	Offset int `json:"offset" binding:"gte=0"`
+}
+
+type TrendingQuery struct {
+	Window string `json:"window" binding:"oneof=1h 24h 7d"`
+	Limit  int    `json:"limit" binding:"gte=1,lte=50"`
+}
+
+func (q TagQuery) Parse(c *gin.Context) (TagQuery, error) {
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	return q, nil
+}
+
+func (q TrendingQuery) Parse(c *gin.Context) (TrendingQuery, error) {
+	if window := c.Query("window"); window != "" {
+		q.Window = window
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	return q, nil
+}
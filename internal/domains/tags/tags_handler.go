@@ -0,0 +1,112 @@
+package tags
+
+import (
+	"net/http"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type TagHandler interface {
+	GetTagPostsHandler(c *gin.Context)
+	TrendingTagsHandler(c *gin.Context)
+	FollowTagHandler(c *gin.Context)
+	UnfollowTagHandler(c *gin.Context)
+	ListFollowedTagsHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc TagUsecase
+}
+
+func NewTagHandler(uc TagUsecase) TagHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) GetTagPostsHandler(c *gin.Context) {
+	q := TagQuery{
+		Limit:  20,
+		Offset: 0,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	result, err := h.uc.GetPosts(c, c.Param("tag"), users.GetAuthUserFromContext(c), q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, result)
+}
+
+func (h *handler) TrendingTagsHandler(c *gin.Context) {
+	q := TrendingQuery{
+		Window: "24h",
+		Limit:  10,
+	}
+
+	q, err := q.Parse(c)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := binding.Validator.ValidateStruct(q); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	trending, err := h.uc.Trending(c, q)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, trending)
+}
+
+func (h *handler) FollowTagHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	if err := h.uc.Follow(c, user.ID, c.Param("tag")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UnfollowTagHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	if err := h.uc.Unfollow(c, user.ID, c.Param("tag")); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) ListFollowedTagsHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	follows, err := h.uc.ListFollows(c, user.ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, follows)
+}
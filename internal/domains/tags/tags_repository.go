@@ -0,0 +1,164 @@
+package tags
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/lib/pq"
+)
+
+type TagRepository interface {
+	GetPosts(ctx context.Context, tag string, viewer *users.User, q TagQuery) ([]posts.PostWithMetaData, error)
+	Trending(ctx context.Context, interval string, limit int) ([]TrendingTag, error)
+	Follow(ctx context.Context, userID int64, tag string) error
+	Unfollow(ctx context.Context, userID int64, tag string) error
+	ListFollows(ctx context.Context, userID int64) ([]TagFollow, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetPosts(ctx context.Context, tag string, viewer *users.User, q TagQuery) ([]posts.PostWithMetaData, error) {
+	query := `
+		SELECT
+			p.id, p.user_id, p.title, p.content, p.format, p.created_at, p.version, p.tags,
+			p.is_quote, p.quoted_post_id, u.username,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts_count,
+			(SELECT COUNT(*) FROM posts q WHERE q.quoted_post_id = p.id AND q.hidden_at IS NULL AND q.publish_at IS NULL AND q.deleted_at IS NULL) AS quotes_count
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		WHERE
+			p.tags @> $1 AND
+			(p.hidden_at IS NULL OR p.user_id = $2 OR $3) AND
+			p.publish_at IS NULL AND
+			p.deleted_at IS NULL AND
+			NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = $2 AND b.blocked_id = p.user_id)
+					OR (b.blocker_id = p.user_id AND b.blocked_id = $2)
+			)
+		ORDER BY p.created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		pq.Array([]string{tag}),
+		viewer.ID,
+		viewer.IsModerator(),
+		q.Limit,
+		q.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []posts.PostWithMetaData{}
+	for rows.Next() {
+		var p posts.PostWithMetaData
+		err := rows.Scan(
+			&p.ID,
+			&p.UserID,
+			&p.Title,
+			&p.Content,
+			&p.Format,
+			&p.CreatedAt,
+			&p.Version,
+			pq.Array(&p.Tags),
+			&p.IsQuote,
+			&p.QuotedPostID,
+			&p.User.Username,
+			&p.CommentsCount,
+			&p.RepostsCount,
+			&p.QuotesCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+
+	return result, rows.Err()
+}
+
+// Trending counts visible posts per tag in the current window and in the
+// window before it. The score favours volume but rewards growth, a tag that
+// is always busy does not stay on top.
+func (r *repository) Trending(ctx context.Context, interval string, limit int) ([]TrendingTag, error) {
+	query := `
+		WITH counts AS (
+			SELECT
+				t AS tag,
+				COUNT(*) FILTER (WHERE p.created_at >= NOW() - $1::interval) AS current,
+				COUNT(*) FILTER (WHERE p.created_at < NOW() - $1::interval) AS previous
+			FROM posts p, unnest(p.tags) t
+			WHERE p.created_at >= NOW() - 2 * $1::interval AND p.hidden_at IS NULL AND p.publish_at IS NULL AND p.deleted_at IS NULL
+			GROUP BY t
+		)
+		SELECT tag, current, previous, current::float * current / (previous + 1) AS score
+		FROM counts
+		WHERE current > 0
+		ORDER BY score DESC, tag
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trending := []TrendingTag{}
+	for rows.Next() {
+		var t TrendingTag
+		if err := rows.Scan(&t.Tag, &t.Posts, &t.Previous, &t.Score); err != nil {
+			return nil, err
+		}
+		trending = append(trending, t)
+	}
+
+	return trending, rows.Err()
+}
+
+func (r *repository) Follow(ctx context.Context, userID int64, tag string) error {
+	query := `INSERT INTO tag_follows (user_id, tag) VALUES ($1, $2)`
+
+	_, err := r.db.ExecContext(ctx, query, userID, tag)
+	return err
+}
+
+func (r *repository) Unfollow(ctx context.Context, userID int64, tag string) error {
+	query := `DELETE FROM tag_follows WHERE user_id = $1 AND tag = $2`
+
+	_, err := r.db.ExecContext(ctx, query, userID, tag)
+	return err
+}
+
+func (r *repository) ListFollows(ctx context.Context, userID int64) ([]TagFollow, error) {
+	query := `SELECT tag, created_at FROM tag_follows WHERE user_id = $1 ORDER BY tag`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []TagFollow{}
+	for rows.Next() {
+		var f TagFollow
+		if err := rows.Scan(&f.Tag, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, f)
+	}
+
+	return follows, rows.Err()
+}
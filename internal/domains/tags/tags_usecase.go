@@ -0,0 +1,100 @@
+package tags
+
+import (
+	"context"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/users"
+)
+
+type TagUsecase interface {
+	GetPosts(ctx context.Context, tag string, viewer *users.User, q TagQuery) ([]posts.PostWithMetaData, error)
+	Trending(ctx context.Context, q TrendingQuery) ([]TrendingTag, error)
+	Follow(ctx context.Context, userID int64, tag string) error
+	Unfollow(ctx context.Context, userID int64, tag string) error
+	ListFollows(ctx context.Context, userID int64) ([]TagFollow, error)
+}
+
+type usecase struct {
+	repo     TagRepository
+	posts    posts.PostRepository
+	renderer *posts.Renderer
+}
+
+func NewTagUsecase(repo TagRepository, posts posts.PostRepository, renderer *posts.Renderer) TagUsecase {
+	return &usecase{
+		repo:     repo,
+		posts:    posts,
+		renderer: renderer,
+	}
+}
+
+func (uc *usecase) GetPosts(ctx context.Context, tag string, viewer *users.User, q TagQuery) ([]posts.PostWithMetaData, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	tag, err := posts.NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := uc.repo.GetPosts(ctx, tag, viewer, q)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*posts.Post, len(result))
+	for i := range result {
+		uc.renderer.Render(&result[i].Post)
+		items[i] = &result[i].Post
+	}
+
+	if err := posts.LoadQuotes(ctx, uc.posts, items...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (uc *usecase) Trending(ctx context.Context, q TrendingQuery) ([]TrendingTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.Trending(ctx, windows[q.Window], q.Limit)
+}
+
+func (uc *usecase) Follow(ctx context.Context, userID int64, tag string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	tag, err := posts.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.repo.Follow(ctx, userID, tag); err != nil {
+		return commons.DBError(err)
+	}
+
+	return nil
+}
+
+func (uc *usecase) Unfollow(ctx context.Context, userID int64, tag string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	tag, err := posts.NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	return uc.repo.Unfollow(ctx, userID, tag)
+}
+
+func (uc *usecase) ListFollows(ctx context.Context, userID int64) ([]TagFollow, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.ListFollows(ctx, userID)
+}
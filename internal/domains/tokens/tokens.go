@@ -0,0 +1,11 @@
+package tokens
+
+import "database/sql"
+
+func InitTokenDomain(db *sql.DB) TokenHandler {
+	repo := NewTokenRepository(db)
+	uc := NewTokenUsecase(repo)
+	hdl := NewTokenHandler(uc)
+
+	return hdl
+}
@@ -0,0 +1,50 @@
+package tokens
+
+import "slices"
+
+const (
+	TokenPrefix = "gsp_"
+
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeFeedRead      = "feed:read"
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+)
+
+var Scopes = []string{
+	ScopePostsRead,
+	ScopePostsWrite,
+	ScopeCommentsWrite,
+	ScopeFeedRead,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+}
+
+type Token struct {
+	ID         int64    `json:"id"`
+	UserID     int64    `json:"user_id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+	Hash       string   `json:"-"`
+}
+
+type TokenWithSecret struct {
+	*Token
+	Secret string `json:"token"`
+}
+
+type CreateTokenPayload struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,gte=1,lte=365"`
+}
+
+func (t *Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
@@ -0,0 +1,71 @@
+package tokens
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type TokenHandler interface {
+	CreateTokenHandler(c *gin.Context)
+	ListTokensHandler(c *gin.Context)
+	RevokeTokenHandler(c *gin.Context)
+}
+
+type handler struct {
+	uc TokenUsecase
+}
+
+func NewTokenHandler(uc TokenUsecase) TokenHandler {
+	return &handler{uc: uc}
+}
+
+func (h *handler) CreateTokenHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	var payload CreateTokenPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	token, err := h.uc.Create(c, user.ID, &payload)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusCreated, token)
+}
+
+func (h *handler) ListTokensHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	tokens, err := h.uc.List(c, user.ID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, tokens)
+}
+
+func (h *handler) RevokeTokenHandler(c *gin.Context) {
+	user := users.GetAuthUserFromContext(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.Revoke(c, user.ID, id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
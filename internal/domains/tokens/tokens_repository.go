@@ -0,0 +1,131 @@
+package tokens
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TokenRepository interface {
+	Create(ctx context.Context, token *Token, expiresAt *time.Time) error
+	ListByUserID(ctx context.Context, userID int64) ([]Token, error)
+	Revoke(ctx context.Context, userID, tokenID int64) error
+	Authenticate(ctx context.Context, hash string) (*Token, error)
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) TokenRepository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, token *Token, expiresAt *time.Time) error {
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, expires_at, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		token.UserID,
+		token.Name,
+		token.Hash,
+		token.Prefix,
+		pq.Array(token.Scopes),
+		expiresAt,
+	).Scan(&token.ID, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) ListByUserID(ctx context.Context, userID int64) ([]Token, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []Token{}
+	for rows.Next() {
+		var t Token
+		err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Name,
+			&t.Prefix,
+			pq.Array(&t.Scopes),
+			&t.ExpiresAt,
+			&t.LastUsedAt,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *repository) Revoke(ctx context.Context, userID, tokenID int64) error {
+	query := `
+		UPDATE api_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Authenticate looks up a live token by hash and records the use in the
+// same statement.
+func (r *repository) Authenticate(ctx context.Context, hash string) (*Token, error) {
+	query := `
+		UPDATE api_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+	`
+	var t Token
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Prefix,
+		pq.Array(&t.Scopes),
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
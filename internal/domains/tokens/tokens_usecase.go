@@ -0,0 +1,121 @@
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+type TokenUsecase interface {
+	Create(ctx context.Context, userID int64, payload *CreateTokenPayload) (*TokenWithSecret, error)
+	List(ctx context.Context, userID int64) ([]Token, error)
+	Revoke(ctx context.Context, userID, tokenID int64) error
+	Authenticate(ctx context.Context, plainToken string) (*Token, error)
+}
+
+type usecase struct {
+	repo TokenRepository
+}
+
+func NewTokenUsecase(repo TokenRepository) TokenUsecase {
+	return &usecase{repo: repo}
+}
+
+func (uc *usecase) Create(ctx context.Context, userID int64, payload *CreateTokenPayload) (*TokenWithSecret, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	for _, scope := range payload.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, commons.ErrInvalidScope
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	plainToken := TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	scopes := slices.Clone(payload.Scopes)
+	slices.Sort(scopes)
+
+	token := &Token{
+		UserID: userID,
+		Name:   payload.Name,
+		Prefix: plainToken[:len(TokenPrefix)+6],
+		Scopes: slices.Compact(scopes),
+		Hash:   hashToken(plainToken),
+	}
+
+	var expiresAt *time.Time
+	if payload.ExpiresInDays > 0 {
+		exp := time.Now().AddDate(0, 0, payload.ExpiresInDays)
+		expiresAt = &exp
+	}
+
+	if err := uc.repo.Create(ctx, token, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &TokenWithSecret{Token: token, Secret: plainToken}, nil
+}
+
+func (uc *usecase) List(ctx context.Context, userID int64) ([]Token, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.ListByUserID(ctx, userID)
+}
+
+func (uc *usecase) Revoke(ctx context.Context, userID, tokenID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := uc.repo.Revoke(ctx, userID, tokenID); err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *usecase) Authenticate(ctx context.Context, plainToken string) (*Token, error) {
+	if !strings.HasPrefix(plainToken, TokenPrefix) {
+		return nil, commons.ErrInvalidToken
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	token, err := uc.repo.Authenticate(ctx, hashToken(plainToken))
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrInvalidToken
+		default:
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
+
+// hashToken returns the hex SHA-256 of the token, as stored in the
+// token_hash column.
+func hashToken(plainToken string) string {
+	hash := sha256.Sum256([]byte(plainToken))
+	return hex.EncodeToString(hash[:])
+}
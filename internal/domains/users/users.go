@@ -4,11 +4,15 @@ import (
 	"database/sql"
 
 	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
 )
 
-func InitUserDomain(db *sql.DB, cfg config.Config) UserHandler {
+func InitUserDomain(db *sql.DB, cfg config.Config, notifier commons.Notifier, mailer mailer.Client, cache UserCache) UserHandler {
 	repo := NewUserRepository(db)
-	uc := NewUserUsecase(db, repo, cfg)
+	auditUC := audit.NewAuditUsecase(audit.NewAuditRepository(db))
+	uc := NewUserUsecase(db, repo, auditUC, notifier, mailer, cache, cfg)
 	hdl := NewUserHandler(uc)
 
 	return hdl
@@ -1,6 +1,12 @@
 package users
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"strconv"
+
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
 
 type User struct {
 	ID        int64  `json:"id"`
@@ -11,6 +17,17 @@ type User struct {
 	IsActive  bool   `json:"is_active"`
 	RoleID    int64  `json:"role_id"`
 	Role      Role   `json:"role"`
+
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatar_url"`
+	Location    string `json:"location"`
+	Website     string `json:"website"`
+
+	BannedAt              *string `json:"banned_at,omitempty"`
+	BanReason             string  `json:"ban_reason,omitempty"`
+	SuspendedUntil        *string `json:"suspended_until,omitempty"`
+	PasswordResetRequired bool    `json:"password_reset_required,omitempty"`
 }
 
 type UserWithToken struct {
@@ -24,6 +41,52 @@ type UserReq struct {
 	Password string `json:"-"`
 }
 
+// UpdateProfilePayload changes only the fields that are set, an empty
+// string clears a field.
+type UpdateProfilePayload struct {
+	DisplayName *string `json:"display_name" binding:"omitempty,max=50"`
+	Bio         *string `json:"bio" binding:"omitempty,max=160"`
+	AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=500"`
+	Location    *string `json:"location" binding:"omitempty,max=50"`
+	Website     *string `json:"website" binding:"omitempty,max=200"`
+}
+
+// Profile is the public view of a user, it never includes the email.
+type Profile struct {
+	ID             int64        `json:"id"`
+	Username       string       `json:"username"`
+	DisplayName    string       `json:"display_name"`
+	Bio            string       `json:"bio"`
+	AvatarURL      string       `json:"avatar_url"`
+	Location       string       `json:"location"`
+	Website        string       `json:"website"`
+	CreatedAt      string       `json:"created_at"`
+	PostsCount     int          `json:"posts_count"`
+	FollowersCount int          `json:"followers_count"`
+	FollowingCount int          `json:"following_count"`
+	PinnedPosts    []PinnedPost `json:"pinned_posts"`
+}
+
+type PinnedPost struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	CreatedAt string `json:"created_at"`
+	PinnedAt  string `json:"pinned_at"`
+}
+
+type ChangeEmailPayload struct {
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+type ChangeUsernamePayload struct {
+	Username string `json:"username" binding:"required,max=100"`
+}
+
+type ResetPasswordPayload struct {
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
 type Role struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
@@ -31,6 +94,14 @@ type Role struct {
 	Level       int    `json:"level"`
 }
 
+type UserQuery struct {
+	Search string `json:"search" binding:"max=100"` // username, email
+	Role   string `json:"role"`
+	Status string `json:"status" binding:"omitempty,oneof=active inactive banned"`
+	Limit  int    `json:"limit" binding:"gte=1,lte=100"`
+	Offset int    `json:"offset" binding:"gte=0"`
+}
+
 type Follower struct {
 	UserID     int64  `json:"user_id"`
 	FollowerID int64  `json:"follower_id"`
@@ -47,6 +118,43 @@ func (u *UserReq) HashPassword(password string) error {
 	return nil
 }
 
+func (q UserQuery) Parse(c *gin.Context) (UserQuery, error) {
+	if limit := c.Query("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = l
+	}
+
+	if offset := c.Query("offset"); offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return q, err
+		}
+		q.Offset = o
+	}
+
+	if search := c.Query("search"); search != "" {
+		q.Search = search
+	}
+
+	if role := c.Query("role"); role != "" {
+		q.Role = role
+	}
+
+	if status := c.Query("status"); status != "" {
+		q.Status = status
+	}
+
+	return q, nil
+}
+
+// IsModerator reports whether the user may see and act on hidden content.
+func (u *User) IsModerator() bool {
+	return u.Role.Level >= commons.RoleLevelStaff
+}
+
 func (u *User) ComparePassword(text string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(text))
 }
@@ -14,9 +14,20 @@ type UserHandler interface {
 	CreateHandler(c *gin.Context)
 	GetByIDHandler(c *gin.Context)
 	ActivateHandler(c *gin.Context)
+	ResetPasswordHandler(c *gin.Context)
 
 	FollowUserHandler(c *gin.Context)
 	UnfollowUserHandler(c *gin.Context)
+	BlockUserHandler(c *gin.Context)
+	UnblockUserHandler(c *gin.Context)
+
+	UpdateProfileHandler(c *gin.Context)
+	GetProfileHandler(c *gin.Context)
+	PinPostHandler(c *gin.Context)
+	UnpinPostHandler(c *gin.Context)
+	ChangeEmailHandler(c *gin.Context)
+	ConfirmEmailHandler(c *gin.Context)
+	ChangeUsernameHandler(c *gin.Context)
 
 	UserContextMiddleware() gin.HandlerFunc
 }
@@ -38,14 +49,7 @@ func (h *handler) CreateHandler(c *gin.Context) {
 
 	user, err := h.uc.Create(c, &payload)
 	if err != nil {
-		switch err {
-		case commons.ErrDuplicateEmail:
-			response.BadRequestResponse(c, err)
-		case commons.ErrDuplicateUsername:
-			response.BadRequestResponse(c, err)
-		default:
-			response.InternalServerError(c, err)
-		}
+		response.Error(c, err)
 		return
 	}
 
@@ -61,13 +65,7 @@ func (h *handler) GetByIDHandler(c *gin.Context) {
 
 	user, err := h.uc.GetByID(c, id)
 	if err != nil {
-		switch err {
-		case commons.ErrNotFound:
-			response.NotFoundResponse(c, err)
-		default:
-			log.Println("Here ??")
-			response.InternalServerError(c, err)
-		}
+		response.Error(c, err)
 		return
 	}
 
@@ -80,74 +78,190 @@ func (h *handler) ActivateHandler(c *gin.Context) {
 	log.Println(token)
 
 	if err := h.uc.Activate(c, token); err != nil {
-		switch err {
-		case commons.ErrNotFound:
-			response.BadRequestResponse(c, err)
-		default:
-			response.InternalServerError(c, err)
-		}
+		response.Error(c, err)
 		return
 	}
 
 	response.ResponseData(c, http.StatusNoContent, nil)
 }
 
-func (h *handler) FollowUserHandler(c *gin.Context) {
-	followerUser := GetUserFromContext(c)
-
-	folleredID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
-	if err != nil {
+func (h *handler) ResetPasswordHandler(c *gin.Context) {
+	var payload ResetPasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		response.BadRequestResponse(c, err)
 		return
 	}
 
-	if err := h.uc.Follow(c, followerUser.ID, folleredID); err != nil {
-		switch err {
-		case commons.ErrConflict:
-			response.BadRequestResponse(c, err)
-			return
-		default:
-			response.InternalServerError(c, err)
-			return
-		}
+	if err := h.uc.ResetPassword(c, c.Param("token"), payload.Password); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) FollowUserHandler(c *gin.Context) {
+	followerUser := GetAuthUserFromContext(c)
+	followedUser := GetUserFromContext(c)
+
+	if err := h.uc.Follow(c, followerUser.ID, followedUser.ID); err != nil {
+		response.Error(c, err)
 	}
 
 	response.ResponseData(c, http.StatusNoContent, nil)
 }
 
 func (h *handler) UnfollowUserHandler(c *gin.Context) {
+	followerUser := GetAuthUserFromContext(c)
 	unfollowedUser := GetUserFromContext(c)
 
-	folleredID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err := h.uc.Unfollow(c, followerUser.ID, unfollowedUser.ID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) BlockUserHandler(c *gin.Context) {
+	blocker := GetAuthUserFromContext(c)
+	blocked := GetUserFromContext(c)
+
+	if err := h.uc.Block(c, blocker.ID, blocked.ID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UnblockUserHandler(c *gin.Context) {
+	blocker := GetAuthUserFromContext(c)
+	blocked := GetUserFromContext(c)
+
+	if err := h.uc.Unblock(c, blocker.ID, blocked.ID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UpdateProfileHandler(c *gin.Context) {
+	var payload UpdateProfilePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.UpdateProfile(c, GetAuthUserFromContext(c), &payload)
 	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
+// GetProfileHandler is public, it does not need an authenticated user.
+func (h *handler) GetProfileHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	profile, err := h.uc.GetProfile(c, id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, profile)
+}
+
+func (h *handler) PinPostHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("postID"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.Pin(c, GetAuthUserFromContext(c).ID, postID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) UnpinPostHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("postID"), 10, 64)
+	if err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	if err := h.uc.Unpin(c, GetAuthUserFromContext(c).ID, postID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusNoContent, nil)
+}
+
+func (h *handler) ChangeEmailHandler(c *gin.Context) {
+	var payload ChangeEmailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
 		response.BadRequestResponse(c, err)
 		return
 	}
 
-	if err := h.uc.Unfollow(c, unfollowedUser.ID, folleredID); err != nil {
-		response.InternalServerError(c, err)
+	if err := h.uc.RequestEmailChange(c, GetAuthUserFromContext(c), payload.Email, payload.Password); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusAccepted, nil)
+}
+
+func (h *handler) ConfirmEmailHandler(c *gin.Context) {
+	if err := h.uc.ConfirmEmailChange(c, c.Param("token")); err != nil {
+		response.Error(c, err)
 		return
 	}
 
 	response.ResponseData(c, http.StatusNoContent, nil)
 }
 
+func (h *handler) ChangeUsernameHandler(c *gin.Context) {
+	var payload ChangeUsernamePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		response.BadRequestResponse(c, err)
+		return
+	}
+
+	user, err := h.uc.ChangeUsername(c, GetAuthUserFromContext(c), payload.Username)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.ResponseData(c, http.StatusOK, user)
+}
+
 func (h *handler) UserContextMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 		if err != nil {
 			response.BadRequestResponse(c, err)
+			c.Abort()
 			return
 		}
 
 		user, err := h.uc.GetByID(c, id)
 		if err != nil {
-			switch err {
-			case commons.ErrNotFound:
-				response.NotFoundResponse(c, err)
-			default:
-				response.InternalServerError(c, err)
-			}
+			response.Abort(c, err)
 			return
 		}
 
@@ -160,3 +274,8 @@ func GetUserFromContext(c *gin.Context) *User {
 	user, _ := c.Get(commons.ContextUserKey)
 	return user.(*User)
 }
+
+func GetAuthUserFromContext(c *gin.Context) *User {
+	user, _ := c.Get(commons.ContextAuthUserKey)
+	return user.(*User)
+}
@@ -12,14 +12,40 @@ import (
 
 type UserRepository interface {
 	Create(ctx context.Context, tx *sql.Tx, user *User) error
-	Activate(ctx context.Context, token string) error
+	Activate(ctx context.Context, tx *sql.Tx, token string) (*User, error)
 	CreateAndInvite(ctx context.Context, user *User, token string, exp time.Duration) error
 	GetByID(ctx context.Context, id int64) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
-	Delete(ctx context.Context, userID int64) error
+	Delete(ctx context.Context, tx *sql.Tx, userID int64) error
+	GetRoleByName(ctx context.Context, name string) (*Role, error)
+
+	List(ctx context.Context, q UserQuery) ([]User, error)
+	GetAccount(ctx context.Context, id int64) (*User, error)
+	UpdateRole(ctx context.Context, tx *sql.Tx, userID int64, roleName string) error
+	SetActive(ctx context.Context, tx *sql.Tx, userID int64, active bool) error
+	SetBanned(ctx context.Context, tx *sql.Tx, userID int64, banned bool, reason string) error
+	SetSuspended(ctx context.Context, tx *sql.Tx, userID int64, until *time.Time) error
+	CreatePasswordReset(ctx context.Context, tx *sql.Tx, userID int64, token string, exp time.Duration) error
+	ResetPassword(ctx context.Context, tx *sql.Tx, token, password string) (*User, error)
 
 	Follow(ctx context.Context, followerID, userID int64) error
 	Unfollow(ctx context.Context, followerID, userID int64) error
+	Block(ctx context.Context, tx *sql.Tx, blockerID, userID int64) error
+	Unblock(ctx context.Context, blockerID, userID int64) error
+	IsBlocked(ctx context.Context, userID, otherID int64) (bool, error)
+
+	UpdateProfile(ctx context.Context, user *User) error
+	GetProfile(ctx context.Context, id int64) (*Profile, error)
+	ListPinned(ctx context.Context, userID int64) ([]PinnedPost, error)
+	Pin(ctx context.Context, tx *sql.Tx, userID, postID int64, max int) error
+	Unpin(ctx context.Context, userID, postID int64) error
+
+	CreateEmailChange(ctx context.Context, tx *sql.Tx, userID int64, email, token string, exp time.Duration) error
+	ConfirmEmailChange(ctx context.Context, tx *sql.Tx, token string) (user *User, oldEmail string, err error)
+	LockUsername(ctx context.Context, tx *sql.Tx, userID int64) (username string, changedAt *time.Time, err error)
+	IsUsernameReserved(ctx context.Context, tx *sql.Tx, username string, userID int64) (bool, error)
+	SetUsername(ctx context.Context, tx *sql.Tx, userID int64, username string) error
+	ReserveUsername(ctx context.Context, tx *sql.Tx, userID int64, username string, until time.Time) error
 }
 
 type repository struct {
@@ -30,23 +56,37 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &repository{db: db}
 }
 
+// Create returns commons.ErrDuplicateUsername when the username is taken or
+// reserved and commons.ErrDuplicateEmail when the email is taken.
 func (r *repository) Create(ctx context.Context, tx *sql.Tx, user *User) error {
 	query := `
-		INSERT INTO users (username, email, password, role_id)
-		VALUES ($1, $2, $3, (SELECT id FROM roles WHERE name = $4)) 
+		INSERT INTO users (username, email, password, role_id, is_active)
+		SELECT $1, $2, $3, (SELECT id FROM roles WHERE name = $4), $5
+		WHERE NOT EXISTS (
+			SELECT 1 FROM username_reservations WHERE username = $1 AND expires_at > NOW()
+		)
 		RETURNING id, created_at
 	`
-	err := r.db.QueryRowContext(
+	queryRow := r.db.QueryRowContext
+	if tx != nil {
+		queryRow = tx.QueryRowContext
+	}
+
+	err := queryRow(
 		ctx,
 		query,
 		user.Username,
 		user.Email,
 		user.Password,
 		user.Role.Name,
+		user.IsActive,
 	).Scan(&user.ID, &user.CreatedAt)
 
-	if err != nil {
-		return err
+	switch {
+	case err == sql.ErrNoRows:
+		return commons.ErrDuplicateUsername
+	case err != nil:
+		return commons.DBError(err)
 	}
 
 	return nil
@@ -79,27 +119,25 @@ func (r *repository) createUserInvitation(ctx context.Context, tx *sql.Tx, token
 	return nil
 }
 
-func (r *repository) Activate(ctx context.Context, token string) error {
-	return commons.WithTransaction(ctx, r.db, func(tx *sql.Tx) error {
-		// find user token
-		user, err := r.getUserFromInvitation(ctx, tx, token)
-		if err != nil {
-			return err
-		}
+func (r *repository) Activate(ctx context.Context, tx *sql.Tx, token string) (*User, error) {
+	// find user token
+	user, err := r.getUserFromInvitation(ctx, tx, token)
+	if err != nil {
+		return nil, err
+	}
 
-		// update user
-		user.IsActive = true
-		if err := r.update(ctx, tx, user); err != nil {
-			return err
-		}
+	// update user
+	user.IsActive = true
+	if err := r.update(ctx, tx, user); err != nil {
+		return nil, err
+	}
 
-		// clean invitations
-		if err := r.deleteUserInvitations(ctx, tx, user.ID); err != nil {
-			return err
-		}
+	// clean invitations
+	if err := r.deleteUserInvitations(ctx, tx, user.ID); err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	return user, nil
 }
 
 // Acticate Method
@@ -124,7 +162,7 @@ func (r *repository) getUserFromInvitation(ctx context.Context, tx *sql.Tx, toke
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
-			return nil, commons.ErrNotFound
+			return nil, commons.ErrInvalidLink
 		default:
 			return nil, err
 		}
@@ -159,10 +197,13 @@ func (r *repository) deleteUserInvitations(ctx context.Context, tx *sql.Tx, user
 
 func (r *repository) GetByID(ctx context.Context, id int64) (*User, error) {
 	query := `
-		SELECT users.id, username, email, password, created_at, roles.* 
+		SELECT
+			users.id, username, email, password, created_at,
+			display_name, bio, avatar_url, location, website, roles.*
 		FROM users
 		JOIN roles ON (users.role_id = roles.id)
-		WHERE users.id = $1 AND is_active = true
+		WHERE users.id = $1 AND is_active = true AND banned_at IS NULL AND
+			(suspended_until IS NULL OR suspended_until <= NOW())
 	`
 	var user User
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -171,6 +212,11 @@ func (r *repository) GetByID(ctx context.Context, id int64) (*User, error) {
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
+		&user.DisplayName,
+		&user.Bio,
+		&user.AvatarURL,
+		&user.Location,
+		&user.Website,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.Level,
@@ -186,8 +232,9 @@ func (r *repository) GetByID(ctx context.Context, id int64) (*User, error) {
 
 func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password, created_at FROM users
-		WHERE email = $1 AND is_active = true
+		SELECT id, username, email, password, created_at, password_reset_required FROM users
+		WHERE email = $1 AND is_active = true AND banned_at IS NULL AND
+			(suspended_until IS NULL OR suspended_until <= NOW())
 	`
 	var user User
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
@@ -196,6 +243,7 @@ func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error
 		&user.Email,
 		&user.Password,
 		&user.CreatedAt,
+		&user.PasswordResetRequired,
 	)
 	if err != nil {
 		return nil, err
@@ -204,18 +252,27 @@ func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error
 	return &user, nil
 }
 
-func (r *repository) Delete(ctx context.Context, userID int64) error {
-	return commons.WithTransaction(ctx, r.db, func(tx *sql.Tx) error {
-		if err := r.delete(ctx, tx, userID); err != nil {
-			return err
-		}
+// Delete removes the user with their posts, comments, follows and
+// invitations. Comments have no foreign keys, so they are removed here,
+// everything else cascades.
+func (r *repository) Delete(ctx context.Context, tx *sql.Tx, userID int64) error {
+	query := `
+		DELETE FROM comments
+		WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)
+	`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
 
-		if err := r.deleteUserInvitations(ctx, tx, userID); err != nil {
-			return err
-		}
+	if err := r.delete(ctx, tx, userID); err != nil {
+		return err
+	}
 
-		return nil
-	})
+	if err := r.deleteUserInvitations(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (r *repository) delete(ctx context.Context, tx *sql.Tx, userID int64) error {
@@ -227,6 +284,191 @@ func (r *repository) delete(ctx context.Context, tx *sql.Tx, userID int64) error
 	return nil
 }
 
+func (r *repository) GetRoleByName(ctx context.Context, name string) (*Role, error) {
+	query := `SELECT id, name, level, description FROM roles WHERE name = $1`
+
+	var role Role
+	err := r.db.QueryRowContext(ctx, query, name).Scan(
+		&role.ID,
+		&role.Name,
+		&role.Level,
+		&role.Description,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func (r *repository) List(ctx context.Context, q UserQuery) ([]User, error) {
+	query := `
+		SELECT
+			u.id, u.username, u.email, u.created_at, u.is_active,
+			u.banned_at, u.ban_reason, u.suspended_until, u.password_reset_required,
+			r.id, r.name, r.level, r.description
+		FROM users u
+		JOIN roles r ON (u.role_id = r.id)
+		WHERE
+			(u.username ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%') AND
+			($2 = '' OR r.name = $2) AND
+			(
+				$3 = '' OR
+				($3 = 'active' AND u.is_active = true AND u.banned_at IS NULL) OR
+				($3 = 'inactive' AND u.is_active = false) OR
+				($3 = 'banned' AND u.banned_at IS NOT NULL)
+			)
+		ORDER BY u.id ASC
+		LIMIT $4 OFFSET $5
+	`
+	rows, err := r.db.QueryContext(ctx, query, q.Search, q.Role, q.Status, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := scanAccount(rows, &user); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *repository) GetAccount(ctx context.Context, id int64) (*User, error) {
+	query := `
+		SELECT
+			u.id, u.username, u.email, u.created_at, u.is_active,
+			u.banned_at, u.ban_reason, u.suspended_until, u.password_reset_required,
+			r.id, r.name, r.level, r.description
+		FROM users u
+		JOIN roles r ON (u.role_id = r.id)
+		WHERE u.id = $1
+	`
+	var user User
+	if err := scanAccount(r.db.QueryRowContext(ctx, query, id), &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func scanAccount(row interface{ Scan(...any) error }, user *User) error {
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.CreatedAt,
+		&user.IsActive,
+		&user.BannedAt,
+		&user.BanReason,
+		&user.SuspendedUntil,
+		&user.PasswordResetRequired,
+		&user.Role.ID,
+		&user.Role.Name,
+		&user.Role.Level,
+		&user.Role.Description,
+	)
+	user.RoleID = user.Role.ID
+
+	return err
+}
+
+func (r *repository) UpdateRole(ctx context.Context, tx *sql.Tx, userID int64, roleName string) error {
+	query := `UPDATE users SET role_id = (SELECT id FROM roles WHERE name = $1) WHERE id = $2`
+
+	return execAffectingOne(ctx, tx, query, roleName, userID)
+}
+
+func (r *repository) SetActive(ctx context.Context, tx *sql.Tx, userID int64, active bool) error {
+	query := `UPDATE users SET is_active = $1 WHERE id = $2`
+
+	return execAffectingOne(ctx, tx, query, active, userID)
+}
+
+func (r *repository) SetBanned(ctx context.Context, tx *sql.Tx, userID int64, banned bool, reason string) error {
+	query := `
+		UPDATE users SET
+			banned_at = CASE WHEN $1 THEN NOW() ELSE NULL END,
+			ban_reason = $2
+		WHERE id = $3
+	`
+
+	return execAffectingOne(ctx, tx, query, banned, reason, userID)
+}
+
+func (r *repository) SetSuspended(ctx context.Context, tx *sql.Tx, userID int64, until *time.Time) error {
+	query := `UPDATE users SET suspended_until = $1 WHERE id = $2`
+
+	return execAffectingOne(ctx, tx, query, until, userID)
+}
+
+func (r *repository) CreatePasswordReset(ctx context.Context, tx *sql.Tx, userID int64, token string, exp time.Duration) error {
+	if err := execAffectingOne(ctx, tx, `UPDATE users SET password_reset_required = true WHERE id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM password_resets WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO password_resets (token, user_id, expiry) VALUES ($1, $2, $3)`
+
+	_, err := tx.ExecContext(ctx, query, token, userID, time.Now().Add(exp))
+	return err
+}
+
+func (r *repository) ResetPassword(ctx context.Context, tx *sql.Tx, token, password string) (*User, error) {
+	query := `
+		DELETE FROM password_resets
+		WHERE token = $1 AND expiry > $2
+		RETURNING user_id
+	`
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+
+	var user User
+	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(&user.ID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrInvalidLink
+		default:
+			return nil, err
+		}
+	}
+
+	query = `UPDATE users SET password = $1, password_reset_required = false WHERE id = $2`
+	if err := execAffectingOne(ctx, tx, query, password, user.ID); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func execAffectingOne(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 func (r *repository) Follow(ctx context.Context, followerID, userID int64) error {
 	query := `INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)`
 
@@ -244,3 +486,259 @@ func (r *repository) Unfollow(ctx context.Context, followerID, userID int64) err
 	_, err := r.db.ExecContext(ctx, query, userID, followerID)
 	return err
 }
+
+func (r *repository) Block(ctx context.Context, tx *sql.Tx, blockerID, userID int64) error {
+	query := `
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, query, blockerID, userID); err != nil {
+		return err
+	}
+
+	// a block ends the follow relationship in both directions
+	query = `
+		DELETE FROM followers
+		WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1)
+	`
+	_, err := tx.ExecContext(ctx, query, blockerID, userID)
+	return err
+}
+
+func (r *repository) Unblock(ctx context.Context, blockerID, userID int64) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, blockerID, userID)
+	return err
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (r *repository) IsBlocked(ctx context.Context, userID, otherID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`
+	var blocked bool
+	err := r.db.QueryRowContext(ctx, query, userID, otherID).Scan(&blocked)
+	return blocked, err
+}
+
+func (r *repository) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+		UPDATE users SET display_name = $1, bio = $2, avatar_url = $3, location = $4, website = $5
+		WHERE id = $6
+	`
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		user.DisplayName,
+		user.Bio,
+		user.AvatarURL,
+		user.Location,
+		user.Website,
+		user.ID,
+	)
+	return err
+}
+
+// GetProfile counts only published posts, hidden and scheduled ones are
+// not part of the public profile.
+func (r *repository) GetProfile(ctx context.Context, id int64) (*Profile, error) {
+	query := `
+		SELECT
+			u.id, u.username, u.display_name, u.bio, u.avatar_url, u.location, u.website, u.created_at,
+			(SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.hidden_at IS NULL AND p.publish_at IS NULL AND p.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id),
+			(SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id)
+		FROM users u
+		WHERE u.id = $1 AND u.is_active = true AND u.banned_at IS NULL AND u.delete_after IS NULL
+	`
+	var p Profile
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&p.ID,
+		&p.Username,
+		&p.DisplayName,
+		&p.Bio,
+		&p.AvatarURL,
+		&p.Location,
+		&p.Website,
+		&p.CreatedAt,
+		&p.PostsCount,
+		&p.FollowersCount,
+		&p.FollowingCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *repository) ListPinned(ctx context.Context, userID int64) ([]PinnedPost, error) {
+	query := `
+		SELECT p.id, p.title, p.created_at, pp.pinned_at
+		FROM pinned_posts pp
+		JOIN posts p ON p.id = pp.post_id
+		WHERE pp.user_id = $1 AND p.hidden_at IS NULL AND p.publish_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY pp.pinned_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pinned := []PinnedPost{}
+	for rows.Next() {
+		var p PinnedPost
+		if err := rows.Scan(&p.ID, &p.Title, &p.CreatedAt, &p.PinnedAt); err != nil {
+			return nil, err
+		}
+		pinned = append(pinned, p)
+	}
+
+	return pinned, rows.Err()
+}
+
+// Pin pins one of the user's own published posts. It returns sql.ErrNoRows
+// when there is no such post and commons.ErrPinLimit when max posts are
+// already pinned. Pinning a pinned post again is a no-op.
+func (r *repository) Pin(ctx context.Context, tx *sql.Tx, userID, postID int64, max int) error {
+	// concurrent pins by the same user queue up here, so the count below
+	// cannot go over max
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM pinned_posts WHERE user_id = $1 AND post_id = $2),
+			-- a deleted post does not take up a slot while it waits for the purge
+			(SELECT COUNT(*) FROM pinned_posts pp JOIN posts p ON p.id = pp.post_id WHERE pp.user_id = $1 AND p.deleted_at IS NULL)
+		FROM posts
+		WHERE id = $2 AND user_id = $1 AND hidden_at IS NULL AND publish_at IS NULL AND deleted_at IS NULL
+	`
+	var (
+		pinned bool
+		count  int
+	)
+	if err := tx.QueryRowContext(ctx, query, userID, postID).Scan(&pinned, &count); err != nil {
+		return err
+	}
+
+	if pinned {
+		return nil
+	}
+
+	if count >= max {
+		return commons.ErrPinLimit
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO pinned_posts (user_id, post_id) VALUES ($1, $2)`, userID, postID)
+	return err
+}
+
+func (r *repository) Unpin(ctx context.Context, userID, postID int64) error {
+	query := `DELETE FROM pinned_posts WHERE user_id = $1 AND post_id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, userID, postID)
+	return err
+}
+
+// CreateEmailChange replaces any pending email change of userID.
+func (r *repository) CreateEmailChange(ctx context.Context, tx *sql.Tx, userID int64, email, token string, exp time.Duration) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO email_changes (token, user_id, email, expiry) VALUES ($1, $2, $3, $4)`
+
+	_, err := tx.ExecContext(ctx, query, token, userID, email, time.Now().Add(exp))
+	return err
+}
+
+// ConfirmEmailChange consumes token and moves its user to the new email.
+// It returns commons.ErrInvalidLink for an unknown or expired token.
+func (r *repository) ConfirmEmailChange(ctx context.Context, tx *sql.Tx, token string) (*User, string, error) {
+	query := `
+		DELETE FROM email_changes
+		WHERE token = $1 AND expiry > $2
+		RETURNING user_id, email
+	`
+	hash := sha256.Sum256([]byte(token))
+	hashToken := hex.EncodeToString(hash[:])
+
+	var user User
+	err := tx.QueryRowContext(ctx, query, hashToken, time.Now()).Scan(&user.ID, &user.Email)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, "", commons.ErrInvalidLink
+		default:
+			return nil, "", err
+		}
+	}
+
+	var oldEmail string
+	query = `SELECT username, email FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, user.ID).Scan(&user.Username, &oldEmail); err != nil {
+		return nil, "", err
+	}
+
+	if err := execAffectingOne(ctx, tx, `UPDATE users SET email = $1 WHERE id = $2`, user.Email, user.ID); err != nil {
+		return nil, "", commons.DBError(err)
+	}
+
+	return &user, oldEmail, nil
+}
+
+// LockUsername locks the user row until tx ends so concurrent username
+// changes of the same user run one at a time.
+func (r *repository) LockUsername(ctx context.Context, tx *sql.Tx, userID int64) (string, *time.Time, error) {
+	query := `SELECT username, username_changed_at FROM users WHERE id = $1 FOR UPDATE`
+
+	var (
+		username  string
+		changedAt *time.Time
+	)
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&username, &changedAt)
+	return username, changedAt, err
+}
+
+// IsUsernameReserved reports whether username is held for a user other
+// than userID.
+func (r *repository) IsUsernameReserved(ctx context.Context, tx *sql.Tx, username string, userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM username_reservations
+			WHERE username = $1 AND user_id <> $2 AND expires_at > NOW()
+		)
+	`
+	var reserved bool
+	err := tx.QueryRowContext(ctx, query, username, userID).Scan(&reserved)
+	return reserved, err
+}
+
+// SetUsername also releases the user's own reservation of username, so an
+// old handle can be taken back.
+func (r *repository) SetUsername(ctx context.Context, tx *sql.Tx, userID int64, username string) error {
+	query := `UPDATE users SET username = $1, username_changed_at = NOW() WHERE id = $2`
+	if err := execAffectingOne(ctx, tx, query, username, userID); err != nil {
+		return commons.DBError(err)
+	}
+
+	query = `DELETE FROM username_reservations WHERE username = $1 AND user_id = $2`
+	_, err := tx.ExecContext(ctx, query, username, userID)
+	return err
+}
+
+func (r *repository) ReserveUsername(ctx context.Context, tx *sql.Tx, userID int64, username string, until time.Time) error {
+	query := `
+		INSERT INTO username_reservations (username, user_id, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (username) DO UPDATE SET user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at
+	`
+	_, err := tx.ExecContext(ctx, query, username, userID, until)
+	return err
+}
@@ -2,14 +2,32 @@ package users
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
 	"github.com/codepnw/gopher-social/internal/domains/commons"
-	"github.com/lib/pq"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+	"github.com/google/uuid"
 )
 
+// usernamePattern is what the mention parser matches after the @, a handle
+// outside it could never be mentioned.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+(?:[.-][A-Za-z0-9_]+)*$`)
+
+// UserCache drops cached accounts once they change.
+type UserCache interface {
+	Delete(ctx context.Context, userID int64) error
+}
+
 type UserUsecase interface {
 	Create(ctx context.Context, user *UserReq) (*User, error)
 	Activate(ctx context.Context, token string) error
@@ -18,19 +36,43 @@ type UserUsecase interface {
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	Follow(ctx context.Context, followerID, userID int64) error
 	Unfollow(ctx context.Context, followerID, userID int64) error
-	Delete(ctx context.Context, userID int64) error
+	Block(ctx context.Context, blockerID, userID int64) error
+	Unblock(ctx context.Context, blockerID, userID int64) error
+	Delete(ctx context.Context, actorID, userID int64) error
+	GetRoleByName(ctx context.Context, name string) (*Role, error)
+	ResetPassword(ctx context.Context, token, password string) error
+
+	UpdateProfile(ctx context.Context, user *User, payload *UpdateProfilePayload) (*User, error)
+	GetProfile(ctx context.Context, id int64) (*Profile, error)
+	Pin(ctx context.Context, userID, postID int64) error
+	Unpin(ctx context.Context, userID, postID int64) error
+
+	RequestEmailChange(ctx context.Context, user *User, email, password string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	ChangeUsername(ctx context.Context, user *User, username string) (*User, error)
 }
 
 type usecase struct {
-	db     *sql.DB
-	repo   UserRepository
-	config config.Config
+	db       *sql.DB
+	repo     UserRepository
+	audit    audit.AuditUsecase
+	notifier commons.Notifier
+	mailer   mailer.Client
+	cache    UserCache
+	config   config.Config
 }
 
-func NewUserUsecase(db *sql.DB, repo UserRepository, config config.Config) UserUsecase {
+// NewUserUsecase accepts a nil notifier, mailer and cache for callers that
+// never follow users or change emails and usernames.
+func NewUserUsecase(db *sql.DB, repo UserRepository, audit audit.AuditUsecase, notifier commons.Notifier, mailer mailer.Client, cache UserCache, config config.Config) UserUsecase {
 	return &usecase{
-		repo:   repo,
-		config: config,
+		db:       db,
+		repo:     repo,
+		audit:    audit,
+		notifier: notifier,
+		mailer:   mailer,
+		cache:    cache,
+		config:   config,
 	}
 }
 
@@ -54,18 +96,8 @@ func (uc *usecase) Create(ctx context.Context, user *UserReq) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	tx, _ := uc.db.BeginTx(ctx, nil)
-
-	err := uc.repo.Create(ctx, tx, &u)
-	if err != nil {
-		switch {
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
-			return nil, commons.ErrDuplicateEmail
-		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
-			return nil, commons.ErrDuplicateUsername
-		default:
-			return nil, err
-		}
+	if err := uc.repo.Create(ctx, nil, &u); err != nil {
+		return nil, err
 	}
 
 	return &u, nil
@@ -75,7 +107,22 @@ func (uc *usecase) Activate(ctx context.Context, token string) error {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	return uc.repo.Activate(ctx, token)
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		user, err := uc.repo.Activate(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserActivate,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+			Diff: map[string]audit.Change{
+				"is_active": {From: false, To: true},
+			},
+		})
+	})
 }
 
 func (uc *usecase) CreateAndInvite(ctx context.Context, user *UserReq, token string, exp time.Duration) error {
@@ -133,11 +180,39 @@ func (uc *usecase) GetByEmail(ctx context.Context, email string) (*User, error)
 	return user, nil
 }
 
-func (uc *usecase) Delete(ctx context.Context, userID int64) error {
+func (uc *usecase) Delete(ctx context.Context, actorID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		if err := uc.repo.Delete(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(actorID),
+			Action:     audit.ActionUserDelete,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(userID),
+		})
+	})
+}
+
+func (uc *usecase) GetRoleByName(ctx context.Context, name string) (*Role, error) {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
-	return uc.repo.Delete(ctx, userID)
+	role, err := uc.repo.GetRoleByName(ctx, name)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return role, nil
 }
 
 func (uc *usecase) Follow(ctx context.Context, followerID, userID int64) error {
@@ -145,16 +220,345 @@ func (uc *usecase) Follow(ctx context.Context, followerID, userID int64) error {
 	defer cancel()
 
 	if err := uc.repo.Follow(ctx, followerID, userID); err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return commons.ErrConflict
-		}
+		return commons.DBError(err)
+	}
+
+	if uc.notifier != nil {
+		uc.notifier.Notify(commons.NotifyEvent{
+			UserID:     userID,
+			ActorID:    followerID,
+			Type:       commons.NotifyFollow,
+			TargetType: "user",
+			TargetID:   userID,
+		})
 	}
 
 	return nil
 }
+
 func (uc *usecase) Unfollow(ctx context.Context, followerID, userID int64) error {
 	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
 	defer cancel()
 
 	return uc.repo.Unfollow(ctx, followerID, userID)
 }
+
+func (uc *usecase) Block(ctx context.Context, blockerID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if blockerID == userID {
+		return commons.ErrForbidden
+	}
+
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.repo.Block(ctx, tx, blockerID, userID)
+	})
+}
+
+func (uc *usecase) Unblock(ctx context.Context, blockerID, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.Unblock(ctx, blockerID, userID)
+}
+
+func (uc *usecase) ResetPassword(ctx context.Context, token, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	req := &UserReq{}
+	if err := req.HashPassword(password); err != nil {
+		return err
+	}
+
+	return commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		user, err := uc.repo.ResetPassword(ctx, tx, token, req.Password)
+		if err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserPasswordReset,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+		})
+	})
+}
+
+func (uc *usecase) UpdateProfile(ctx context.Context, user *User, payload *UpdateProfilePayload) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	u := *user
+
+	setField(&u.DisplayName, payload.DisplayName)
+	setField(&u.Bio, payload.Bio)
+	setField(&u.AvatarURL, payload.AvatarURL)
+	setField(&u.Location, payload.Location)
+	setField(&u.Website, payload.Website)
+
+	if err := checkProfileURL("avatar_url", u.AvatarURL); err != nil {
+		return nil, err
+	}
+
+	if err := checkProfileURL("website", u.Website); err != nil {
+		return nil, err
+	}
+
+	if err := uc.repo.UpdateProfile(ctx, &u); err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (uc *usecase) GetProfile(ctx context.Context, id int64) (*Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	profile, err := uc.repo.GetProfile(ctx, id)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, commons.ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	profile.PinnedPosts, err = uc.repo.ListPinned(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (uc *usecase) Pin(ctx context.Context, userID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.repo.Pin(ctx, tx, userID, postID, uc.config.Users.MaxPinnedPosts)
+	})
+	if err == sql.ErrNoRows {
+		return commons.ErrNotFound
+	}
+
+	return err
+}
+
+func (uc *usecase) Unpin(ctx context.Context, userID, postID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	return uc.repo.Unpin(ctx, userID, postID)
+}
+
+// RequestEmailChange mails a confirmation link to the new address, the
+// email only changes once the link is used.
+func (uc *usecase) RequestEmailChange(ctx context.Context, user *User, email, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	if err := CheckPassword(ctx, uc.repo, user.ID, password); err != nil {
+		return err
+	}
+
+	email = strings.TrimSpace(email)
+	if strings.EqualFold(email, user.Email) {
+		return commons.ErrDuplicateEmail
+	}
+
+	plainToken := uuid.New().String()
+
+	hash := sha256.Sum256([]byte(plainToken))
+	hashToken := hex.EncodeToString(hash[:])
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		return uc.repo.CreateEmailChange(ctx, tx, user.ID, email, hashToken, uc.config.Mail.Exp)
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.sendMail(mailer.EmailChangeTemplate, user.Username, email, map[string]string{
+		"Username":   user.Username,
+		"Email":      email,
+		"ConfirmURL": uc.config.App.FrontendURL + "/confirm-email/" + plainToken,
+	})
+}
+
+// ConfirmEmailChange switches to the new email and tells the old address
+// about it.
+func (uc *usecase) ConfirmEmailChange(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	var (
+		user     *User
+		oldEmail string
+	)
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		var err error
+		user, oldEmail, err = uc.repo.ConfirmEmailChange(ctx, tx, token)
+		if err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserEmailChange,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+			Diff: map[string]audit.Change{
+				"email": {From: oldEmail, To: user.Email},
+			},
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.evict(ctx, user.ID)
+
+	// the change is done, a failed notice must not fail the request
+	err = uc.sendMail(mailer.EmailChangedTemplate, user.Username, oldEmail, map[string]string{
+		"Username": user.Username,
+		"Email":    user.Email,
+	})
+	if err != nil {
+		log.Println("failed to send email change notice:", user.ID, err)
+	}
+
+	return nil
+}
+
+// ChangeUsername keeps the old username reserved for the user for a while,
+// so links and mentions are not taken over by someone else.
+func (uc *usecase) ChangeUsername(ctx context.Context, user *User, username string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, commons.ContextQueryTimeout)
+	defer cancel()
+
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: a username is letters, digits and _, joined by single . or -", commons.ErrInvalidValue)
+	}
+
+	err := commons.WithTransaction(ctx, uc.db, func(tx *sql.Tx) error {
+		current, changedAt, err := uc.repo.LockUsername(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if current == username {
+			return nil
+		}
+
+		if changedAt != nil && time.Since(*changedAt) < uc.config.Users.UsernameCooldown {
+			return commons.ErrUsernameCooldown
+		}
+
+		reserved, err := uc.repo.IsUsernameReserved(ctx, tx, username, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if reserved {
+			return commons.ErrDuplicateUsername
+		}
+
+		if err := uc.repo.SetUsername(ctx, tx, user.ID, username); err != nil {
+			return err
+		}
+
+		until := time.Now().Add(uc.config.Users.UsernameReservation)
+		if err := uc.repo.ReserveUsername(ctx, tx, user.ID, current, until); err != nil {
+			return err
+		}
+
+		return uc.audit.Record(ctx, tx, &audit.Event{
+			ActorID:    audit.ID(user.ID),
+			Action:     audit.ActionUserRename,
+			TargetType: audit.TargetUser,
+			TargetID:   audit.ID(user.ID),
+			Diff: map[string]audit.Change{
+				"username": {From: current, To: username},
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.evict(ctx, user.ID)
+
+	u := *user
+	u.Username = username
+
+	return &u, nil
+}
+
+// CheckPassword re-authenticates userID before a sensitive change. The
+// authenticated user may come from the cache, which has no password hash.
+func CheckPassword(ctx context.Context, repo UserRepository, userID int64, password string) error {
+	user, err := repo.GetByID(ctx, userID)
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return commons.ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if user.ComparePassword(password) != nil {
+		return commons.ErrInvalidPassword
+	}
+
+	return nil
+}
+
+// evict drops the cached account, a failure only delays the change until
+// the entry expires.
+func (uc *usecase) evict(ctx context.Context, userID int64) {
+	if uc.cache == nil {
+		return
+	}
+
+	if err := uc.cache.Delete(ctx, userID); err != nil {
+		log.Println("failed to evict cached user:", userID, err)
+	}
+}
+
+func (uc *usecase) sendMail(template, username, email string, data any) error {
+	if uc.mailer == nil {
+		log.Println("mailer is not configured, skipping", template, "to user", username)
+		return nil
+	}
+
+	isSandbox := uc.config.App.Env != "production"
+	return uc.mailer.Send(template, username, email, data, isSandbox)
+}
+
+func setField(dst, value *string) {
+	if value != nil {
+		*dst = strings.TrimSpace(*value)
+	}
+}
+
+// checkProfileURL allows an empty value or an absolute http(s) URL.
+func checkProfileURL(field, raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s must be an http or https URL", commons.ErrInvalidProfile, field)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+)
+
+func TestUsernamePattern(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"gopher", true},
+		{"Go_Pher99", true},
+		{"go.pher", true},
+		{"go-pher.dev", true},
+		{"", false},
+		{"go pher", false},
+		{"@gopher", false},
+		{"go@pher", false},
+		{".gopher", false},
+		{"gopher-", false},
+		{"go..pher", false},
+		{"gö", false},
+	}
+
+	for _, tt := range tests {
+		if got := usernamePattern.MatchString(tt.username); got != tt.want {
+			t.Errorf("usernamePattern.MatchString(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
+
+// Invalid handles are refused before the database is touched.
+func TestChangeUsernameRejectsInvalid(t *testing.T) {
+	uc := NewUserUsecase(nil, nil, nil, nil, nil, nil, config.Config{})
+
+	for _, username := range []string{"   ", "go pher", "me@example.com"} {
+		_, err := uc.ChangeUsername(context.Background(), &User{ID: 1, Username: "gopher"}, username)
+		if !errors.Is(err, commons.ErrInvalidValue) {
+			t.Errorf("ChangeUsername(%q) error = %v, want %v", username, err, commons.ErrInvalidValue)
+		}
+	}
+}
@@ -3,50 +3,70 @@ package middleware
 import (
 	"context"
 	"fmt"
-	"log"
-	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/tokens"
 	"github.com/codepnw/gopher-social/internal/domains/users"
 	// "github.com/codepnw/gopher-social/internal/entity"
 	// "github.com/codepnw/gopher-social/internal/handler"
 	// "github.com/codepnw/gopher-social/internal/store"
 	"github.com/codepnw/gopher-social/internal/store/cache"
+	"github.com/codepnw/gopher-social/internal/utils/response"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type middleware struct {
-	auth auth.JWTAuthenticator
+	auth *auth.JWTAuthenticator
 	// store store.Storage
-	redis cache.Storage
+	users  users.UserUsecase
+	tokens tokens.TokenUsecase
+	redis  *cache.Storage
 }
 
-func InitMiddleware(redis cache.Storage) *middleware {
+func InitMiddleware(jwt *auth.JWTAuthenticator, users users.UserUsecase, tokens tokens.TokenUsecase, redis *cache.Storage) *middleware {
 	return &middleware{
-		redis: redis,
+		auth:   jwt,
+		users:  users,
+		tokens: tokens,
+		redis:  redis,
 	}
 }
 
+// AuthTokenMiddleware accepts either a JWT issued by /auth/login or a
+// personal access token. Access tokens are limited to their scopes, see
+// RequireScope. Requests an earlier middleware already authenticated, e.g.
+// with a realtime ticket, pass through.
 func (m *middleware) AuthTokenMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := c.Get(commons.ContextAuthUserKey); ok {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "authorization header is missing"})
+			response.Abort(c, commons.ErrUnauthorized.WithMessage("authorization header is missing"))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "authorization header is invalid"})
+			response.Abort(c, commons.ErrUnauthorized.WithMessage("authorization header is invalid"))
+			return
+		}
+
+		if strings.HasPrefix(parts[1], tokens.TokenPrefix) {
+			m.authenticateAPIToken(c, parts[1])
 			return
 		}
 
 		token, err := m.auth.ValidateToken(parts[1])
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			response.Abort(c, commons.ErrInvalidToken.Wrap(err))
 			return
 		}
 
@@ -54,77 +74,120 @@ func (m *middleware) AuthTokenMiddleware() gin.HandlerFunc {
 
 		userID, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 64)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			response.Abort(c, commons.ErrInvalidToken.Wrap(err))
 			return
 		}
 
 		user, err := m.getUser(c, userID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			response.Abort(c, commons.ErrInvalidToken.Wrap(err))
 			return
 		}
 
-		c.Set("user", user)
+		c.Set(commons.ContextAuthUserKey, user)
 		c.Next()
 	}
 }
 
-func (m *middleware) CheckPostOwnership(role string) gin.HandlerFunc {
+func (m *middleware) authenticateAPIToken(c *gin.Context, plainToken string) {
+	token, err := m.tokens.Authenticate(c, plainToken)
+	if err != nil {
+		response.Abort(c, err)
+		return
+	}
+
+	user, err := m.getUser(c, token.UserID)
+	if err != nil {
+		response.Abort(c, commons.ErrInvalidToken.Wrap(err))
+		return
+	}
+
+	c.Set(commons.ContextAuthUserKey, user)
+	c.Set(commons.ContextAuthTokenKey, token)
+	c.Next()
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// JWT sessions are not scoped and always pass.
+func (m *middleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := getAPIToken(c)
+		if token != nil && !token.HasScope(scope) {
+			response.Abort(c, commons.ErrForbidden.WithMessage("token is missing scope "+scope))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SessionOnly rejects requests authenticated with a personal access token,
+// e.g. so a leaked token cannot mint new ones.
+func (m *middleware) SessionOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// user := handler.GetUserFromContext(c)
-		// post := handler.GetPostFromContext(c)
-
-		// TODO
-		// check user post
-		// if post.UserID == user.ID {
-		// 	c.Next()
-		// 	return
-		// }
-
-		// TODO
-		// role precedence check
-		// allowed, err := m.checkRolePrecedence(c, user, role)
-		// if err != nil {
-		// 	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		// 	return
-		// }
-
-		// if !allowed {
-		// 	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		// 	return
-		// }
+		if getAPIToken(c) != nil {
+			response.Abort(c, commons.ErrForbidden.WithMessage("personal access tokens are not allowed"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func getAPIToken(c *gin.Context) *tokens.Token {
+	token, ok := c.Get(commons.ContextAuthTokenKey)
+	if !ok {
+		return nil
+	}
+	return token.(*tokens.Token)
+}
+
+// RequireRole only lets through users whose role level is at least the
+// level of roleName.
+func (m *middleware) RequireRole(roleName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := users.GetAuthUserFromContext(c)
+
+		allowed, err := m.checkRolePrecedence(c, user, roleName)
+		if err != nil {
+			response.Abort(c, err)
+			return
+		}
+
+		if !allowed {
+			response.Abort(c, commons.ErrForbidden)
+			return
+		}
 
 		c.Next()
 	}
 }
 
 func (m *middleware) checkRolePrecedence(ctx context.Context, user *users.User, roleName string) (bool, error) {
-	// role, err := store.GetRoleRepo().GetByName(ctx, roleName)
-	// if err != nil {
-	// 	return false, err
-	// }
-
-	// return user.Role.Level >= role.Level, nil
-	// TODO:
-	return true, nil
+	role, err := m.users.GetRoleByName(ctx, roleName)
+	if err != nil {
+		return false, err
+	}
+
+	return user.Role.Level >= role.Level, nil
 }
 
 func (m *middleware) getUser(ctx context.Context, userID int64) (*users.User, error) {
+	if m.redis == nil {
+		return m.users.GetByID(ctx, userID)
+	}
+
 	// get user from cache
 	user, err := m.redis.Users.Get(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("user 1", user)
-
 	if user == nil {
-		// TODO:
-		// get user from db
-		// user, err = store.GetUserRepo().GetByID(ctx, userID)
-		// if err != nil {
-		// 	return nil, err
-		// }
+		user, err = m.users.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
 
 		// set user
 		if err := m.redis.Users.Set(ctx, user); err != nil {
@@ -3,31 +3,151 @@ package newrouter
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/codepnw/gopher-social/cmd/config"
 	"github.com/codepnw/gopher-social/internal/auth"
+	"github.com/codepnw/gopher-social/internal/domains/accounts"
+	"github.com/codepnw/gopher-social/internal/domains/admin"
+	"github.com/codepnw/gopher-social/internal/domains/audit"
 	"github.com/codepnw/gopher-social/internal/domains/authdomain"
+	"github.com/codepnw/gopher-social/internal/domains/comments"
+	"github.com/codepnw/gopher-social/internal/domains/commons"
+	"github.com/codepnw/gopher-social/internal/domains/digests"
 	"github.com/codepnw/gopher-social/internal/domains/feed"
+	"github.com/codepnw/gopher-social/internal/domains/filters"
+	"github.com/codepnw/gopher-social/internal/domains/media"
+	"github.com/codepnw/gopher-social/internal/domains/moderation"
+	"github.com/codepnw/gopher-social/internal/domains/notifications"
+	"github.com/codepnw/gopher-social/internal/domains/polls"
 	"github.com/codepnw/gopher-social/internal/domains/posts"
+	"github.com/codepnw/gopher-social/internal/domains/previews"
+	"github.com/codepnw/gopher-social/internal/domains/realtime"
+	"github.com/codepnw/gopher-social/internal/domains/search"
+	"github.com/codepnw/gopher-social/internal/domains/tags"
+	"github.com/codepnw/gopher-social/internal/domains/tokens"
 	"github.com/codepnw/gopher-social/internal/domains/users"
+	"github.com/codepnw/gopher-social/internal/middleware"
+	"github.com/codepnw/gopher-social/internal/store/blob"
+	"github.com/codepnw/gopher-social/internal/store/cache"
+	"github.com/codepnw/gopher-social/internal/utils/mailer"
+	"github.com/codepnw/gopher-social/internal/utils/requestid"
+	"github.com/codepnw/gopher-social/internal/utils/response"
+	"github.com/codepnw/gopher-social/internal/utils/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
 type Routes struct {
 	DB     *sql.DB
 	Config config.Config
 	JWT    *auth.JWTAuthenticator
+	Cache  *cache.Storage
+	Mailer mailer.Client
+	// Redis is shared by the API instances when cfg.Redis.Enabled, it is
+	// created from the config if left nil.
+	Redis *redis.Client
+	// Realtime fans events out across instances through Redis, it is closed
+	// on shutdown before the open streams are drained. Built from Redis if
+	// left nil, without Redis delivery is local to this instance.
+	Realtime *realtime.Gateway
 }
 
 func (s *Routes) SetupRoutes() *gin.Engine {
 	auth := authdomain.InitAuthDomain(s.DB, s.Config, s.JWT)
-	post := posts.InitPostDomain(s.DB)
-	user := users.InitUserDomain(s.DB, s.Config)
-	feed := feed.InitFeedDomain(s.DB)
+	if s.Redis == nil && s.Config.Redis.Enabled {
+		s.Redis = cache.NewRedisClient(s.Config.Redis.Addr, s.Config.Redis.Pw, s.Config.Redis.DB)
+	}
+	if s.Realtime == nil {
+		s.Realtime = realtime.NewGateway(s.Redis)
+	}
+
+	storage, err := blob.NewStorage(s.Config.Media)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exports, err := blob.NewStorage(s.Config.Exports)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if blob.Overlaps(s.Config.Media, s.Config.Exports) {
+		log.Fatal("exports storage must be apart from the public media storage")
+	}
+
+	filter := filters.NewFilter(s.DB)
+	notifier := notifications.NewNotifier(s.DB, s.Realtime)
+	defer notifier.Close()
+	links := previews.NewPreviewQueue(s.DB)
+	defer links.Close()
+
+	renderer := posts.NewRenderer(s.Config.Posts.RenderCacheSize)
+
+	post := posts.InitPostDomain(s.DB, s.Config, filter, storage, links, renderer, notifier, s.Realtime)
+	comment := comments.InitCommentsDomain(s.DB, s.Config, filter, notifier)
+	var usercache users.UserCache
+	if s.Cache != nil {
+		usercache = s.Cache.Users
+	}
+	user := users.InitUserDomain(s.DB, s.Config, notifier, s.Mailer, usercache)
+	notification := notifications.InitNotificationDomain(s.DB)
+	feed := feed.InitFeedDomain(s.DB, storage, renderer)
+	token := tokens.InitTokenDomain(s.DB)
+	audits := audit.InitAuditDomain(s.DB)
+	admins := admin.InitAdminDomain(s.DB, s.Config, s.Cache, s.Mailer)
+	moderations := moderation.InitModerationDomain(s.DB, s.Config, s.Cache)
+	filterrules := filters.InitFilterDomain(s.DB, filter)
+	searches := search.InitSearchDomain(s.DB)
+	tag := tags.InitTagDomain(s.DB, renderer)
+	stream := realtime.InitRealtimeDomain(s.DB, s.Config, s.Realtime)
+	digest := digests.InitDigestDomain(s.DB)
+	medias := media.InitMediaDomain(s.DB, s.Config, storage)
+	poll := polls.InitPollDomain(s.DB, notifier)
+	account := accounts.InitAccountDomain(s.DB, s.Config, storage, exports)
+
+	scheduler := posts.NewPostScheduler(s.DB, s.Config, notifier, s.Realtime)
+	scheduler.Start()
+	defer scheduler.Close()
+
+	purger := posts.NewPostPurger(s.DB, s.Config)
+	purger.Start()
+	defer purger.Close()
+
+	cleaner := media.NewMediaCleaner(s.DB, s.Config, storage)
+	cleaner.Start()
+	defer cleaner.Close()
+
+	closer := polls.NewPollCloser(s.DB, notifier)
+	closer.Start()
+	defer closer.Close()
+
+	accountworker := accounts.NewAccountWorker(s.DB, s.Config, storage, exports)
+	accountworker.Start()
+	defer accountworker.Close()
+
+	if s.Config.Mail.Digest.Enabled && s.Mailer != nil {
+		worker := digests.NewDigestWorker(s.DB, s.Config, s.Mailer)
+		worker.Start()
+		defer worker.Close()
+	}
+
+	userrepo := users.NewUserRepository(s.DB)
+	auditusecase := audit.NewAuditUsecase(audit.NewAuditRepository(s.DB))
+	mid := middleware.InitMiddleware(
+		s.JWT,
+		users.NewUserUsecase(s.DB, userrepo, auditusecase, nil, nil, nil, s.Config),
+		tokens.NewTokenUsecase(tokens.NewTokenRepository(s.DB)),
+		s.Cache,
+	)
 
 	gin.SetMode(gin.ReleaseMode)
+	validation.Init()
 	r := gin.Default()
+	r.Use(requestid.Middleware())
+	r.NoRoute(func(c *gin.Context) {
+		response.Error(c, commons.ErrNotFound)
+	})
 
 	version := s.Config.App.ApiVersion
 	port := fmt.Sprintf(":%s", s.Config.App.Addr)
@@ -45,30 +165,145 @@ func (s *Routes) SetupRoutes() *gin.Engine {
 	authroutes := r.Group(version + "/auth")
 	authroutes.POST("/register", auth.Register)
 	authroutes.POST("/login", auth.Login)
+	authroutes.GET("/oidc/login", auth.OIDCLogin)
+	authroutes.GET("/oidc/callback", auth.OIDCCallback)
 
 	// Post Routes
-	postroutes := r.Group(version + "/posts")
-	postroutes.POST("/", post.CreatePostHandler)
+	postroutes := r.Group(version+"/posts", mid.AuthTokenMiddleware())
+	postroutes.POST("/", mid.RequireScope(tokens.ScopePostsWrite), post.CreatePostHandler)
+	postroutes.GET("/scheduled", mid.RequireScope(tokens.ScopePostsRead), post.ListScheduledHandler)
+	postroutes.POST("/:id/restore", mid.RequireScope(tokens.ScopePostsWrite), post.RestorePostHandler)
 	{
 		postroutes.Use(post.PostContextMiddleware())
-		postroutes.GET("/:id", post.GetPostHandler)
-		postroutes.PATCH("/:id", post.UpdatePostHandler)
-		postroutes.DELETE("/:id", post.DeletePostHandler)
+		postroutes.GET("/:id", mid.RequireScope(tokens.ScopePostsRead), post.GetPostHandler)
+		postroutes.PATCH("/:id", mid.RequireScope(tokens.ScopePostsWrite), post.UpdatePostHandler)
+		postroutes.DELETE("/:id", mid.RequireScope(tokens.ScopePostsWrite), post.DeletePostHandler)
+		postroutes.GET("/:id/revisions", mid.RequireScope(tokens.ScopePostsRead), post.ListRevisionsHandler)
+		postroutes.PUT("/:id/reaction", mid.RequireScope(tokens.ScopePostsWrite), post.ReactHandler)
+		postroutes.DELETE("/:id/reaction", mid.RequireScope(tokens.ScopePostsWrite), post.UnreactHandler)
+		postroutes.PUT("/:id/repost", mid.RequireScope(tokens.ScopePostsWrite), post.RepostHandler)
+		postroutes.DELETE("/:id/repost", mid.RequireScope(tokens.ScopePostsWrite), post.UnrepostHandler)
+		postroutes.POST("/:id/poll/votes", mid.RequireScope(tokens.ScopePostsWrite), poll.VoteHandler)
+		postroutes.PUT("/:id/schedule", mid.RequireScope(tokens.ScopePostsWrite), post.RescheduleHandler)
+		postroutes.DELETE("/:id/schedule", mid.RequireScope(tokens.ScopePostsWrite), post.CancelScheduledHandler)
+		postroutes.GET("/:id/comments", mid.RequireScope(tokens.ScopePostsRead), comment.ListCommentsHandler)
+		postroutes.POST("/:id/comments", mid.RequireScope(tokens.ScopeCommentsWrite), comment.CreateCommentHandler)
+		postroutes.DELETE("/:id/comments/:commentID", mid.RequireScope(tokens.ScopeCommentsWrite), comment.DeleteCommentHandler)
+		postroutes.POST("/:id/comments/:commentID/restore", mid.RequireScope(tokens.ScopeCommentsWrite), comment.RestoreCommentHandler)
 	}
 
 	// User Routes
 	userroutes := r.Group(version + "/users")
 	userroutes.POST("/", user.CreateHandler)
 	userroutes.PUT("/activate/:token", user.ActivateHandler)
+	userroutes.PUT("/password-reset/:token", user.ResetPasswordHandler)
+	userroutes.PUT("/email-change/:token", user.ConfirmEmailHandler)
+	userroutes.GET("/:id/profile", user.GetProfileHandler)
+	{
+		userroutes.Use(mid.AuthTokenMiddleware(), user.UserContextMiddleware())
+		userroutes.GET("/:id", mid.RequireScope(tokens.ScopeUsersRead), user.GetByIDHandler)
+		userroutes.GET("/:id/follow", mid.RequireScope(tokens.ScopeUsersWrite), user.FollowUserHandler)
+		userroutes.GET("/:id/unfollow", mid.RequireScope(tokens.ScopeUsersWrite), user.UnfollowUserHandler)
+		userroutes.PUT("/:id/block", mid.RequireScope(tokens.ScopeUsersWrite), user.BlockUserHandler)
+		userroutes.PUT("/:id/unblock", mid.RequireScope(tokens.ScopeUsersWrite), user.UnblockUserHandler)
+		userroutes.GET("/:id/feed", mid.RequireScope(tokens.ScopeFeedRead), feed.GetUserFeedHandler)
+	}
+
+	meroutes := r.Group(version+"/users/me", mid.AuthTokenMiddleware())
+	meroutes.PATCH("", mid.RequireScope(tokens.ScopeUsersWrite), user.UpdateProfileHandler)
+	meroutes.POST("/email", mid.SessionOnly(), user.ChangeEmailHandler)
+	meroutes.PUT("/username", mid.SessionOnly(), user.ChangeUsernameHandler)
+	meroutes.DELETE("", mid.SessionOnly(), account.DeleteAccountHandler)
+	meroutes.POST("/restore", mid.RequireScope(tokens.ScopeUsersWrite), account.RestoreAccountHandler)
+	meroutes.POST("/exports", mid.SessionOnly(), account.RequestExportHandler)
+	meroutes.GET("/exports/:id", mid.SessionOnly(), account.GetExportHandler)
+	meroutes.GET("/exports/:id/download", mid.SessionOnly(), account.DownloadExportHandler)
+	meroutes.PUT("/pins/:postID", mid.RequireScope(tokens.ScopeUsersWrite), user.PinPostHandler)
+	meroutes.DELETE("/pins/:postID", mid.RequireScope(tokens.ScopeUsersWrite), user.UnpinPostHandler)
+
+	// Media Routes
+	if s.Config.Media.Backend == "local" {
+		r.Static("/media", s.Config.Media.LocalDir)
+	}
+	mediaroutes := r.Group(version+"/media", mid.AuthTokenMiddleware())
+	mediaroutes.POST("/", mid.RequireScope(tokens.ScopePostsWrite), medias.UploadHandler)
+	mediaroutes.GET("/:id", mid.RequireScope(tokens.ScopePostsRead), medias.GetHandler)
+	mediaroutes.DELETE("/:id", mid.RequireScope(tokens.ScopePostsWrite), medias.DeleteHandler)
+
+	// Search Routes
+	r.GET(version+"/search", mid.AuthTokenMiddleware(), mid.RequireScope(tokens.ScopePostsRead), searches.SearchHandler)
+
+	// Tag Routes
+	tagroutes := r.Group(version+"/tags", mid.AuthTokenMiddleware())
+	tagroutes.GET("/trending", mid.RequireScope(tokens.ScopePostsRead), tag.TrendingTagsHandler)
+	tagroutes.GET("/following", mid.RequireScope(tokens.ScopeFeedRead), tag.ListFollowedTagsHandler)
+	tagroutes.GET("/:tag/posts", mid.RequireScope(tokens.ScopePostsRead), tag.GetTagPostsHandler)
+	tagroutes.PUT("/:tag/follow", mid.RequireScope(tokens.ScopeUsersWrite), tag.FollowTagHandler)
+	tagroutes.PUT("/:tag/unfollow", mid.RequireScope(tokens.ScopeUsersWrite), tag.UnfollowTagHandler)
+
+	// Notification Routes
+	notificationroutes := r.Group(version+"/notifications", mid.AuthTokenMiddleware(), mid.SessionOnly())
+	notificationroutes.GET("/", notification.ListNotificationsHandler)
+	notificationroutes.GET("/unread-count", notification.UnreadCountHandler)
+	notificationroutes.PUT("/read", notification.MarkAllReadHandler)
+	notificationroutes.PUT("/:id/read", notification.MarkReadHandler)
+	notificationroutes.GET("/preferences", notification.GetPreferencesHandler)
+	notificationroutes.PUT("/preferences", notification.UpdatePreferencesHandler)
+
+	// Digest Routes
+	r.POST(version+"/digests/unsubscribe/:token", digest.UnsubscribeHandler)
+	digestroutes := r.Group(version+"/digests", mid.AuthTokenMiddleware(), mid.SessionOnly())
+	digestroutes.GET("/preferences", digest.GetPreferencesHandler)
+	digestroutes.PUT("/preferences", digest.UpdatePreferencesHandler)
+
+	// Realtime Routes
+	realtimeroutes := r.Group(version + "/realtime")
+	realtimeroutes.POST("/ticket", mid.AuthTokenMiddleware(), mid.SessionOnly(), stream.CreateTicketHandler)
+	{
+		realtimeroutes.Use(stream.TicketMiddleware(), mid.AuthTokenMiddleware(), mid.SessionOnly())
+		realtimeroutes.GET("/ws", stream.WebSocketHandler)
+		realtimeroutes.GET("/events", stream.EventStreamHandler)
+	}
+
+	// Personal Access Token Routes
+	tokenroutes := r.Group(version+"/tokens", mid.AuthTokenMiddleware(), mid.SessionOnly())
+	tokenroutes.POST("/", token.CreateTokenHandler)
+	tokenroutes.GET("/", token.ListTokensHandler)
+	tokenroutes.DELETE("/:id", token.RevokeTokenHandler)
+
+	// Moderation Routes
+	reportroutes := r.Group(version+"/reports", mid.AuthTokenMiddleware(), mid.SessionOnly())
+	reportroutes.POST("/", moderations.CreateReportHandler)
+
+	modroutes := r.Group(version+"/moderation", mid.AuthTokenMiddleware(), mid.SessionOnly(), mid.RequireRole("staff"))
+	modroutes.GET("/reports", moderations.ListReportsHandler)
+	modroutes.GET("/reports/:id", moderations.GetReportHandler)
+	modroutes.POST("/reports/:id/resolve", moderations.ResolveReportHandler)
+	modroutes.GET("/filter-matches", filterrules.ListMatchesHandler)
+	modroutes.POST("/filter-matches/:id/approve", filterrules.ApproveMatchHandler)
+
+	// Admin Routes
+	adminroutes := r.Group(version+"/admin", mid.AuthTokenMiddleware(), mid.SessionOnly(), mid.RequireRole("staff"))
+	adminroutes.GET("/users", admins.ListUsersHandler)
+	adminroutes.GET("/users/:id", admins.GetUserHandler)
 	{
-		userroutes.Use(user.UserContextMiddleware())
-		userroutes.GET("/:id", user.GetByIDHandler)
-		userroutes.GET("/:id/follow", user.FollowUserHandler)
-		userroutes.GET("/:id/unfollow", user.UnfollowUserHandler)
-		userroutes.GET("/:id/feed", feed.GetUserFeedHandler)
+		adminroutes.Use(mid.RequireRole("admin"))
+		adminroutes.GET("/audit-events", audits.ListEventsHandler)
+		adminroutes.PUT("/users/:id/role", admins.ChangeRoleHandler)
+		adminroutes.PUT("/users/:id/activate", admins.ActivateUserHandler)
+		adminroutes.PUT("/users/:id/deactivate", admins.DeactivateUserHandler)
+		adminroutes.PUT("/users/:id/ban", admins.BanUserHandler)
+		adminroutes.PUT("/users/:id/unban", admins.UnbanUserHandler)
+		adminroutes.POST("/users/:id/password-reset", admins.ForcePasswordResetHandler)
+		adminroutes.DELETE("/users/:id", admins.DeleteUserHandler)
+		adminroutes.GET("/filter-rules", filterrules.ListRulesHandler)
+		adminroutes.POST("/filter-rules", filterrules.CreateRuleHandler)
+		adminroutes.PUT("/filter-rules/:id", filterrules.UpdateRuleHandler)
+		adminroutes.DELETE("/filter-rules/:id", filterrules.DeleteRuleHandler)
 	}
 
-	r.Run(port)
+	// the deferred worker Close calls run once the server has shut down
+	s.serve(r, port)
 
 	return r
 }
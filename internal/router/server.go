@@ -0,0 +1,56 @@
+package newrouter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish.
+const shutdownTimeout = 10 * time.Second
+
+// serve runs the server until SIGINT or SIGTERM and returns once it has
+// shut down, so the caller can close the workers afterwards. There is no
+// write timeout, it would cut off SSE streams.
+func (s *Routes) serve(handler http.Handler, addr string) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       time.Minute,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Println("server stopped:", err)
+		}
+		return
+	case sig := <-quit:
+		log.Println("signal caught, shutting down:", sig)
+	}
+
+	// open WebSocket and SSE streams would keep Shutdown waiting
+	s.Realtime.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("failed to shut down server:", err)
+	}
+}
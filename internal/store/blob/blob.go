@@ -0,0 +1,65 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid storage key")
+	ErrNotFound   = errors.New("storage key not found")
+)
+
+// Storage keeps uploaded files. Keys are slash separated relative paths
+// chosen by the caller; URL returns where clients can fetch them when the
+// storage is served publicly.
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+func NewStorage(cfg config.MediaConfig) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStorage(cfg.LocalDir, cfg.PublicURL), nil
+	case "s3":
+		return NewS3Storage(cfg.S3, cfg.PublicURL, nil)
+	default:
+		return nil, fmt.Errorf("unknown media backend %q", cfg.Backend)
+	}
+}
+
+// Overlaps reports whether files in private would be reachable through the
+// public URLs of public: the same bucket, or a local dir inside the
+// public one.
+func Overlaps(public, private config.MediaConfig) bool {
+	if public.Backend != private.Backend {
+		return false
+	}
+
+	switch public.Backend {
+	case "local":
+		pub, err := filepath.Abs(public.LocalDir)
+		if err != nil {
+			return true
+		}
+		priv, err := filepath.Abs(private.LocalDir)
+		if err != nil {
+			return true
+		}
+
+		rel, err := filepath.Rel(pub, priv)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	case "s3":
+		return public.S3.Endpoint == private.S3.Endpoint && public.S3.Bucket == private.S3.Bucket
+	default:
+		return false
+	}
+}
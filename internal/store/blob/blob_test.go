@@ -0,0 +1,39 @@
+package blob
+
+import (
+	"testing"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+)
+
+func TestOverlaps(t *testing.T) {
+	local := func(dir string) config.MediaConfig {
+		return config.MediaConfig{Backend: "local", LocalDir: dir}
+	}
+	bucket := func(name string) config.MediaConfig {
+		return config.MediaConfig{Backend: "s3", S3: config.S3Config{Endpoint: "https://s3.amazonaws.com", Bucket: name}}
+	}
+
+	tests := []struct {
+		name            string
+		public, private config.MediaConfig
+		want            bool
+	}{
+		{"same dir", local("./uploads"), local("uploads"), true},
+		{"dir inside", local("./uploads"), local("./uploads/exports"), true},
+		{"sibling dir", local("./uploads"), local("./exports"), false},
+		{"sibling with a common prefix", local("./uploads"), local("./uploads-private"), false},
+		{"parent dir", local("./data/uploads"), local("./data"), false},
+		{"same bucket", bucket("media"), bucket("media"), true},
+		{"other bucket", bucket("media"), bucket("exports"), false},
+		{"other backend", local("./uploads"), bucket("uploads"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlaps(tt.public, tt.private); got != tt.want {
+				t.Errorf("Overlaps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package blobtest runs an in-memory stand-in for S3 in tests. It checks
+// the SigV4 signature of every request the way S3 does, so a signing bug
+// fails the request instead of going unnoticed.
+package blobtest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codepnw/gopher-social/cmd/config"
+)
+
+const (
+	Region    = "us-east-1"
+	AccessKey = "AKIDTEST"
+	SecretKey = "test-secret-key"
+)
+
+type Object struct {
+	ContentType string
+	Data        []byte
+}
+
+// S3 serves path-style PUT, GET and DELETE for a single bucket.
+type S3 struct {
+	*httptest.Server
+	Bucket string
+
+	mu       sync.Mutex
+	objects  map[string]Object
+	failures map[string]int
+}
+
+// NewS3 starts the stand-in, it is closed when the test ends.
+func NewS3(t testing.TB, bucket string) *S3 {
+	s := &S3{
+		Bucket:   bucket,
+		objects:  make(map[string]Object),
+		failures: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Config points an S3Storage at the stand-in.
+func (s *S3) Config() config.S3Config {
+	return config.S3Config{
+		Endpoint:  s.URL,
+		Region:    Region,
+		Bucket:    s.Bucket,
+		AccessKey: AccessKey,
+		SecretKey: SecretKey,
+		PathStyle: true,
+	}
+}
+
+func (s *S3) Object(key string) (Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.objects[key]
+	return o, ok
+}
+
+func (s *S3) SetObject(key string, o Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = o
+}
+
+// Len is the number of stored objects.
+func (s *S3) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.objects)
+}
+
+// Fail makes every request for key answer with status.
+func (s *S3) Fail(key string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[key] = status
+}
+
+func (s *S3) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if msg := verify(r, body); msg != "" {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+msg+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.Bucket+"/")
+	if !ok || key == "" {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status, ok := s.failures[key]; ok {
+		http.Error(w, "<Error><Code>InternalError</Code></Error>", status)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = Object{ContentType: r.Header.Get("Content-Type"), Data: body}
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet:
+		o, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", o.ContentType)
+		w.Write(o.Data)
+
+	case http.MethodDelete:
+		// S3 answers 204 whether or not the key existed
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify rebuilds the signature from what arrived on the wire and returns
+// why it does not match, or "".
+func verify(r *http.Request, body []byte) string {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "missing AWS4-HMAC-SHA256 authorization"
+	}
+
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		fields[k] = v
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[0] != AccessKey || credential[2] != Region ||
+		credential[3] != "s3" || credential[4] != "aws4_request" {
+		return "bad credential scope " + fields["Credential"]
+	}
+	date := credential[1]
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, date) {
+		return "x-amz-date does not match the credential date"
+	}
+
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		return "x-amz-content-sha256 does not match the body"
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) || !contains(signed, "host") || !contains(signed, "x-amz-date") {
+		return "bad signed headers " + fields["SignedHeaders"]
+	}
+
+	var headers strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	canonical := strings.Join([]string{
+		r.Method,
+		path,
+		query,
+		headers.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" +
+		strings.Join(credential[1:], "/") + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + SecretKey)
+	for _, part := range []string{date, Region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+
+	if !hmac.Equal([]byte(hex.EncodeToString(key)), []byte(fields["Signature"])) {
+		return "signature does not match"
+	}
+
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,7 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*users.User, error)
 		Set(context.Context, *users.User) error
+		Delete(context.Context, int64) error
 	}
 }
 
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/codepnw/gopher-social/internal/domains/users"
@@ -20,8 +19,6 @@ const UserExpTime = time.Minute
 func (s *UserStore) Get(ctx context.Context, userID int64) (*users.User, error) {
 	cacheKey := fmt.Sprintf("user-%v", userID)
 
-	log.Println("cache key", cacheKey)
-
 	data, err := s.rdb.Get(ctx, cacheKey).Result()
 	if err == redis.Nil {
 		return nil, nil
@@ -47,7 +44,11 @@ func (s *UserStore) Set(ctx context.Context, user *users.User) error {
 		return err
 	}
 
-	log.Println("set json", string(json))
-
 	return s.rdb.SetEx(ctx, cacheKey, json, UserExpTime).Err()
 }
+
+func (s *UserStore) Delete(ctx context.Context, userID int64) error {
+	cacheKey := fmt.Sprintf("user-%v", userID)
+
+	return s.rdb.Del(ctx, cacheKey).Err()
+}
@@ -0,0 +1,92 @@
+// Package errs is the error model shared by usecases and handlers.
+//
+// An Error carries a stable machine-readable code, the HTTP status it maps
+// to and a message that is safe to show to clients. The cause, if any, is
+// only logged.
+package errs
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	Internal   = New(http.StatusInternalServerError, "internal_error", "the server encountered a problem")
+	Validation = New(http.StatusBadRequest, "validation_failed", "the request is invalid")
+)
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Fields lists per-field problems with the request, if any
+	Fields []FieldError
+	// Err is the underlying cause, it never reaches the client
+	Err error
+}
+
+// FieldError points at one invalid field by its name in the request, e.g.
+// "title" or "options[2]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any Error with the same code, so a wrapped or reworded copy
+// still matches its sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithMessage returns a copy of e with a more specific public message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithFields returns a copy of e listing the invalid fields.
+func (e *Error) WithFields(fields []FieldError) *Error {
+	c := *e
+	c.Fields = fields
+	return &c
+}
+
+// From returns the Error in err's chain, or Internal caused by err. Detail
+// added by fmt.Errorf("%w: ...") around a sentinel is part of its public
+// message, so it must not carry internal errors.
+func From(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return Internal.Wrap(err)
+	}
+
+	if err != error(e) && e.Err == nil {
+		return e.WithMessage(err.Error())
+	}
+
+	return e
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"github.com/codepnw/gopher-social/internal/utils/requestid"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -22,9 +23,9 @@ func InitLogger() (*zap.SugaredLogger, error) {
 }
 
 func Error(c *gin.Context, msg string, err error) {
-	logger.Errorw(msg, "method", c.Request.Method, "path", c.Request.URL.Path, "error", err.Error())
+	logger.Errorw(msg, "method", c.Request.Method, "path", c.Request.URL.Path, "request_id", requestid.Get(c), "error", err.Error())
 }
 
 func Warn(c *gin.Context, msg string, err error) {
-	logger.Warn(msg, "method", c.Request.Method, "path", c.Request.URL.Path, "error", err.Error())
+	logger.Warnw(msg, "method", c.Request.Method, "path", c.Request.URL.Path, "request_id", requestid.Get(c), "error", err.Error())
 }
\ No newline at end of file
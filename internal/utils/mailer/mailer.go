@@ -6,6 +6,10 @@ const (
 	FromName = "GopherSocial"
 	maxRetires = 3
 	UserWelcomeTemplate = "user_invitation.templ"
+	UserDigestTemplate = "user_digest.templ"
+	EmailChangeTemplate = "email_change.templ"
+	EmailChangedTemplate = "email_changed.templ"
+	PasswordResetTemplate = "password_reset.templ"
 )
 
 //go:embed "templates"
@@ -3,8 +3,7 @@ package mailer
 import (
 	"bytes"
 	"errors"
-	"log"
-	"text/template"
+	"html/template"
 
 	gomail "gopkg.in/mail.v2"
 )
@@ -25,23 +24,25 @@ func NewMailTrapClient(apiKey, fromEmail string) (MailtrapClient, error) {
 	}, nil
 }
 
-func (m MailtrapClient) Send(templateFile, username, email string, data any, isSandbox bool) (int, error) {
+// Send renders templateFile with html/template, post titles and usernames in
+// the data are user content.
+func (m MailtrapClient) Send(templateFile, username, email string, data any, isSandbox bool) error {
 	// template parsing and building
 	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
 	if err != nil {
-		return -1, err
+		return err
 	}
 
 	subject := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return -1, err
+		return err
 	}
 
 	body := new(bytes.Buffer)
 	err = tmpl.ExecuteTemplate(body, "body", data) // name from define templ file
 	if err != nil {
-		return -1, err
+		return err
 	}
 
 	message := gomail.NewMessage()
@@ -53,11 +54,5 @@ func (m MailtrapClient) Send(templateFile, username, email string, data any, isS
 
 	dialer := gomail.NewDialer("live.smtp.mailtrap.io", 587, "api", m.apiKey)
 
-	if err := dialer.DialAndSend(message); err != nil {
-		// TODO: Need Domains
-		log.Println("Dialer", err)
-		return -1, nil
-	}
-
-	return 200, nil
+	return dialer.DialAndSend(message)
 }
@@ -0,0 +1,209 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+type inline struct {
+	b *strings.Builder
+	// plain only renders line breaks and bare URLs
+	plain bool
+	// inLink stops links from nesting
+	inLink bool
+}
+
+func renderInline(b *strings.Builder, s string) {
+	(&inline{b: b}).render(s)
+}
+
+func (r *inline) render(s string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\n':
+			r.b.WriteString("<br>\n")
+			i++
+
+		case !r.inLink && (strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")) &&
+			(i == 0 || !isAlnum(s[i-1])):
+			i += r.autolink(s[i:])
+
+		case r.plain:
+			r.escape(c)
+			i++
+
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#+-.!>", s[i+1]) >= 0:
+			r.escape(s[i+1])
+			i += 2
+
+		case c == '`':
+			i += r.code(s[i:])
+
+		case strings.HasPrefix(s[i:], "**"), strings.HasPrefix(s[i:], "__"):
+			i += r.emphasis(s, i, s[i:i+2], "strong")
+
+		case strings.HasPrefix(s[i:], "~~"):
+			i += r.emphasis(s, i, "~~", "del")
+
+		case c == '*', c == '_':
+			i += r.emphasis(s, i, s[i:i+1], "em")
+
+		case c == '[' && !r.inLink:
+			i += r.link(s[i:])
+
+		default:
+			r.escape(c)
+			i++
+		}
+	}
+}
+
+// code renders a `code span` and returns the bytes consumed.
+func (r *inline) code(s string) int {
+	n := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:n]
+
+	end := strings.Index(s[n:], fence)
+	if end < 0 {
+		r.b.WriteString(fence)
+		return n
+	}
+
+	r.b.WriteString("<code>")
+	r.b.WriteString(html.EscapeString(strings.TrimSpace(s[n : n+end])))
+	r.b.WriteString("</code>")
+
+	return n + end + n
+}
+
+// emphasis wraps the text between delim and its closing pair in tag, or
+// writes delim literally when there is no pair. It returns the bytes
+// consumed from s[i:].
+func (r *inline) emphasis(s string, i int, delim, tag string) int {
+	n := len(delim)
+
+	// intraword underscores, as in snake_case, are literal
+	if delim[0] == '_' && i > 0 && isAlnum(s[i-1]) {
+		r.b.WriteString(delim)
+		return n
+	}
+
+	start := i + n
+	if start >= len(s) || s[start] == ' ' || s[start] == '\n' {
+		r.b.WriteString(delim)
+		return n
+	}
+
+	end := closingDelim(s, start, delim)
+	if end < 0 {
+		r.b.WriteString(delim)
+		return n
+	}
+
+	r.b.WriteString("<" + tag + ">")
+	r.render(s[start:end])
+	r.b.WriteString("</" + tag + ">")
+
+	return end + n - i
+}
+
+func closingDelim(s string, start int, delim string) int {
+	for j := start + 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case s[j] == '`':
+			// delimiters inside code spans do not count
+			if end := strings.IndexByte(s[j+1:], '`'); end >= 0 {
+				j += end + 1
+			}
+		case strings.HasPrefix(s[j:], delim):
+			// a single * must not close on half of a **
+			if len(delim) == 1 && j+1 < len(s) && s[j+1] == delim[0] {
+				j++
+				continue
+			}
+			if s[j-1] == ' ' || s[j-1] == '\n' {
+				continue
+			}
+			if delim[0] == '_' && j+len(delim) < len(s) && isAlnum(s[j+len(delim)]) {
+				continue
+			}
+			return j
+		}
+	}
+
+	return -1
+}
+
+// link renders [text](url) and returns the bytes consumed. Unsafe or
+// malformed links are written as text.
+func (r *inline) link(s string) int {
+	mid := strings.IndexByte(s, ']')
+	if mid < 0 || mid+1 >= len(s) || s[mid+1] != '(' {
+		r.escape('[')
+		return 1
+	}
+
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end < 0 {
+		r.escape('[')
+		return 1
+	}
+
+	text := s[1:mid]
+	href := safeURL(s[mid+2 : mid+2+end])
+	consumed := mid + 2 + end + 1
+
+	if href == "" {
+		r.render(text)
+		return consumed
+	}
+
+	r.b.WriteString(`<a href="` + href + `" ` + linkRel + `>`)
+	r.inLink = true
+	r.render(text)
+	r.inLink = false
+	r.b.WriteString("</a>")
+
+	return consumed
+}
+
+// autolink links the bare URL at the start of s and returns its length.
+func (r *inline) autolink(s string) int {
+	end := strings.IndexAny(s, " \t\n<>\"")
+	if end < 0 {
+		end = len(s)
+	}
+
+	raw := strings.TrimRight(s[:end], ".,;:!?)]}'*_~")
+
+	href := safeURL(raw)
+	if href == "" {
+		r.b.WriteString(html.EscapeString(raw))
+		return len(raw)
+	}
+
+	r.b.WriteString(`<a href="` + href + `" ` + linkRel + `>` + html.EscapeString(raw) + `</a>`)
+
+	return len(raw)
+}
+
+func (r *inline) escape(c byte) {
+	switch c {
+	case '<':
+		r.b.WriteString("&lt;")
+	case '>':
+		r.b.WriteString("&gt;")
+	case '&':
+		r.b.WriteString("&amp;")
+	case '"':
+		r.b.WriteString("&#34;")
+	case '\'':
+		r.b.WriteString("&#39;")
+	default:
+		r.b.WriteByte(c)
+	}
+}
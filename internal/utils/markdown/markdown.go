@@ -0,0 +1,293 @@
+// Package markdown renders the Markdown subset allowed in posts to HTML.
+//
+// The output is safe by construction: every piece of source text is escaped
+// and only the tags below are emitted, so there is no raw HTML to sanitize.
+//
+//	paragraphs, line breaks, # headings, > quotes, - and 1. lists,
+//	``` code blocks, --- rules, **strong**, *em*, ~~del~~, `code`,
+//	[links](https://...) and bare http(s) URLs
+//
+// Links are limited to http, https and mailto and carry rel="nofollow".
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxQuoteDepth bounds the recursion on nested > quotes.
+const maxQuoteDepth = 8
+
+const linkRel = `rel="nofollow noopener ugc"`
+
+// Render converts Markdown source to sanitized HTML.
+func Render(src string) string {
+	var b strings.Builder
+	renderBlocks(&b, splitLines(src), 0)
+	return b.String()
+}
+
+// Plain converts plain text to HTML, keeping paragraphs and line breaks
+// and linking bare URLs.
+func Plain(src string) string {
+	var b strings.Builder
+
+	for _, para := range strings.Split(strings.Join(splitLines(src), "\n"), "\n\n") {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+
+		b.WriteString("<p>")
+		(&inline{b: &b, plain: true}).render(para)
+		b.WriteString("</p>\n")
+	}
+
+	return b.String()
+}
+
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(src, "\r", "\n"), "\n")
+}
+
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case strings.HasPrefix(trimmed, "```"):
+			i = renderFence(b, lines, i)
+
+		case isRule(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+
+		case headingLevel(trimmed) > 0:
+			level := strconv.Itoa(headingLevel(trimmed))
+			b.WriteString("<h" + level + ">")
+			renderInline(b, strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+			b.WriteString("</h" + level + ">\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">") && depth < maxQuoteDepth:
+			var quoted []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					break
+				}
+				quoted = append(quoted, strings.TrimPrefix(t[1:], " "))
+			}
+
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+
+		case isListItem(trimmed):
+			i = renderList(b, lines, i)
+
+		default:
+			i = renderParagraph(b, lines, i)
+		}
+	}
+}
+
+// startsBlock reports whether line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, ">") ||
+		isRule(line) || headingLevel(line) > 0 || isListItem(line)
+}
+
+func renderParagraph(b *strings.Builder, lines []string, i int) int {
+	var para []string
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if t == "" || (len(para) > 0 && startsBlock(t)) {
+			break
+		}
+		para = append(para, t)
+	}
+
+	b.WriteString("<p>")
+	renderInline(b, strings.Join(para, "\n"))
+	b.WriteString("</p>\n")
+
+	return i
+}
+
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	lang := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), "```"))
+
+	b.WriteString("<pre><code")
+	if lang = safeLanguage(lang); lang != "" {
+		b.WriteString(` class="language-` + lang + `"`)
+	}
+	b.WriteString(">")
+
+	for i++; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "```" {
+			i++
+			break
+		}
+		b.WriteString(html.EscapeString(lines[i]))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("</code></pre>\n")
+
+	return i
+}
+
+func renderList(b *strings.Builder, lines []string, i int) int {
+	ordered, start, _ := listMarker(strings.TrimSpace(lines[i]))
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	b.WriteString("<" + tag)
+	if ordered && start != 1 {
+		b.WriteString(` start="` + strconv.Itoa(start) + `"`)
+	}
+	b.WriteString(">\n")
+
+	var item []string
+	flush := func() {
+		if item == nil {
+			return
+		}
+		b.WriteString("<li>")
+		renderInline(b, strings.Join(item, "\n"))
+		b.WriteString("</li>\n")
+		item = nil
+	}
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		t := strings.TrimSpace(line)
+		if t == "" {
+			break
+		}
+
+		if o, _, text := listMarker(t); isListItem(t) {
+			if o != ordered {
+				break
+			}
+			flush()
+			item = []string{text}
+			continue
+		}
+
+		// indented lines continue the current item
+		if line[0] != ' ' && line[0] != '\t' {
+			break
+		}
+		item = append(item, t)
+	}
+	flush()
+
+	b.WriteString("</" + tag + ">\n")
+
+	return i
+}
+
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+
+	// "#golang" is a hashtag, not a heading
+	if n == 0 || n > 6 || (n < len(line) && line[n] != ' ') {
+		return 0
+	}
+
+	return n
+}
+
+func isRule(line string) bool {
+	line = strings.ReplaceAll(line, " ", "")
+	if len(line) < 3 || !strings.ContainsRune("-*_", rune(line[0])) {
+		return false
+	}
+
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+func isListItem(line string) bool {
+	_, start, _ := listMarker(line)
+	return start >= 0
+}
+
+// listMarker parses "- item", "* item", "+ item" and "1. item". start is -1
+// when line is not a list item.
+func listMarker(line string) (ordered bool, start int, text string) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return false, 0, strings.TrimSpace(line[2:])
+	}
+
+	n := 0
+	for n < len(line) && n < 9 && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+
+	if n > 0 && n+1 < len(line) && (line[n] == '.' || line[n] == ')') && line[n+1] == ' ' {
+		start, _ := strconv.Atoi(line[:n])
+		return true, start, strings.TrimSpace(line[n+2:])
+	}
+
+	return false, -1, ""
+}
+
+func safeLanguage(lang string) string {
+	if i := strings.IndexAny(lang, " \t"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	if len(lang) > 20 {
+		return ""
+	}
+
+	for _, r := range lang {
+		if !isAlnum(byte(r)) && r != '-' && r != '_' && r != '+' {
+			return ""
+		}
+	}
+
+	return strings.ToLower(lang)
+}
+
+// safeURL returns the escaped href for raw, or "" when raw is not an
+// absolute http, https or mailto URL.
+func safeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return ""
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	return html.EscapeString(u.String())
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,182 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "paragraph and emphasis",
+			src:  "**bold** *em* ~~del~~ `code`",
+			want: "<p><strong>bold</strong> <em>em</em> <del>del</del> <code>code</code></p>\n",
+		},
+		{
+			name: "http link",
+			src:  "[go](https://go.dev/doc)",
+			want: `<p><a href="https://go.dev/doc" ` + linkRel + `>go</a></p>` + "\n",
+		},
+		{
+			name: "mailto link",
+			src:  "[mail](mailto:gopher@example.com)",
+			want: `<p><a href="mailto:gopher@example.com" ` + linkRel + `>mail</a></p>` + "\n",
+		},
+		{
+			name: "bare url",
+			src:  "see https://go.dev.",
+			want: `<p>see <a href="https://go.dev" ` + linkRel + `>https://go.dev</a>.</p>` + "\n",
+		},
+		{
+			name: "javascript link",
+			src:  "[x](javascript:alert)",
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "javascript link with mixed case",
+			src:  "[x](JaVaScRiPt:alert)",
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "javascript link with leading space",
+			src:  "[x]( javascript:alert)",
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "data link",
+			src:  "[x](data:text/html;base64,PHNjcmlwdD4=)",
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "relative link",
+			src:  "[x](/admin)",
+			want: "<p>x</p>\n",
+		},
+		{
+			name: "bare javascript url is text",
+			src:  "javascript:alert(1)",
+			want: "<p>javascript:alert(1)</p>\n",
+		},
+		{
+			name: "raw html",
+			src:  "<script>alert(1)</script>",
+			want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name: "raw html attribute",
+			src:  `<img src=x onerror="alert(1)">`,
+			want: "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n",
+		},
+		{
+			name: "html in code span",
+			src:  "`<b>&amp;</b>`",
+			want: "<p><code>&lt;b&gt;&amp;amp;&lt;/b&gt;</code></p>\n",
+		},
+		{
+			name: "html in code block",
+			src:  "```\n<script>\n```",
+			want: "<pre><code>&lt;script&gt;\n</code></pre>\n",
+		},
+		{
+			name: "code block language breakout",
+			src:  "```js\"onload=\"alert(1)\nx\n```",
+			want: "<pre><code>x\n</code></pre>\n",
+		},
+		{
+			name: "entities are escaped",
+			src:  "Tom & Jerry &lt; &#60; 'quoted'",
+			want: "<p>Tom &amp; Jerry &amp;lt; &amp;#60; &#39;quoted&#39;</p>\n",
+		},
+		{
+			name: "query string ampersand",
+			src:  "https://example.com/?a=1&b=2",
+			want: `<p><a href="https://example.com/?a=1&amp;b=2" ` + linkRel + `>https://example.com/?a=1&amp;b=2</a></p>` + "\n",
+		},
+		{
+			name: "heading and hashtag",
+			src:  "# Title\n#golang",
+			want: "<h1>Title</h1>\n<p>#golang</p>\n",
+		},
+		{
+			name: "list",
+			src:  "- a\n- b",
+			want: "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n",
+		},
+		{
+			name: "quote",
+			src:  "> quoted <b>",
+			want: "<blockquote>\n<p>quoted &lt;b&gt;</p>\n</blockquote>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderAttributeBreakout checks that nothing in a link can close the
+// href attribute and add one of its own.
+func TestRenderAttributeBreakout(t *testing.T) {
+	tests := []string{
+		`[x](https://example.com/"onmouseover="alert(1))`,
+		`[x](https://example.com/'onmouseover='alert(1))`,
+		`https://example.com/"onmouseover="alert(1)`,
+		`[x](https://example.com/?q=<script>)`,
+		`[x](mailto:a@b.c"onclick="alert(1))`,
+		`[<img src=x onerror=alert(1)>](https://example.com)`,
+	}
+
+	for _, src := range tests {
+		got := Render(src)
+
+		for _, bad := range []string{`"on`, `'on`, "<script", "<img"} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q) = %q, contains %q", src, got, bad)
+			}
+		}
+
+		if n := strings.Count(got, `"`); n%2 != 0 {
+			t.Errorf("Render(%q) = %q, unbalanced quotes", src, got)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "markdown is literal",
+			src:  "**bold** [x](https://go.dev)",
+			want: `<p>**bold** [x](<a href="https://go.dev" ` + linkRel + `>https://go.dev</a>)</p>` + "\n",
+		},
+		{
+			name: "raw html",
+			src:  "<script>alert(1)</script>",
+			want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+		},
+		{
+			name: "paragraphs and line breaks",
+			src:  "a\r\nb\n\nc",
+			want: "<p>a<br>\nb</p>\n<p>c</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Plain(tt.src); got != tt.want {
+				t.Errorf("Plain(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
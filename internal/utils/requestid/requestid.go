@@ -0,0 +1,51 @@
+// Package requestid tags every request with an id that is echoed in the
+// X-Request-ID header, in error responses and in the logs.
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	Header = "X-Request-ID"
+
+	contextKey = "request_id"
+	maxLength  = 64
+)
+
+// Middleware keeps a sane X-Request-ID set by a proxy in front of the API,
+// or generates a new one.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(contextKey, id)
+		c.Header(Header, id)
+
+		c.Next()
+	}
+}
+
+// Get returns the id of the request, or "" outside of Middleware.
+func Get(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// valid only lets through ids that are safe to log and echo back.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+
+	return true
+}
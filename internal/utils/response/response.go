@@ -1,27 +1,114 @@
 package response
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/codepnw/gopher-social/internal/utils/errs"
 	"github.com/codepnw/gopher-social/internal/utils/logger"
+	"github.com/codepnw/gopher-social/internal/utils/requestid"
+	"github.com/codepnw/gopher-social/internal/utils/validation"
 	"github.com/gin-gonic/gin"
 )
 
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code, RequestID and Errors
+// are extension members.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail"`
+	Instance  string            `json:"instance"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id"`
+	Errors    []errs.FieldError `json:"errors,omitempty"`
+}
+
+// Error writes err as a problem with the status of its errs.Error.
+// Anything else is a 500 and only its cause is logged.
+func Error(c *gin.Context, err error) {
+	e := errs.From(err)
+
+	if e.Status >= http.StatusInternalServerError {
+		logger.Error(c, e.Code, err)
+	} else {
+		logger.Warn(c, e.Code, err)
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(e.Status, Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      e.Code,
+		RequestID: requestid.Get(c),
+		Errors:    e.Fields,
+	})
+}
+
+// Abort writes err like Error and stops the handler chain, for middleware.
+func Abort(c *gin.Context, err error) {
+	Error(c, err)
+	c.Abort()
+}
+
+// The helpers below force a status on errors that do not carry one, such
+// as binding errors, whose message is about the client's own input and is
+// safe to echo. A domain error keeps its own code and message.
+
+// BadRequestResponse reports binding and validation errors field by field.
 func BadRequestResponse(c *gin.Context, err error) {
-	logger.Warn(c, "bad request", err)
-	c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+	if v := validation.Translate(err); v != nil {
+		Error(c, v)
+		return
+	}
+
+	Error(c, withStatus(http.StatusBadRequest, "bad_request", err))
 }
 
 func NotFoundResponse(c *gin.Context, err error) {
-	logger.Warn(c, "not found", err)
-	c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": err.Error()})
+	Error(c, withStatus(http.StatusNotFound, "not_found", err))
 }
 
+func ForbiddenResponse(c *gin.Context, err error) {
+	Error(c, withStatus(http.StatusForbidden, "forbidden", err))
+}
+
+func ConflictResponse(c *gin.Context, err error) {
+	Error(c, withStatus(http.StatusConflict, "conflict", err))
+}
+
+func PayloadTooLargeResponse(c *gin.Context, err error) {
+	Error(c, withStatus(http.StatusRequestEntityTooLarge, "payload_too_large", err))
+}
+
+func UnsupportedMediaTypeResponse(c *gin.Context, err error) {
+	Error(c, withStatus(http.StatusUnsupportedMediaType, "unsupported_media_type", err))
+}
+
+// InternalServerError never shows err to the client.
 func InternalServerError(c *gin.Context, err error) {
-	logger.Error(c, "internal server", err)
-	c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+	Error(c, errs.Internal.Wrap(err))
 }
 
+// ResponseData writes the success envelope.
+//
+//	{"status": "success", "data": ...}
 func ResponseData(c *gin.Context, code int, data any) {
 	c.JSON(code, gin.H{"status": "success", "data": data})
-}
\ No newline at end of file
+}
+
+func withStatus(status int, code string, err error) *errs.Error {
+	var e *errs.Error
+	if errors.As(err, &e) {
+		forced := *e
+		forced.Status = status
+		return &forced
+	}
+
+	return errs.New(status, code, err.Error()).Wrap(err)
+}
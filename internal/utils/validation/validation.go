@@ -0,0 +1,162 @@
+// Package validation turns binding and validator errors into field errors
+// that name fields the way clients send them.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/codepnw/gopher-social/internal/utils/errs"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Init makes the validator report fields by their json name, falling back
+// to the form name used by query structs. Call it once before serving.
+func Init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+}
+
+// Translate returns errs.Validation with the problems found in err, or
+// nil when err is not a binding error about the request content.
+func Translate(err error) *errs.Error {
+	var (
+		verrs     validator.ValidationErrors
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+	)
+
+	switch {
+	case errors.As(err, &verrs):
+		fields := make([]errs.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, errs.FieldError{Field: fieldPath(fe), Message: message(fe)})
+		}
+		return errs.Validation.WithFields(fields).Wrap(err)
+
+	case errors.As(err, &typeErr):
+		field := errs.FieldError{Field: typeErr.Field, Message: "must be " + article(typeErr.Type.Kind())}
+		return errs.Validation.WithFields([]errs.FieldError{field}).Wrap(err)
+
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return errs.Validation.WithMessage("the request body is not valid JSON").Wrap(err)
+
+	case errors.Is(err, io.EOF):
+		return errs.Validation.WithMessage("the request body is empty").Wrap(err)
+	}
+
+	return nil
+}
+
+// fieldPath drops the struct name from the namespace, so
+// "CreatePollPayload.options[1]" becomes "options[1]".
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		return atLeast(fe.Kind(), param)
+	case "max", "lte":
+		return atMost(fe.Kind(), param)
+	case "len":
+		return "must be exactly " + param + unit(fe.Kind(), param)
+	case "datetime":
+		return "must be a time formatted as " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	}
+
+	return "is invalid"
+}
+
+func atLeast(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return "must be at least " + param + unit(kind, param) + " long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "must have at least " + param + unit(kind, param)
+	}
+	return "must be at least " + param
+}
+
+func atMost(kind reflect.Kind, param string) string {
+	switch kind {
+	case reflect.String:
+		return "must be at most " + param + unit(kind, param) + " long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "must have at most " + param + unit(kind, param)
+	}
+	return "must be at most " + param
+}
+
+func unit(kind reflect.Kind, param string) string {
+	var u string
+	switch kind {
+	case reflect.String:
+		u = " character"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		u = " item"
+	default:
+		return ""
+	}
+
+	if param != "1" {
+		u += "s"
+	}
+	return u
+}
+
+func article(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a valid value"
+}